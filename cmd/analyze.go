@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+	"github.com/spf13/cobra"
+)
+
+var (
+	analyzeDate  string
+	analyzeApply bool
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Suggest meeting moves that leave longer focus windows",
+	Long: `Look at the meetings of a day and suggest moving the ones you organize, or the
+ones tagged as movable, next to other meetings so the largest free window of the
+day grows. Moves keep to the free time of the attendees whose calendars share
+free/busy with you. The fragmentation of the day is shown before and after the moves.
+Nothing is changed on the calendar unless --apply is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		date, err := cfg.GetPlanningDate()
+		if err != nil {
+			return fmt.Errorf("failed to parse planning date: %w", err)
+		}
+		if analyzeDate != "" {
+			if date, err = time.ParseInLocation(config.DateFormat, analyzeDate, time.Local); err != nil {
+				return fmt.Errorf("invalid --date (expected YYYY-MM-DD): %w", err)
+			}
+		}
+
+		ctx := cmd.Context()
+		calClient, err := newCalendar(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to authenticate with Google Calendar: %w", err)
+		}
+		service := planning.NewService(cfg, calClient, nil)
+
+		day, err := service.PrepareFullDay(ctx, date)
+		if err != nil {
+			return err
+		}
+		analysis, err := service.Analyze(ctx, day)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("🔍 Meetings on %s: %d movable (organized by you or tagged %s)\n",
+			date.Format("Monday, January 2, 2006"), len(analysis.Movable), cfg.Analyze.Tag())
+		fmt.Printf("Fragmentation: %s\n", describeFragmentation(analysis.Before))
+
+		if len(analysis.Moves) == 0 {
+			fmt.Println("\n✅ No moves would make the largest focus window longer")
+			return nil
+		}
+		fmt.Println("\nSuggested moves:")
+		for _, m := range analysis.Moves {
+			if m.Meeting.Organized {
+				fmt.Printf("  ↪ %s\n", m)
+			} else {
+				fmt.Printf("  ↪ %s, ask the organizer\n", m)
+			}
+		}
+		fmt.Printf("\nFragmentation after the moves: %s\n", describeFragmentation(analysis.After))
+		if len(analysis.Unchecked) > 0 {
+			fmt.Printf("\n⚠️  Could not check the availability of %s; the new times may not suit them\n",
+				strings.Join(analysis.Unchecked, ", "))
+		}
+
+		if !analyzeApply {
+			fmt.Println("\nRun with --apply to move the meetings.")
+			return nil
+		}
+		skipped, err := service.ApplyMoves(ctx, analysis.Moves)
+		if err != nil {
+			return err
+		}
+		if len(skipped) > 0 {
+			fmt.Println()
+		}
+		for _, m := range skipped {
+			fmt.Printf("⚠️  Not moved: %s is organized by someone else\n", m.Meeting.Title)
+		}
+		if len(skipped) < len(analysis.Moves) {
+			fmt.Println("\n✅ Meetings moved and attendees notified")
+		}
+		return nil
+	},
+}
+
+func describeFragmentation(f planning.Fragmentation) string {
+	return fmt.Sprintf("%d%% (largest focus window %d min, %d min free in %d windows)",
+		f.Score(), int(f.Largest.Minutes()), int(f.Free.Minutes()), f.Windows)
+}
+
+func init() {
+	rootCmd.AddCommand(analyzeCmd)
+	analyzeCmd.Flags().StringVar(&analyzeDate, "date", "", "Date to analyze, as YYYY-MM-DD (default: the planning date)")
+	analyzeCmd.Flags().BoolVar(&analyzeApply, "apply", false, "Move the meetings as suggested")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	gcal "google.golang.org/api/calendar/v3"
+)
+
+func TestAnalyzeCommand(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer()
+	defer openAI.Close()
+
+	cfg := testConfig()
+	date, _ := cfg.GetPlanningDate()
+	cal.AddMeeting("primary", "Standup", date.Add(9*time.Hour), date.Add(9*time.Hour+30*time.Minute))
+	cal.Add("primary", &gcal.Event{
+		Summary:   "1:1",
+		Organizer: &gcal.EventOrganizer{Self: true},
+		Attendees: []*gcal.EventAttendee{
+			{Email: "me@example.com", Self: true},
+			{Email: "bob@example.com"},
+			{Email: "carol@example.com"},
+			{Email: "dave@example.com", ResponseStatus: "declined"},
+		},
+		Start: &gcal.EventDateTime{DateTime: date.Add(15 * time.Hour).Format(time.RFC3339)},
+		End:   &gcal.EventDateTime{DateTime: date.Add(15*time.Hour + 30*time.Minute).Format(time.RFC3339)},
+	})
+
+	// Bob is busy before lunch, Carol's calendar is not shared and Dave declined
+	cal.AddMeeting("bob@example.com", "Busy", date.Add(11*time.Hour), date.Add(12*time.Hour))
+	cal.SetAccessRole("carol@example.com", "")
+	cal.AddMeeting("dave@example.com", "Busy", date.Add(9*time.Hour+30*time.Minute), date.Add(10*time.Hour+30*time.Minute))
+
+	useFakes(t, cfg, cal, openAI)
+	t.Cleanup(func() { analyzeDate, analyzeApply = "", false })
+
+	rootCmd.SetArgs([]string{"analyze"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("analyze command failed: %v", err)
+	}
+	moved := cal.Events("primary")[1]
+	if moved.Start.DateTime != date.Add(15*time.Hour).Format(time.RFC3339) {
+		t.Fatalf("expected analyze to leave the calendar unchanged without --apply, got %s", moved.Start.DateTime)
+	}
+
+	rootCmd.SetArgs([]string{"analyze", "--apply"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("analyze --apply failed: %v", err)
+	}
+	moved = cal.Events("primary")[1]
+	if moved.Start.DateTime != date.Add(9*time.Hour+30*time.Minute).Format(time.RFC3339) {
+		t.Errorf("expected the 1:1 to be moved after the standup, where Bob is free, got %s", moved.Start.DateTime)
+	}
+	if got := cal.SentUpdates(moved.Id); got != "all" {
+		t.Errorf("expected the attendees to be notified of the move, got sendUpdates=%q", got)
+	}
+}
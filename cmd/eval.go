@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/eval"
+	"github.com/spf13/cobra"
+)
+
+var (
+	evalScenarios string
+	evalRuns      int
+	evalFormat    string
+	evalModel     string
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Score the planner on recorded scenarios",
+	Long: `Run every scenario file in a directory through the configured planner several times
+and score the plans on overlaps, task coverage, break adherence, fragmentation and
+blocks outside work hours. Nothing is written to your calendar.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if evalFormat != "table" && evalFormat != "json" {
+			return fmt.Errorf("invalid format: %s (valid formats: table, json)", evalFormat)
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if evalModel != "" {
+			cfg.OpenAIModel = evalModel
+		}
+		model := cfg.OpenAIModel
+		if model == "" {
+			model = ai.DefaultModel
+		}
+
+		scenarios, err := eval.LoadScenarios(evalScenarios)
+		if err != nil {
+			return err
+		}
+
+		if evalFormat == "table" {
+			fmt.Printf("🧪 Evaluating %d scenario(s) with %s, %d run(s) each...\n\n", len(scenarios), model, evalRuns)
+		}
+
+		runner := &eval.Runner{
+			Config:  cfg,
+			Planner: newPlanner(cfg),
+			Model:   model,
+			Runs:    evalRuns,
+		}
+		report, err := runner.Run(cmd.Context(), scenarios)
+		if err != nil {
+			return err
+		}
+
+		if evalFormat == "json" {
+			return report.WriteJSON(os.Stdout)
+		}
+		return report.WriteTable(os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(evalCmd)
+	evalCmd.Flags().StringVarP(&evalScenarios, "scenarios", "s", "scenarios", "Directory with scenario files (*.json)")
+	evalCmd.Flags().IntVarP(&evalRuns, "runs", "n", 3, "Number of runs per scenario")
+	evalCmd.Flags().StringVarP(&evalFormat, "format", "f", "table", "Output format: table or json")
+	evalCmd.Flags().StringVar(&evalModel, "model", "", "Model to evaluate (default from config)")
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Alvkoen/barely-incharge/internal/history"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyLimit int
+	historyJSON  bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show past plan runs",
+	Long:  `Every plan run is recorded in history.jsonl in the data directory (data_dir in config, ~/.barely-incharge by default): the tasks, mode, model, prompt, raw model response, final blocks and the created calendar event IDs.`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent plan runs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := loadHistory()
+		if err != nil {
+			return err
+		}
+		records, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			fmt.Println("No plan runs recorded yet")
+			return nil
+		}
+		if historyLimit > 0 && len(records) > historyLimit {
+			records = records[len(records)-historyLimit:]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tRUN AT\tDATE\tMODE\tMODEL\tTASKS\tBLOCKS\tSTATUS")
+		for i := len(records) - 1; i >= 0; i-- {
+			r := records[i]
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+				r.ID, r.CreatedAt.Local().Format("2006-01-02 15:04"), r.Date, r.Mode, r.Model,
+				len(r.Tasks), len(r.Blocks), r.Status())
+		}
+		return w.Flush()
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the details of a plan run",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := loadHistory()
+		if err != nil {
+			return err
+		}
+		record, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		if historyJSON {
+			data, err := json.MarshalIndent(record, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to format history record: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		printRecord(record)
+		return nil
+	},
+}
+
+func loadHistory() (*history.Store, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	store, err := openHistory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	return store, nil
+}
+
+func printRecord(r history.Record) {
+	fmt.Printf("Run #%s at %s (%s)\n", r.ID, r.CreatedAt.Local().Format("2006-01-02 15:04"), r.Status())
+	fmt.Printf("Date: %s\n", r.Date)
+	fmt.Printf("Calendar: %s\n", r.Calendar)
+	fmt.Printf("Mode: %s\n", r.Mode)
+	if r.Model != "" {
+		fmt.Printf("Model: %s\n", r.Model)
+	}
+	if r.Error != "" {
+		fmt.Printf("Error: %s\n", r.Error)
+	}
+
+	fmt.Printf("\nTasks (%d):\n", len(r.Tasks))
+	for i, task := range r.Tasks {
+		fmt.Printf("  %d. %s (%d min)\n", i+1, task.Title, task.Minutes)
+	}
+
+	if len(r.Blocks) > 0 {
+		fmt.Printf("\nBlocks (%d):\n", len(r.Blocks))
+		for i, b := range r.Blocks {
+			fmt.Printf("  %d. %s (%s - %s)", i+1, b.Title,
+				b.Start.Local().Format(planner.TimeFormat), b.End.Local().Format(planner.TimeFormat))
+			if b.Status != "" {
+				fmt.Printf(" %s", b.Status)
+			}
+			if b.EventID != "" {
+				fmt.Printf(" [%s]", b.EventID)
+			}
+			fmt.Println()
+		}
+	}
+	for _, title := range r.Unscheduled {
+		fmt.Printf("  ⚠️  No room left for: %s\n", title)
+	}
+
+	if r.Prompt != "" {
+		fmt.Printf("\nPrompt:\n%s\n", r.Prompt)
+	}
+	if len(r.Chat) > 0 {
+		fmt.Println("\nChanges asked:")
+		for i, turn := range r.Chat {
+			fmt.Printf("  %d. %s\n", i+1, turn.Instruction)
+		}
+	}
+	if r.RawResponse != "" {
+		fmt.Printf("\nModel response:\n%s\n", r.RawResponse)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyListCmd, historyShowCmd)
+	historyListCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Number of runs to show (0 for all)")
+	historyShowCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the full record as JSON")
+}
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"slices"
 	"strings"
 	"time"
@@ -10,138 +13,185 @@ import (
 	"github.com/Alvkoen/barely-incharge/internal/ai"
 	"github.com/Alvkoen/barely-incharge/internal/calendar"
 	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/history"
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
 	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
 	"github.com/spf13/cobra"
 )
 
 var (
-	tasks string
-	mode  string
+	tasks       string
+	mode        string
+	keepPartial bool
+	fresh       bool
+	suggest     bool
+	showPrompt  bool
+	chat        bool
+	ask         string
 )
 
+// Seams for tests: the plan command builds its config and clients through
+// these so it can run against fakes.
+var (
+	loadConfig  = config.Load
+	newCalendar = func(ctx context.Context, cfg *config.Config) (planning.Calendar, error) {
+		return calendar.NewGoogleClient(ctx, httpPolicy(cfg))
+	}
+	newPlanner = func(cfg *config.Config) planning.Planner {
+		return ai.NewClient(cfg.OpenAIAPIKey,
+			append(plannerOptions(cfg), ai.WithHTTPClient(httpx.NewClient(aiPolicy(cfg))))...)
+	}
+	openHistory = func(cfg *config.Config) (*history.Store, error) {
+		dir, err := cfg.GetDataDir()
+		if err != nil {
+			return nil, err
+		}
+		return history.Open(dir)
+	}
+)
+
+// plannerOptions returns the AI client options set by config and flags.
+func plannerOptions(cfg *config.Config) []ai.Option {
+	opts := []ai.Option{ai.WithModel(cfg.OpenAIModel), ai.WithPrivacy(cfg.Privacy)}
+	if dir, err := cfg.GetPromptsDir(); err == nil {
+		opts = append(opts, ai.WithPromptsDir(dir))
+	}
+	if showPrompt {
+		opts = append(opts, ai.WithPromptWriter(os.Stdout))
+	}
+	return opts
+}
+
+// httpPolicy returns the timeout and retry policy configured for Google Calendar.
+func httpPolicy(cfg *config.Config) httpx.Policy {
+	policy := httpx.DefaultPolicy(cfg.HTTP.Timeout())
+	policy.MaxRetries = cfg.HTTP.Retries()
+	return policy
+}
+
+// aiPolicy returns the policy for OpenAI, whose completions take longer.
+func aiPolicy(cfg *config.Config) httpx.Policy {
+	policy := httpPolicy(cfg)
+	policy.Timeout = cfg.HTTP.AITimeout()
+	return policy
+}
+
 var planCmd = &cobra.Command{
 	Use:   "plan",
 	Short: "Plan your day with AI-powered focus blocks",
-	Long:  `Create focus and break blocks in your calendar based on your tasks, meetings, and chosen mode (crunch, normal, or saver).`,
+	Long:  `Create focus and break blocks in your calendar based on your tasks, meetings, and chosen mode (crunch, normal, saver, or any mode defined in config).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.Load()
+		cfg, err := loadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		selectedMode := strings.TrimSpace(mode)
-		if selectedMode == "" {
-			selectedMode = cfg.DefaultMode
-		} else {
-			if err := config.ValidateMode(selectedMode); err != nil {
-				return err
-			}
+		modeName := strings.TrimSpace(mode)
+		if modeName == "" {
+			modeName = cfg.DefaultMode
+		}
+		selectedMode, err := cfg.GetMode(modeName)
+		if err != nil {
+			return err
 		}
 
-		if tasks == "" {
-			return fmt.Errorf("--tasks flag is required")
+		ctx := cmd.Context()
+		in := bufio.NewReader(cmd.InOrStdin())
+		aiPlanner := newPlanner(cfg)
+
+		var taskList []planner.Task
+		switch {
+		case ask != "" && tasks != "":
+			return fmt.Errorf("use either --tasks or --ask, not both")
+		case ask != "":
+			if taskList, err = askTasks(ctx, in, cfg, aiPlanner, ask); err != nil {
+				return err
+			}
+		case tasks != "":
+			taskList = planner.ParseTaskList(tasks)
+		default:
+			return fmt.Errorf("--tasks or --ask flag is required")
 		}
-		taskList := planner.ParseTaskList(tasks)
 
 		planningDate, err := cfg.GetPlanningDate()
 		if err != nil {
 			return fmt.Errorf("failed to parse planning date: %w", err)
 		}
 
+		var adjustments []string
+		if !fresh {
+			taskList, adjustments = applyReviews(cfg, planningDate, taskList)
+		}
+
 		fmt.Println("🎯 Planning your day...")
 		fmt.Printf("Date: %s\n", planningDate.Format("Monday, January 2, 2006"))
-		fmt.Printf("Mode: %s\n", selectedMode)
+		fmt.Printf("Mode: %s\n", selectedMode.Name)
 		fmt.Printf("Work Hours: %s - %s\n", cfg.WorkHours.Start, cfg.WorkHours.End)
 		fmt.Printf("Lunch Time: %s - %s\n", cfg.LunchTime.Start, cfg.LunchTime.End)
 		fmt.Printf("Tasks (%d):\n", len(taskList))
 		for i, task := range taskList {
-			fmt.Printf("  %d. %s (%d min)\n", i+1, task.Title, int(task.Duration.Minutes()))
+			fmt.Printf("  %d. %s\n", i+1, describeTask(task))
+		}
+		for _, note := range adjustments {
+			fmt.Printf("  %s\n", note)
 		}
 		fmt.Printf("\nCalendar: %s\n", cfg.Calendar)
 
-		ctx := context.Background()
-
-		calClient, err := authenticateCalendar(ctx)
-		if err != nil {
-			return err
+		if suggest {
+			cfg.Suggestions.Enabled = true
 		}
 
-		meetings, err := fetchMeetings(calClient, cfg.Calendar)
+		calClient, err := authenticateCalendar(ctx, cfg)
 		if err != nil {
 			return err
 		}
 
-		workStart, err := planner.ParseTimeOnDate(cfg.WorkHours.Start, planningDate)
-		if err != nil {
-			return fmt.Errorf("invalid work start time: %w", err)
-		}
-		workEnd, err := planner.ParseTimeOnDate(cfg.WorkHours.End, planningDate)
-		if err != nil {
-			return fmt.Errorf("invalid work end time: %w", err)
-		}
-		lunchStart, err := planner.ParseTimeOnDate(cfg.LunchTime.Start, planningDate)
-		if err != nil {
-			return fmt.Errorf("invalid lunch start time: %w", err)
-		}
-		lunchEnd, err := planner.ParseTimeOnDate(cfg.LunchTime.End, planningDate)
+		service := planning.NewService(cfg, calClient, aiPlanner)
+
+		fmt.Printf("\n📆 Fetching meetings from calendar: %s\n", cfg.Calendar)
+		day, err := service.PrepareDay(ctx, planningDate)
 		if err != nil {
-			return fmt.Errorf("invalid lunch end time: %w", err)
+			return err
 		}
+		printMeetings(day.Meetings)
+		printRoutines(day)
 
-		// Adjust workStart if planning for today and current time is after work start
-		now := time.Now()
-		if planningDate.YearDay() == now.YearDay() && now.After(workStart) {
-			// Round up to next 15-minute slot for clean scheduling
-			roundedNow := now.Truncate(15 * time.Minute).Add(15 * time.Minute)
-			if roundedNow.Before(workEnd) {
-				workStart = roundedNow
-				fmt.Printf("📍 Adjusted start time to %s (current time)\n", workStart.Format(planner.TimeFormat))
-			} else {
-				return fmt.Errorf("no time left in work day to plan (it's already %s)", now.Format(planner.TimeFormat))
-			}
+		if day.StartAdjusted {
+			fmt.Printf("📍 Adjusted start time to %s (current time)\n", day.WorkStart.Format(planner.TimeFormat))
 		}
 
-		busyBlocks := make([]planner.TimeBlock, 0, len(meetings)+1)
-		busyBlocks = append(busyBlocks, planner.TimeBlock{
-			Type:  planner.BlockTypeLunch,
-			Title: "Lunch",
-			Start: lunchStart,
-			End:   lunchEnd,
-		})
-		for _, meeting := range meetings {
-			busyBlocks = append(busyBlocks, meeting.ToTimeBlock())
-		}
+		record := history.NewRecord(planningDate, cfg.Calendar, selectedMode, taskList)
 
-		plan, err := generatePlan(cfg, selectedMode, workStart, workEnd, taskList, busyBlocks)
+		fmt.Println("\n🤖 Generating plan with AI...")
+		plan, err := service.Generate(ctx, day, selectedMode, taskList)
 		if err != nil {
+			saveHistory(cfg, record, err)
 			return err
 		}
 
-		fmt.Printf("\n✨ Generated %d blocks:\n", len(plan.Blocks))
-		for i, block := range plan.Blocks {
-			icon := "🎯"
-			if block.Type == planner.BlockTypeBreak {
-				icon = "☕"
-			}
-			fmt.Printf("  %d. %s %s (%s - %s)\n", i+1, icon, block.Title, block.Start, block.End)
-		}
+		printPlan(plan)
 
-		parsedBlocks, err := parseAIBlocks(plan.Blocks, planningDate)
-		if err != nil {
-			return fmt.Errorf("failed to parse AI blocks: %w", err)
+		if chat {
+			if plan, err = refineInChat(ctx, in, service, plan); err != nil {
+				saveHistory(cfg, record, err)
+				return err
+			}
 		}
 
-		// Add lunch block if the slot is free
-		if isLunchSlotFree(lunchStart, lunchEnd, meetings) {
-			parsedBlocks = append(parsedBlocks, planner.TimeBlock{
-				Type:  planner.BlockTypeLunch,
-				Title: "Lunch",
-				Start: lunchStart,
-				End:   lunchEnd,
-			})
+		if len(plan.Suggested) > 0 {
+			if err := confirmSuggestions(in, plan); err != nil {
+				saveHistory(cfg, record, err)
+				return err
+			}
 		}
+		record.SetPlan(plan)
 
-		err = createBlocks(calClient, cfg.Calendar, parsedBlocks)
+		fmt.Println("\n📝 Creating blocks in calendar...")
+		tx, err := service.Apply(ctx, plan, planning.ApplyOptions{KeepPartial: keepPartial})
+		printTransaction(tx)
+		record.SetTransaction(tx)
+		saveHistory(cfg, record, err)
 		if err != nil {
 			return err
 		}
@@ -152,10 +202,51 @@ var planCmd = &cobra.Command{
 	},
 }
 
-func authenticateCalendar(ctx context.Context) (*calendar.GoogleClient, error) {
+// askTasks turns a to-do list written in plain language into tasks and asks
+// whether to plan them.
+func askTasks(ctx context.Context, in *bufio.Reader, cfg *config.Config, p planning.Planner, text string) ([]planner.Task, error) {
+	fmt.Println("🧠 Reading your to-do list...")
+	parsed, err := planning.ParseTasks(ctx, cfg, p, text)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Fallback != nil {
+		fmt.Printf("  ⚠️  Parsed without AI: %v\n", parsed.Fallback)
+	}
+	for i, task := range parsed.Tasks {
+		fmt.Printf("  %d. %s\n", i+1, describeTask(task))
+	}
+
+	answer, err := prompt(in, "Plan these tasks? [Y/n]: ")
+	if err != nil {
+		return nil, err
+	}
+	if a := strings.ToLower(answer); a == "n" || a == "no" {
+		return nil, fmt.Errorf("canceled: rephrase the list or pass --tasks")
+	}
+	return parsed.Tasks, nil
+}
+
+// describeTask formats a task with its length, kind and time constraints, e.g.
+// "Write docs (60 min, deep, before 12:00)".
+func describeTask(task planner.Task) string {
+	details := []string{fmt.Sprintf("%d min", int(task.Duration.Minutes()))}
+	if task.Kind != "" {
+		details = append(details, task.Kind)
+	}
+	if task.After != "" {
+		details = append(details, "after "+task.After)
+	}
+	if task.Before != "" {
+		details = append(details, "before "+task.Before)
+	}
+	return fmt.Sprintf("%s (%s)", task.Title, strings.Join(details, ", "))
+}
+
+func authenticateCalendar(ctx context.Context, cfg *config.Config) (planning.Calendar, error) {
 	fmt.Println("\n🔐 Authenticating with Google Calendar...")
 
-	client, err := calendar.NewGoogleClient(ctx)
+	client, err := newCalendar(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to authenticate with Google Calendar: %w", err)
 	}
@@ -165,93 +256,226 @@ func authenticateCalendar(ctx context.Context) (*calendar.GoogleClient, error) {
 	return client, nil
 }
 
-func fetchMeetings(client *calendar.GoogleClient, calendarID string) ([]calendar.Event, error) {
-	fmt.Printf("\n📆 Fetching meetings from calendar: %s\n", calendarID)
+// applyReviews scales task sizes by the factors learned from past reviews and adds
+// the tasks carried over to date. It returns the tasks and a note per change.
+// History errors are reported as warnings.
+func applyReviews(cfg *config.Config, date time.Time, tasks []planner.Task) ([]planner.Task, []string) {
+	store, err := openHistory(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to open plan history: %v\n", err)
+		return tasks, nil
+	}
+
+	var notes []string
 
-	meetings, err := client.FetchTodaysMeetings(calendarID)
+	reviews, err := store.Reviews()
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch meetings: %w", err)
+		fmt.Fprintf(os.Stderr, "Warning: failed to read reviews: %v\n", err)
+		return tasks, nil
+	}
+	factors := history.SizeFactors(reviews)
+	scaled := planner.ScaleSizes(tasks, history.Factors(factors))
+	for i, task := range scaled {
+		if task.Duration != tasks[i].Duration {
+			size := planner.SizeOf(tasks[i].Duration)
+			notes = append(notes, fmt.Sprintf("📏 %s: %s is %d min for you (learned from %d reviewed tasks)",
+				task.Title, size, int(task.Duration.Minutes()), factors[size].Samples))
+		}
 	}
 
-	if len(meetings) == 0 {
-		fmt.Println("  No meetings found for today")
-	} else {
-		fmt.Printf("  Found %d meeting(s):\n", len(meetings))
-		for i, meeting := range meetings {
-			fmt.Printf("  %d. %s (%s - %s)\n",
-				i+1,
-				meeting.Title,
-				meeting.Start.Format(planner.TimeFormat),
-				meeting.End.Format(planner.TimeFormat))
+	carried, err := store.CarryOver(date)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read carried over tasks: %v\n", err)
+		return scaled, notes
+	}
+	for _, task := range carried {
+		if slices.ContainsFunc(scaled, func(t planner.Task) bool { return strings.EqualFold(t.Title, task.Title) }) {
+			continue
 		}
+		scaled = append(scaled, task)
+		notes = append(notes, fmt.Sprintf("↪️  Carried over: %s (%d min)", task.Title, int(task.Duration.Minutes())))
 	}
 
-	return meetings, nil
+	return scaled, notes
 }
 
-func init() {
-	rootCmd.AddCommand(planCmd)
-	planCmd.Flags().StringVarP(&tasks, "tasks", "t", "", "Comma-separated list of tasks to accomplish (required)")
-	planCmd.Flags().StringVarP(&mode, "mode", "m", "", "Planning mode: crunch, normal, or saver (default from config)")
-	if err := planCmd.MarkFlagRequired("tasks"); err != nil {
-		panic(err)
+// saveHistory records the run. Failing to write the history does not fail the run.
+func saveHistory(cfg *config.Config, record *history.Record, runErr error) {
+	record.SetError(runErr)
+
+	store, err := openHistory(cfg)
+	if err == nil {
+		err = store.Append(record)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save plan history: %v\n", err)
+		return
 	}
-}
 
-func generatePlan(cfg *config.Config, mode string, workStart, workEnd time.Time, tasks []planner.Task, busyBlocks []planner.TimeBlock) (*ai.PlanResponse, error) {
-	fmt.Println("\n🤖 Generating plan with AI...")
+	fmt.Printf("\n📚 Saved to history as #%s\n", record.ID)
+}
 
-	client := ai.NewClient(cfg.OpenAIAPIKey)
-	req := ai.PlanRequest{
-		WorkStart:  workStart,
-		WorkEnd:    workEnd,
-		BusyBlocks: busyBlocks,
-		Tasks:      tasks,
-		Mode:       mode,
+func printMeetings(meetings []calendar.Event) {
+	if len(meetings) == 0 {
+		fmt.Println("  No meetings found for today")
+		return
 	}
 
-	return client.GeneratePlan(context.Background(), req)
+	fmt.Printf("  Found %d meeting(s):\n", len(meetings))
+	for i, meeting := range meetings {
+		fmt.Printf("  %d. %s (%s - %s)\n",
+			i+1,
+			meeting.Title,
+			meeting.Start.Format(planner.TimeFormat),
+			meeting.End.Format(planner.TimeFormat))
+	}
 }
 
-func parseAIBlocks(aiBlocks []ai.Block, date time.Time) ([]planner.TimeBlock, error) {
-	blocks := make([]planner.TimeBlock, len(aiBlocks))
+func printPlan(plan *planning.Plan) {
+	fmt.Printf("\n✨ Generated %d blocks:\n", len(plan.Response.Blocks))
+	for i, block := range plan.Response.Blocks {
+		icon := blockIcon(block.Type)
+		if block.Suggested {
+			icon = "💡"
+		}
+		fmt.Printf("  %d. %s %s (%s - %s)\n", i+1, icon, block.Title, block.Start, block.End)
+	}
 
-	for i, block := range aiBlocks {
-		timeBlock, err := block.ToTimeBlock(date)
-		if err != nil {
-			return nil, fmt.Errorf("invalid block %d: %w", i+1, err)
+	if plan.Enforced {
+		fmt.Printf("\n⏱️  Enforced %s cadence:\n", plan.Mode.Name)
+		i := 0
+		for _, block := range plan.Blocks {
+			if block.Type != planner.BlockTypeFocus && block.Type != planner.BlockTypeBreak {
+				continue
+			}
+			i++
+			fmt.Printf("  %d. %s %s (%s - %s)\n", i, blockIcon(block.Type), block.Title,
+				block.Start.Format(planner.TimeFormat), block.End.Format(planner.TimeFormat))
 		}
-		blocks[i] = timeBlock
+	}
+	for _, task := range plan.Unscheduled {
+		fmt.Printf("  ⚠️  No room left for: %s\n", task.Title)
 	}
 
-	return blocks, nil
+	for _, block := range plan.Blocks {
+		if block.Type == planner.BlockTypeOutOfOffice {
+			fmt.Printf("\n🏁 Finishing early: out of office from %s\n", block.Start.Format(planner.TimeFormat))
+		}
+	}
 }
 
-func createBlocks(client *calendar.GoogleClient, calendarID string, parsedBlocks []planner.TimeBlock) error {
-	fmt.Println("\n📝 Creating blocks in calendar...")
-
-	for _, block := range parsedBlocks {
-		event := calendar.Event{
-			Type:        block.Type,
-			Title:       block.GetCalendarTitle(),
-			Description: block.GetCalendarDescription(),
-			Start:       block.Start,
-			End:         block.End,
+// refineInChat asks for changes to plan until the user is done and shows the
+// blocks that changed after every turn. An answer that fails validation is
+// reported and leaves the plan as it was.
+func refineInChat(ctx context.Context, in *bufio.Reader, service *planning.Service, plan *planning.Plan) (*planning.Plan, error) {
+	fmt.Println("\n💬 Describe a change (e.g. \"move the XL task to the morning\"), \"done\" to apply the plan or \"quit\" to discard it.")
+	for {
+		instruction, err := prompt(in, "> ")
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(instruction) {
+		case "":
+			continue
+		case "done":
+			return plan, nil
+		case "quit":
+			return nil, fmt.Errorf("plan discarded: nothing was added to the calendar")
 		}
 
-		if err := client.CreateEvent(calendarID, event); err != nil {
-			return fmt.Errorf("failed to create block '%s': %w", event.Title, err)
+		fmt.Println("🤖 Updating plan...")
+		refined, err := service.Refine(ctx, plan, instruction)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			fmt.Printf("  ⚠️  %v\n  The plan was not changed.\n", err)
+			continue
 		}
 
-		fmt.Printf("  ✓ Created: %s (%s)\n", event.Title, block.Title)
+		diffs := planning.DiffBlocks(plan.Blocks, refined.Blocks)
+		if len(diffs) == 0 {
+			fmt.Println("  No changes")
+		}
+		for _, d := range diffs {
+			fmt.Printf("  %s\n", d)
+		}
+		for _, task := range refined.Unscheduled {
+			fmt.Printf("  ⚠️  No room left for: %s\n", task.Title)
+		}
+		plan = refined
 	}
+}
 
+// confirmSuggestions asks about every suggested block and adds the accepted ones
+// to the plan.
+func confirmSuggestions(in *bufio.Reader, plan *planning.Plan) error {
+	fmt.Printf("\n💡 %d suggestion(s) for your meetings:\n", len(plan.Suggested))
+	var accepted []planner.TimeBlock
+	for _, block := range plan.Suggested {
+		answer, err := prompt(in, fmt.Sprintf("  Add %s (%s - %s)? [y/N]: ", block.Title,
+			block.Start.Format(planner.TimeFormat), block.End.Format(planner.TimeFormat)))
+		if err != nil {
+			return err
+		}
+		if a := strings.ToLower(answer); a == "y" || a == "yes" {
+			accepted = append(accepted, block)
+		}
+	}
+	for _, block := range plan.Accept(accepted) {
+		fmt.Printf("  ⚠️  No room left for: %s\n", block.Title)
+	}
 	return nil
 }
 
-// isLunchSlotFree checks if the lunch time slot has no overlapping meetings
-func isLunchSlotFree(lunchStart, lunchEnd time.Time, meetings []calendar.Event) bool {
-	return !slices.ContainsFunc(meetings, func(m calendar.Event) bool {
-		return m.Start.Before(lunchEnd) && m.End.After(lunchStart)
-	})
+func printTransaction(tx *planning.Transaction) {
+	for _, entry := range tx.Entries {
+		switch {
+		case entry.Interrupted:
+			fmt.Printf("  ⚠️  Canceled mid-request, check your calendar for it: %s (%s)\n", entry.Event.Title, entry.Block.Title)
+		case errors.Is(entry.Err, planning.ErrSkipped):
+			fmt.Printf("  – Skipped: %s (%s)\n", entry.Event.Title, entry.Block.Title)
+		case entry.Err != nil:
+			fmt.Printf("  ✗ Failed: %s (%s): %v\n", entry.Event.Title, entry.Block.Title, entry.Err)
+		case entry.RolledBack:
+			fmt.Printf("  ↩ Rolled back: %s (%s)\n", entry.Event.Title, entry.Block.Title)
+		case entry.Reused:
+			fmt.Printf("  ✓ Kept: %s (%s) [%s]\n", entry.Event.Title, entry.Block.Title, entry.Event.ID)
+		case entry.Event.ID != "":
+			fmt.Printf("  ✓ Created: %s (%s) [%s]\n", entry.Event.Title, entry.Block.Title, entry.Event.ID)
+		}
+	}
+}
+
+func printRoutines(day *planning.Day) {
+	if len(day.Routines) > 0 {
+		fmt.Printf("  Planned %d routine(s):\n", len(day.Routines))
+	}
+	for _, r := range day.Routines {
+		fmt.Printf("  - %s (%s - %s)\n", r.Title, r.Start.Format(planner.TimeFormat), r.End.Format(planner.TimeFormat))
+	}
+	for _, r := range day.RoutineConflicts {
+		fmt.Printf("  ⚠️  Skipped routine %s (%s - %s): it overlaps lunch or another routine\n",
+			r.Title, r.Start.Format(planner.TimeFormat), r.End.Format(planner.TimeFormat))
+	}
+}
+
+func blockIcon(blockType string) string {
+	if blockType == planner.BlockTypeBreak {
+		return "☕"
+	}
+	return "🎯"
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.Flags().StringVarP(&tasks, "tasks", "t", "", "Comma-separated list of tasks to accomplish (required unless --ask is given)")
+	planCmd.Flags().StringVarP(&mode, "mode", "m", "", "Planning mode: crunch, normal, saver, or a mode defined in config (default from config)")
+	planCmd.Flags().BoolVar(&fresh, "fresh", false, "Ignore tasks carried over by review and the task sizes learned from reviews")
+	planCmd.Flags().BoolVar(&suggest, "suggest", false, "Share meeting titles and descriptions with the AI to get prep and follow-up suggestions to confirm")
+	planCmd.Flags().StringVar(&ask, "ask", "", "Tasks in plain language, e.g. \"finish the RFC (2h, before lunch), call Bob after 3pm\"")
+	planCmd.Flags().BoolVar(&chat, "chat", false, "Change the plan in plain language before it is added to the calendar")
+	planCmd.Flags().BoolVar(&showPrompt, "show-prompt", false, "Print the prompt exactly as it is sent to the AI, after the privacy policy is applied")
+	planCmd.Flags().BoolVar(&keepPartial, "keep-partial", false, "Keep the blocks already created when creating others fails, instead of rolling back")
+	planCmd.MarkFlagsOneRequired("tasks", "ask")
 }
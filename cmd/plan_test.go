@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+	gcal "google.golang.org/api/calendar/v3"
+)
+
+// useFakes points the command seams at a fake calendar server and a fake
+// OpenAI server for the duration of the test.
+func useFakes(t *testing.T, cfg *config.Config, cal *calendartest.Server, openAI *aitest.Server) {
+	t.Helper()
+
+	origLoad, origCal, origPlanner, origHistory := loadConfig, newCalendar, newPlanner, openHistory
+	t.Cleanup(func() {
+		loadConfig, newCalendar, newPlanner, openHistory = origLoad, origCal, origPlanner, origHistory
+		tasks, mode, ask, keepPartial, fresh, suggest, showPrompt, chat = "", "", "", false, false, false, false, false
+	})
+
+	cfg.DataDir = t.TempDir()
+	loadConfig = func() (*config.Config, error) { return cfg, nil }
+	newCalendar = func(ctx context.Context, cfg *config.Config) (planning.Calendar, error) { return cal.Client(), nil }
+	newPlanner = func(cfg *config.Config) planning.Planner {
+		return ai.NewClient(cfg.OpenAIAPIKey, append(plannerOptions(cfg), ai.WithEndpoint(openAI.Endpoint()))...)
+	}
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		WorkHours:    config.TimeRange{Start: "09:00", End: "17:00"},
+		LunchTime:    config.TimeRange{Start: "12:00", End: "13:00"},
+		Calendar:     "primary",
+		DefaultMode:  config.ModeNormal,
+		OpenAIAPIKey: "sk-test",
+		Date:         "2030-01-07",
+	}
+}
+
+func TestPlanCommandOffline(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(aitest.Reply{Content: `{"blocks": [
+		{"type": "focus", "title": "Write docs", "start": "09:00", "end": "10:00"},
+		{"type": "break", "title": "Short break", "start": "10:00", "end": "10:15"},
+		{"type": "focus", "title": "Review PRs", "start": "10:30", "end": "10:45"}
+	]}`})
+	defer openAI.Close()
+
+	cfg := testConfig()
+	date, _ := cfg.GetPlanningDate()
+	cal.AddMeeting("primary", "Standup", date.Add(10*time.Hour+15*time.Minute), date.Add(10*time.Hour+30*time.Minute))
+
+	useFakes(t, cfg, cal, openAI)
+
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Write docs:L, Review PRs:S"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+
+	if got := len(openAI.Requests()); got != 1 {
+		t.Errorf("expected 1 OpenAI request, got %d", got)
+	}
+
+	events := cal.Events("primary")
+	// Standup plus three planned blocks and lunch
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events in calendar, got %d", len(events))
+	}
+
+	titles := make(map[string]int)
+	for _, e := range events[1:] {
+		titles[e.Summary]++
+	}
+	if titles["Focus time"] != 2 || titles["Break"] != 1 || titles["Lunch"] != 1 {
+		t.Errorf("unexpected created events: %v", titles)
+	}
+
+	store, err := openHistory(cfg)
+	if err != nil {
+		t.Fatalf("openHistory() error: %v", err)
+	}
+	record, err := store.Get("1")
+	if err != nil {
+		t.Fatalf("expected the run to be recorded: %v", err)
+	}
+	if record.Status() != "applied" || len(record.CreatedBlocks()) != 4 || record.Prompt == "" || record.RawResponse == "" {
+		t.Errorf("unexpected history record: %+v", record)
+	}
+	for _, b := range record.CreatedBlocks() {
+		if b.EventID == "" {
+			t.Errorf("expected an event ID for %s", b.Title)
+		}
+	}
+
+	rootCmd.SetArgs([]string{"history", "show", "1"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("history show failed: %v", err)
+	}
+}
+
+func TestPlanCommandInvalidMode(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer()
+	defer openAI.Close()
+
+	useFakes(t, testConfig(), cal, openAI)
+
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Write docs", "--mode", "turbo"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+
+	if got := len(openAI.Requests()); got != 0 {
+		t.Errorf("expected no OpenAI requests, got %d", got)
+	}
+}
+
+func TestPlanCommandSuggestions(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(aitest.Reply{Content: `{"blocks": [
+		{"type": "focus", "title": "Write docs", "start": "09:00", "end": "10:00"},
+		{"type": "focus", "title": "Prep for Design review", "start": "14:30", "end": "15:00", "suggested": true},
+		{"type": "focus", "title": "Notes for Design review", "start": "16:00", "end": "16:20", "suggested": true}
+	]}`})
+	defer openAI.Close()
+
+	cfg := testConfig()
+	date, _ := cfg.GetPlanningDate()
+	cal.Add("primary", &gcal.Event{
+		Summary:     "Design review",
+		Description: "New sync API #notes",
+		Start:       &gcal.EventDateTime{DateTime: date.Add(15 * time.Hour).Format(time.RFC3339)},
+		End:         &gcal.EventDateTime{DateTime: date.Add(16 * time.Hour).Format(time.RFC3339)},
+	})
+	useFakes(t, cfg, cal, openAI)
+
+	// Accept the prep block, decline the notes
+	rootCmd.SetIn(strings.NewReader("y\nn\n"))
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Write docs:L", "--suggest"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+
+	if prompt := openAI.Requests()[0].Messages[0].Content; !strings.Contains(prompt, "Design review (15:00 - 16:00) [needs notes]: New sync API #notes") {
+		t.Errorf("expected the meeting details in the prompt, got:\n%s", prompt)
+	}
+
+	var descriptions []string
+	for _, e := range cal.Events("primary")[1:] {
+		descriptions = append(descriptions, e.Description)
+	}
+	got := strings.Join(descriptions, "\n")
+	if !strings.Contains(got, "Prep for Design review") || strings.Contains(got, "Notes for Design review") {
+		t.Errorf("expected only the accepted suggestion to be created, got:\n%s", got)
+	}
+}
+
+func TestPlanCommandPrivacy(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(aitest.Reply{Content: `{"blocks": [
+		{"type": "focus", "title": "Redacted task 1", "start": "09:00", "end": "10:00"}
+	]}`})
+	defer openAI.Close()
+
+	cfg := testConfig()
+	cfg.Privacy = config.Privacy{MeetingTitles: config.PrivacyAnonymize, Denylist: []string{"salary"}}
+	date, _ := cfg.GetPlanningDate()
+	cal.AddMeeting("primary", "Customer call with Acme", date.Add(14*time.Hour), date.Add(15*time.Hour))
+	useFakes(t, cfg, cal, openAI)
+
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Salary review:L", "--show-prompt"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+
+	prompt := openAI.Requests()[0].Messages[0].Content
+	if strings.Contains(prompt, "Acme") || strings.Contains(prompt, "Salary") || !strings.Contains(prompt, "- Busy (14:00 - 15:00)") {
+		t.Errorf("expected meeting and task titles to be hidden, got prompt:\n%s", prompt)
+	}
+
+	restored := false
+	for _, e := range cal.Events("primary") {
+		restored = restored || strings.HasSuffix(e.Description, ": Salary review")
+	}
+	if !restored {
+		t.Errorf("expected the task title to be restored in the calendar")
+	}
+}
+
+func TestPlanCommandChat(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(
+		aitest.Reply{Content: `{"blocks": [{"type": "focus", "title": "Write docs", "start": "09:00", "end": "10:00"}]}`},
+		// Rejected: overlaps lunch
+		aitest.Reply{Content: `{"blocks": [{"type": "focus", "title": "Write docs", "start": "11:30", "end": "12:30"}]}`},
+		aitest.Reply{Content: `{"blocks": [{"type": "focus", "title": "Write docs", "start": "14:00", "end": "15:00"}]}`},
+	)
+	defer openAI.Close()
+
+	cfg := testConfig()
+	useFakes(t, cfg, cal, openAI)
+
+	rootCmd.SetIn(strings.NewReader("Just before lunch\nAfter lunch instead\ndone\n"))
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Write docs:L", "--chat"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+
+	requests := openAI.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 OpenAI requests, got %d", len(requests))
+	}
+	// The rejected answer is not part of the conversation
+	if got := len(requests[2].Messages); got != 3 || !strings.Contains(requests[2].Messages[2].Content, "After lunch instead") {
+		t.Errorf("unexpected last request: %+v", requests[2].Messages)
+	}
+
+	date, _ := cfg.GetPlanningDate()
+	found := false
+	for _, e := range cal.Events("primary") {
+		if strings.HasSuffix(e.Description, ": Write docs") {
+			found = e.Start.DateTime == date.Add(14*time.Hour).Format(time.RFC3339)
+		}
+	}
+	if !found {
+		t.Errorf("expected the refined block to be created at 14:00, got %+v", cal.Events("primary"))
+	}
+
+	store, _ := openHistory(cfg)
+	record, err := store.Get("1")
+	if err != nil {
+		t.Fatalf("expected the run to be recorded: %v", err)
+	}
+	if len(record.Chat) != 1 || record.Chat[0].Instruction != "After lunch instead" || record.Prompt != requests[0].Messages[0].Content {
+		t.Errorf("unexpected history record: %+v", record)
+	}
+}
+
+func TestPlanCommandAsk(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(
+		aitest.Reply{Content: `{"tasks": [
+			{"title": "Finish the RFC", "minutes": 120, "kind": "deep", "after": "", "before": "12:00"},
+			{"title": "Call Bob", "minutes": 15, "kind": "", "after": "15:00", "before": ""}
+		]}`},
+		aitest.Reply{Content: `{"blocks": [
+			{"type": "focus", "title": "Finish the RFC", "start": "09:00", "end": "11:00"},
+			{"type": "focus", "title": "Call Bob", "start": "15:00", "end": "15:15"}
+		]}`},
+	)
+	defer openAI.Close()
+
+	cfg := testConfig()
+	useFakes(t, cfg, cal, openAI)
+
+	rootCmd.SetIn(strings.NewReader("y\n"))
+	rootCmd.SetArgs([]string{"plan", "--ask", "finish the RFC (2h, before lunch), call Bob after 3pm"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+
+	requests := openAI.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 OpenAI requests, got %d", len(requests))
+	}
+	prompt := requests[1].Messages[0].Content
+	if !strings.Contains(prompt, "- Finish the RFC (120 minutes, deep work, finished by 12:00)") ||
+		!strings.Contains(prompt, "- Call Bob (15 minutes, not before 15:00)") {
+		t.Errorf("expected the parsed tasks in the plan prompt, got:\n%s", prompt)
+	}
+}
+
+func TestPlanCommandAskFallback(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(aitest.Reply{Content: `{"blocks": []}`})
+	defer openAI.Close()
+
+	cfg := testConfig()
+	cfg.Privacy = config.Privacy{TaskTitles: config.PrivacyRedact}
+	useFakes(t, cfg, cal, openAI)
+
+	// Declining the parsed tasks plans nothing
+	rootCmd.SetIn(strings.NewReader("n\n"))
+	rootCmd.SetArgs([]string{"plan", "--ask", "inbox 45m, deploy after 15:00"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Fatalf("plan command error = %v, want canceled", err)
+	}
+	if got := len(openAI.Requests()); got != 0 {
+		t.Errorf("expected private tasks to be parsed without OpenAI, got %d requests", got)
+	}
+}
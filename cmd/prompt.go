@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promptInput string
+	promptTasks string
+	promptMode  string
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Work with the plan prompt templates",
+	Long: `The plan prompt is rendered from text/template files embedded in the binary:
+prompt.tmpl includes system.tmpl, mode.tmpl, energy.tmpl, suggestions.tmpl and
+format.tmpl. refine.tmpl is sent for every change asked with plan --chat and
+tasks.tmpl reads the to-do list of plan --ask. A file with one of these names in
+the prompts directory (prompts_dir in config, a prompts directory next to
+config.json by default) replaces the embedded one, and mode-<name>.tmpl replaces
+mode.tmpl for that mode only.`,
+}
+
+var promptRenderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Print the plan prompt for a given input",
+	Long: `Print the prompt that plan would send for a given input, with the template
+overrides and the privacy policy applied. Nothing is sent and the calendar is not
+read.
+
+The input is either a JSON request file (--input) or tasks and a mode, planned
+over the configured work hours, lunch and energy profile. A request file looks like:
+
+  {
+    "date": "2030-01-07",
+    "work_hours": {"start": "09:00", "end": "17:00"},
+    "busy": [{"type": "meeting", "title": "Standup", "start": "10:00", "end": "10:15"}],
+    "tasks": "Write docs:L, Review PRs:S",
+    "mode": "normal"
+  }`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		var file ai.RequestFile
+		switch {
+		case promptInput != "":
+			data, err := os.ReadFile(promptInput)
+			if err != nil {
+				return fmt.Errorf("failed to read input: %w", err)
+			}
+			if err := json.Unmarshal(data, &file); err != nil {
+				return fmt.Errorf("failed to parse input: %w", err)
+			}
+		case promptTasks != "":
+			if file, err = requestFileFromConfig(cfg, promptTasks); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("--input or --tasks is required")
+		}
+		if promptMode != "" {
+			file.Mode = promptMode
+		}
+		if strings.TrimSpace(file.Mode) == "" {
+			file.Mode = cfg.DefaultMode
+		}
+
+		req, err := file.PlanRequest(cfg.Modes)
+		if err != nil {
+			return err
+		}
+		redacted, _, err := ai.Redact(req, cfg.Privacy)
+		if err != nil {
+			return err
+		}
+
+		dir, err := cfg.GetPromptsDir()
+		if err != nil {
+			return err
+		}
+		prompts, err := ai.LoadPrompts(dir)
+		if err != nil {
+			return err
+		}
+		text, err := prompts.Render(redacted)
+		if err != nil {
+			return err
+		}
+
+		if len(prompts.Overridden) > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Overridden in %s: %s\n", dir, strings.Join(prompts.Overridden, ", "))
+		}
+		fmt.Fprint(cmd.OutOrStdout(), text)
+		return nil
+	},
+}
+
+// requestFileFromConfig builds a request for tasks over the configured day.
+func requestFileFromConfig(cfg *config.Config, tasks string) (ai.RequestFile, error) {
+	date, err := cfg.GetPlanningDate()
+	if err != nil {
+		return ai.RequestFile{}, fmt.Errorf("failed to parse planning date: %w", err)
+	}
+	file := ai.RequestFile{
+		Date:      date.Format(config.DateFormat),
+		WorkHours: cfg.WorkHours,
+		Tasks:     tasks,
+		Energy:    cfg.Energy,
+	}
+	if cfg.LunchTime.Start != "" && cfg.LunchTime.End != "" {
+		file.Busy = append(file.Busy, ai.RequestBlock{
+			Type:  planner.BlockTypeLunch,
+			Title: "Lunch",
+			Start: cfg.LunchTime.Start,
+			End:   cfg.LunchTime.End,
+		})
+	}
+	for _, r := range cfg.Routines {
+		if !r.AppliesOn(date) {
+			continue
+		}
+		start, err := planner.ParseTimeOnDate(r.Time, date)
+		if err != nil {
+			return ai.RequestFile{}, fmt.Errorf("invalid time for routine %s: %w", r.Title, err)
+		}
+		file.Busy = append(file.Busy, ai.RequestBlock{
+			Type:  planner.BlockTypeRoutine,
+			Title: r.Title,
+			Start: r.Time,
+			End:   start.Add(r.Duration()).Format(planner.TimeFormat),
+		})
+	}
+	return file, nil
+}
+
+func init() {
+	rootCmd.AddCommand(promptCmd)
+	promptCmd.AddCommand(promptRenderCmd)
+	promptRenderCmd.Flags().StringVarP(&promptInput, "input", "i", "", "JSON request file to render the prompt for")
+	promptRenderCmd.Flags().StringVarP(&promptTasks, "tasks", "t", "", "Comma-separated list of tasks, planned over the configured day")
+	promptRenderCmd.Flags().StringVarP(&promptMode, "mode", "m", "", "Planning mode (default: the mode of the input, then config)")
+}
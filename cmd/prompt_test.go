@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+)
+
+func TestPromptRenderCommand(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "system.tmpl"), []byte("You plan days for a night owl.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "day.json")
+	if err := os.WriteFile(input, []byte(`{
+		"date": "2030-01-07",
+		"work_hours": {"start": "09:00", "end": "17:00"},
+		"busy": [{"type": "meeting", "title": "Customer call with Acme", "start": "10:00", "end": "11:00"}],
+		"tasks": "Write docs:L",
+		"mode": "crunch"
+	}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.PromptsDir = dir
+	cfg.Privacy = config.Privacy{MeetingTitles: config.PrivacyAnonymize}
+	origLoad := loadConfig
+	t.Cleanup(func() {
+		loadConfig = origLoad
+		promptInput, promptTasks, promptMode = "", "", ""
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	})
+	loadConfig = func() (*config.Config, error) { return cfg, nil }
+
+	var out, errOut bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+	rootCmd.SetArgs([]string{"prompt", "render", "--input", input})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("prompt render failed: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"You plan days for a night owl.", "- Busy (10:00 - 11:00)", "Write docs (60 minutes)", "CRUNCH"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in the prompt, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Acme") {
+		t.Errorf("expected the privacy policy to be applied, got:\n%s", got)
+	}
+	if !strings.Contains(errOut.String(), "system.tmpl") {
+		t.Errorf("expected the overridden templates to be listed, got %q", errOut.String())
+	}
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/history"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replanFrom  string
+	replanTasks string
+	replanMode  string
+	replanYes   bool
+)
+
+var replanCmd = &cobra.Command{
+	Use:   "replan",
+	Short: "Plan the rest of the day again",
+	Long: `Plan the rest of the day again from --from on, in place of the blocks planned
+earlier. Blocks that already started are kept. For every focus block that is
+over you are asked whether its task is done; done tasks are dropped and the
+others are shortened by the time already spent. Blocks created by Barely In
+Charge after --from make room for the new plan; a block that comes back with the
+same title is moved instead of created again, so its event stays the same.
+
+Tasks and mode default to the latest plan of the day in history.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		planningDate, err := cfg.GetPlanningDate()
+		if err != nil {
+			return fmt.Errorf("failed to parse planning date: %w", err)
+		}
+		from, err := parseFrom(replanFrom, planningDate, time.Now())
+		if err != nil {
+			return err
+		}
+
+		previous, hasPrevious := latestPlan(cfg, planningDate)
+
+		var taskList []planner.Task
+		switch {
+		case replanTasks != "":
+			taskList = planner.ParseTaskList(replanTasks)
+		case hasPrevious:
+			taskList = previous.PlannerTasks()
+		default:
+			return fmt.Errorf("--tasks flag is required: no plan for %s in history", planningDate.Format(config.DateFormat))
+		}
+
+		modeName := strings.TrimSpace(replanMode)
+		if modeName == "" && hasPrevious {
+			modeName = previous.Mode
+		}
+		if modeName == "" {
+			modeName = cfg.DefaultMode
+		}
+		selectedMode, err := cfg.GetMode(modeName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("🔁 Re-planning %s from %s\n", planningDate.Format("Monday, January 2, 2006"), from.Format(planner.TimeFormat))
+		fmt.Printf("Mode: %s\n", selectedMode.Name)
+
+		ctx := cmd.Context()
+		calClient, err := authenticateCalendar(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		service := planning.NewService(cfg, calClient, newPlanner(cfg))
+
+		replan, err := service.PrepareReplan(ctx, planningDate, from)
+		if err != nil {
+			return err
+		}
+		printReplan(replan)
+
+		done := map[string]bool{}
+		progress := map[string]string{}
+		in := bufio.NewReader(cmd.InOrStdin())
+		for _, e := range replan.Elapsed() {
+			title := planning.BlockTitle(e)
+			progress[e.ID] = history.ProgressUnknown
+			if replanYes {
+				continue
+			}
+			answer, err := prompt(in, fmt.Sprintf("  Done with %s (%s - %s)? [y/N]: ", title,
+				e.Start.Format(planner.TimeFormat), e.End.Format(planner.TimeFormat)))
+			if err != nil {
+				return err
+			}
+			if a := strings.ToLower(answer); a == "y" || a == "yes" {
+				progress[e.ID] = history.ProgressDone
+				done[planner.TaskTitle(title)] = true
+			}
+		}
+
+		remaining := replan.Remaining(taskList, done)
+		if len(remaining) == 0 {
+			fmt.Println("\n✅ Nothing left to re-plan")
+			return nil
+		}
+		fmt.Printf("\nRemaining tasks (%d):\n", len(remaining))
+		for i, task := range remaining {
+			fmt.Printf("  %d. %s (%d min)\n", i+1, task.Title, int(task.Duration.Minutes()))
+		}
+
+		record := history.NewRecord(planningDate, cfg.Calendar, selectedMode, taskList)
+
+		fmt.Println("\n🤖 Generating plan with AI...")
+		plan, err := service.Generate(ctx, replan.Day, selectedMode, remaining)
+		if err != nil {
+			saveHistory(cfg, record, err)
+			return err
+		}
+		record.SetPlan(plan)
+
+		printPlan(plan)
+
+		fmt.Println("\n📝 Updating calendar...")
+		tx, changes, err := service.ApplyReplan(ctx, replan, plan)
+		for _, c := range changes {
+			fmt.Printf("  • %s\n", c)
+		}
+		printTransaction(tx)
+		record.SetTransaction(tx)
+		record.SetKept(replan.Kept, progress)
+		saveHistory(cfg, record, err)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("\n✅ Rest of the day re-planned!")
+		return nil
+	},
+}
+
+// parseFrom resolves --from on date: "now" or a time like 13:30. "now" only
+// makes sense when planning today.
+func parseFrom(value string, date, now time.Time) (time.Time, error) {
+	if strings.EqualFold(value, "now") {
+		if now.Format(config.DateFormat) != date.Format(config.DateFormat) {
+			return time.Time{}, fmt.Errorf("--from now only works when planning today; pass a time like 13:30")
+		}
+		return now, nil
+	}
+	from, err := planner.ParseTimeOnDate(value, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --from (expected now or HH:MM): %w", err)
+	}
+	return from, nil
+}
+
+// latestPlan returns the most recent applied plan for date. History errors are
+// treated as no plan.
+func latestPlan(cfg *config.Config, date time.Time) (history.Record, bool) {
+	store, err := openHistory(cfg)
+	if err != nil {
+		return history.Record{}, false
+	}
+	records, err := store.List()
+	if err != nil {
+		return history.Record{}, false
+	}
+	return latestAppliedRecord(records, date.Format(config.DateFormat))
+}
+
+func printReplan(r *planning.Replan) {
+	for _, e := range r.Kept {
+		fmt.Printf("  📌 Keeping %s (%s - %s)\n", planning.EventLabel(e),
+			e.Start.Format(planner.TimeFormat), e.End.Format(planner.TimeFormat))
+	}
+	if len(r.Replaced) > 0 {
+		fmt.Printf("  Re-planning %d block(s) from %s\n", len(r.Replaced), r.Day.WorkStart.Format(planner.TimeFormat))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(replanCmd)
+	replanCmd.Flags().StringVar(&replanFrom, "from", "now", "Re-plan from this time: now or HH:MM")
+	replanCmd.Flags().StringVarP(&replanTasks, "tasks", "t", "", "Comma-separated list of tasks (default: the tasks of the latest plan of the day)")
+	replanCmd.Flags().StringVarP(&replanMode, "mode", "m", "", "Planning mode (default: the mode of the latest plan of the day, then config)")
+	replanCmd.Flags().BoolVar(&showPrompt, "show-prompt", false, "Print the prompt exactly as it is sent to the AI, after the privacy policy is applied")
+	replanCmd.Flags().BoolVarP(&replanYes, "yes", "y", false, "Don't ask about elapsed focus blocks; their progress is recorded as unknown")
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+)
+
+func TestReplanMovesFutureBlocks(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(
+		aitest.Reply{Content: `{"blocks": [
+			{"type": "focus", "title": "Write docs", "start": "09:00", "end": "10:00"},
+			{"type": "focus", "title": "Review PRs", "start": "14:00", "end": "14:30"}
+		]}`},
+		aitest.Reply{Content: `{"blocks": [
+			{"type": "focus", "title": "Review PRs", "start": "11:00", "end": "11:30"}
+		]}`},
+	)
+	defer openAI.Close()
+
+	cfg := testConfig()
+	useFakes(t, cfg, cal, openAI)
+	t.Cleanup(func() { replanFrom, replanTasks, replanMode, replanYes = "now", "", "", false })
+
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Write docs:L, Review PRs:M"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+	before := cal.Events("primary")
+
+	rootCmd.SetIn(strings.NewReader("y\n"))
+	rootCmd.SetArgs([]string{"replan", "--from", "10:40"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("replan command failed: %v", err)
+	}
+
+	requests := openAI.Requests()
+	prompt := requests[len(requests)-1].Messages[0].Content
+	if strings.Contains(prompt, "Write docs (") || !strings.Contains(prompt, "Review PRs (30 minutes)") {
+		t.Errorf("expected only Review PRs to be planned again, got prompt:\n%s", prompt)
+	}
+
+	after := cal.Events("primary")
+	if len(after) != len(before) {
+		t.Fatalf("expected %d events after replan, got %d", len(before), len(after))
+	}
+	for i, e := range after {
+		if e.Id != before[i].Id {
+			t.Errorf("expected event IDs to stay the same, got %s for %s", e.Id, before[i].Id)
+		}
+		if strings.HasSuffix(e.Description, "Review PRs") && !strings.HasPrefix(e.Start.DateTime, "2030-01-07T11:00") {
+			t.Errorf("expected Review PRs to move to 11:00, got %s", e.Start.DateTime)
+		}
+	}
+
+	store, err := openHistory(cfg)
+	if err != nil {
+		t.Fatalf("openHistory() error: %v", err)
+	}
+	record, err := store.Get("2")
+	if err != nil {
+		t.Fatalf("expected the replan to be recorded: %v", err)
+	}
+	if len(record.Blocks) != 3 || record.Blocks[0].Progress != "done" || len(record.FocusBlocks()) != 2 {
+		t.Errorf("unexpected history record: %+v", record.Blocks)
+	}
+	if got := record.Blocks[1].Start; !got.Equal(time.Date(2030, 1, 7, 11, 0, 0, 0, got.Location())) {
+		t.Errorf("expected Review PRs at 11:00 in history, got %v", got)
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/report"
+	"github.com/spf13/cobra"
+)
+
+// currentWeek is the --week value for the week containing today.
+const currentWeek = "current"
+
+var (
+	reportWeek   string
+	reportFormat string
+	reportOutput string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Weekly report on focus time, meeting load and fragmentation",
+	Long: `Read a week of your calendar and report meeting hours, focus hours created by
+Barely In Charge, the largest block of time without meetings per day and the number
+of context switches, compared to the targets in config.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if reportFormat != "table" && reportFormat != "json" && reportFormat != "html" {
+			return fmt.Errorf("invalid format: %s (valid formats: table, json, html)", reportFormat)
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		date := time.Now()
+		if reportWeek != currentWeek {
+			parsed, err := time.ParseInLocation(config.DateFormat, reportWeek, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid --week (expected a date in the week as YYYY-MM-DD): %w", err)
+			}
+			date = parsed
+		}
+		days := report.Week(date)
+
+		ctx := cmd.Context()
+		calClient, err := newCalendar(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to authenticate with Google Calendar: %w", err)
+		}
+		events, err := calClient.FetchMeetings(ctx, cfg.Calendar, days[0], days[len(days)-1].AddDate(0, 0, 1))
+		if err != nil {
+			return fmt.Errorf("failed to fetch events: %w", err)
+		}
+
+		r, err := report.Build(events, days, cfg.WorkHours, cfg.Targets)
+		if err != nil {
+			return err
+		}
+
+		var out io.Writer = os.Stdout
+		if reportOutput != "" {
+			f, err := os.Create(reportOutput)
+			if err != nil {
+				return fmt.Errorf("failed to create report file: %w", err)
+			}
+			defer func() {
+				if closeErr := f.Close(); closeErr != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to close report file: %v\n", closeErr)
+				}
+			}()
+			out = f
+		}
+
+		switch reportFormat {
+		case "json":
+			err = r.WriteJSON(out)
+		case "html":
+			err = r.WriteHTML(out)
+		default:
+			err = r.WriteTable(out)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+
+		if reportOutput != "" {
+			fmt.Printf("📊 Report written to %s\n", reportOutput)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVar(&reportWeek, "week", currentWeek, "Week to report on, as any date in it (YYYY-MM-DD); without a value the current week")
+	reportCmd.Flags().Lookup("week").NoOptDefVal = currentWeek
+	reportCmd.Flags().StringVarP(&reportFormat, "format", "f", "table", "Output format: table, json or html")
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "Write the report to a file instead of stdout")
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+)
+
+func TestReportCommand(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(aitest.Reply{Content: `{"blocks": [
+		{"type": "focus", "title": "Write docs", "start": "09:00", "end": "11:00"}
+	]}`})
+	defer openAI.Close()
+
+	cfg := testConfig()
+	cfg.Targets.FocusHoursPerWeek = 1
+	date, _ := cfg.GetPlanningDate()
+	cal.AddMeeting("primary", "Standup", date.Add(11*time.Hour), date.Add(11*time.Hour+30*time.Minute))
+
+	useFakes(t, cfg, cal, openAI)
+	t.Cleanup(func() { reportWeek, reportFormat, reportOutput = currentWeek, "table", "" })
+
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Write docs:XL"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+
+	output := filepath.Join(t.TempDir(), "report.json")
+	rootCmd.SetArgs([]string{"report", "--week=2030-01-09", "--format", "json", "--output", output})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("report command failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	for _, want := range []string{`"from": "2030-01-07"`, `"meeting_hours": 0.5`, `"focus_hours": 2`, `"met": true`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("report is missing %s:\n%s", want, data)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/history"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/spf13/cobra"
+)
+
+var reviewDate string
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Review how the day's focus blocks went",
+	Long: `Walk through the focus blocks created for a day and record whether each was done,
+partially done or skipped, and how long it really took. Unfinished tasks are carried
+over to the next workday's plan, and the answers teach the planner how long your
+XS/S/M/L/XL tasks actually take.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		store, err := openHistory(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to open history: %w", err)
+		}
+
+		date := reviewDate
+		if date == "" {
+			date = time.Now().Format(config.DateFormat)
+		} else if _, err := time.Parse(config.DateFormat, date); err != nil {
+			return fmt.Errorf("invalid --date (expected YYYY-MM-DD): %w", err)
+		}
+
+		records, err := store.List()
+		if err != nil {
+			return err
+		}
+		record, ok := latestAppliedRecord(records, date)
+		if !ok {
+			return fmt.Errorf("no plan with focus blocks found for %s", date)
+		}
+
+		focus := record.FocusBlocks()
+		fmt.Printf("📋 Reviewing plan #%s for %s (%d focus blocks)\n", record.ID, date, len(focus))
+
+		in := bufio.NewReader(cmd.InOrStdin())
+		answers := make([]history.ReviewedBlock, 0, len(focus))
+		for i, block := range focus {
+			planned := int(block.End.Sub(block.Start).Minutes())
+			fmt.Printf("\n%d/%d 🎯 %s (%s - %s)\n", i+1, len(focus), block.Title,
+				block.Start.Local().Format(planner.TimeFormat), block.End.Local().Format(planner.TimeFormat))
+
+			answer, err := askReview(in, planned)
+			if err != nil {
+				return err
+			}
+			answer.Title = block.Title
+			answer.Task = record.TaskFor(block.Title)
+			answers = append(answers, answer)
+		}
+
+		review, err := history.NewReview(record, answers)
+		if err != nil {
+			return err
+		}
+		if err := store.AppendReview(review); err != nil {
+			return fmt.Errorf("failed to save review: %w", err)
+		}
+
+		printReview(review)
+
+		reviews, err := store.Reviews()
+		if err != nil {
+			return err
+		}
+		printSizeFactors(history.SizeFactors(reviews))
+
+		return nil
+	},
+}
+
+// latestAppliedRecord returns the most recent run for date that created focus blocks.
+func latestAppliedRecord(records []history.Record, date string) (history.Record, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Date == date && len(records[i].FocusBlocks()) > 0 {
+			return records[i], true
+		}
+	}
+	return history.Record{}, false
+}
+
+func askReview(in *bufio.Reader, planned int) (history.ReviewedBlock, error) {
+	answer := history.ReviewedBlock{PlannedMinutes: planned}
+
+	for answer.Outcome == "" {
+		line, err := prompt(in, "  Done, partial or skipped? [d/p/s]: ")
+		if err != nil {
+			return answer, err
+		}
+		switch strings.ToLower(line) {
+		case "d", "done":
+			answer.Outcome = history.OutcomeDone
+		case "p", "partial":
+			answer.Outcome = history.OutcomePartial
+		case "s", "skipped", "skip":
+			answer.Outcome = history.OutcomeSkipped
+			return answer, nil
+		}
+	}
+
+	for {
+		line, err := prompt(in, fmt.Sprintf("  How many minutes did you spend on it? [%d]: ", planned))
+		if err != nil {
+			return answer, err
+		}
+		if line == "" {
+			answer.ActualMinutes = planned
+			return answer, nil
+		}
+		if minutes, err := strconv.Atoi(line); err == nil && minutes >= 0 {
+			answer.ActualMinutes = minutes
+			return answer, nil
+		}
+	}
+}
+
+func prompt(in *bufio.Reader, question string) (string, error) {
+	fmt.Print(question)
+	line, err := in.ReadString('\n')
+	if errors.Is(err, io.EOF) && line == "" {
+		return "", fmt.Errorf("aborted: no more input")
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func printReview(review *history.Review) {
+	fmt.Println("\n✅ Review saved:")
+	for _, task := range review.Tasks {
+		fmt.Printf("  %s %s (planned %d min, spent %d min)\n", outcomeIcon(task.Outcome), task.Title, task.Minutes, task.ActualMinutes)
+	}
+
+	if len(review.CarryOver) > 0 {
+		fmt.Printf("\n↪️  Carried over to %s:\n", review.CarryOverTo)
+		for _, task := range review.CarryOver {
+			fmt.Printf("  - %s (%d min)\n", task.Title, task.Minutes)
+		}
+	}
+}
+
+func printSizeFactors(factors map[string]history.SizeFactor) {
+	if len(factors) == 0 {
+		return
+	}
+
+	sizes := make([]string, 0, len(factors))
+	for size := range factors {
+		sizes = append(sizes, size)
+	}
+	slices.SortFunc(sizes, func(a, b string) int {
+		da, _ := planner.SizeDuration(a)
+		db, _ := planner.SizeDuration(b)
+		return int(da - db)
+	})
+
+	fmt.Println("\n📏 Learned task sizes:")
+	for _, size := range sizes {
+		f := factors[size]
+		d, _ := planner.SizeDuration(size)
+		scaled := planner.ScaleSizes([]planner.Task{{Duration: d}}, map[string]float64{size: f.Factor})[0]
+		fmt.Printf("  %s: %d min → %d min (%.2fx, from %d tasks)\n", size,
+			int(d.Minutes()), int(scaled.Duration.Minutes()), f.Factor, f.Samples)
+	}
+}
+
+func outcomeIcon(outcome string) string {
+	switch outcome {
+	case history.OutcomeDone:
+		return "✓"
+	case history.OutcomePartial:
+		return "◐"
+	default:
+		return "✗"
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+	reviewCmd.Flags().StringVarP(&reviewDate, "date", "d", "", "Date to review in YYYY-MM-DD format (default today)")
+}
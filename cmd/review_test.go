@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+)
+
+func TestReviewCarriesOverUnfinishedTasks(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(aitest.Reply{Content: `{"blocks": [
+		{"type": "focus", "title": "Write docs", "start": "09:00", "end": "10:00"},
+		{"type": "focus", "title": "Review PRs", "start": "10:00", "end": "10:15"}
+	]}`})
+	defer openAI.Close()
+
+	cfg := testConfig()
+	useFakes(t, cfg, cal, openAI)
+	t.Cleanup(func() { reviewDate = "" })
+
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Write docs:L, Review PRs:S"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+
+	// Write docs done in 45 minutes, Review PRs only started
+	rootCmd.SetIn(strings.NewReader("d\n45\nmaybe\np\n5\n"))
+	rootCmd.SetArgs([]string{"review", "--date", "2030-01-07"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("review command failed: %v", err)
+	}
+
+	store, err := openHistory(cfg)
+	if err != nil {
+		t.Fatalf("openHistory() error: %v", err)
+	}
+	reviews, err := store.Reviews()
+	if err != nil || len(reviews) != 1 {
+		t.Fatalf("expected one review, got %v, %v", reviews, err)
+	}
+	if got := reviews[0].Tasks[0]; got.Outcome != "done" || got.ActualMinutes != 45 {
+		t.Errorf("unexpected outcome for Write docs: %+v", got)
+	}
+
+	cfg.Date = "2030-01-08"
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Write docs:L"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+
+	requests := openAI.Requests()
+	if !strings.Contains(requests[len(requests)-1].Messages[0].Content, "Review PRs (15 minutes)") {
+		t.Errorf("expected Review PRs to be carried over into the next plan")
+	}
+}
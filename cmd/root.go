@@ -1,6 +1,15 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
+	"github.com/spf13/cobra"
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "barely-incharge",
@@ -10,6 +19,34 @@ schedule focus blocks and breaks in your Google Calendar based on
 your meetings and tasks for the day.`,
 }
 
+// Execute runs the CLI. Ctrl-C cancels in-flight API calls.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
+	if hint := errorHint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "💡 %s\n", hint)
+	}
+	return err
+}
+
+// errorHint suggests what to do about an error returned by a command.
+func errorHint(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "Canceled. Blocks created before the interruption were removed unless --keep-partial was set."
+	case errors.Is(err, httpx.ErrAuth):
+		return "Check openai_api_key in config.json, or delete token.json to sign in to Google Calendar again."
+	case errors.Is(err, httpx.ErrQuota):
+		return "You hit a rate limit or ran out of quota. Wait a few minutes or check your plan and billing."
+	case errors.Is(err, httpx.ErrTransient):
+		return "The service is temporarily unavailable. Try again later, or raise http.max_retries / http.timeout_seconds in config.json."
+	case errors.Is(err, httpx.ErrBadRequest):
+		return "The request was rejected. Check the calendar ID and openai_model in config.json."
+	default:
+		return ""
+	}
 }
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var serveAddr string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the plan API and a web page to adjust plans",
+	Long: `Serve a local REST/JSON API for the plan pipeline and a web page that shows the
+day timeline, lets you drag blocks to adjust them before applying and regenerates
+the plan on demand.
+
+  POST   /plan          preview a plan, or apply it with "apply": true
+  GET    /plans/{date}  plan runs recorded for a date (YYYY-MM-DD)
+  DELETE /plans/{id}    delete the calendar events created by a plan run
+
+The API has no authentication, so it listens on localhost by default. It only
+accepts JSON bodies and rejects requests from other web pages.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		ctx := cmd.Context()
+		calClient, err := authenticateCalendar(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		store, err := openHistory(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to open history: %w", err)
+		}
+
+		listener, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", serveAddr, err)
+		}
+
+		srv := &http.Server{
+			Handler:           server.New(cfg, calClient, newPlanner(cfg), store).Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		}
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Printf("\n🌐 Serving on http://%s (Ctrl-C to stop)\n", listener.Addr())
+		if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server failed: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080", "Address to listen on")
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/team"
+	"github.com/spf13/cobra"
+)
+
+var (
+	teamDate    string
+	teamMinutes int
+	teamCreate  bool
+)
+
+var teamCmd = &cobra.Command{
+	Use:   "team",
+	Short: "Find a shared focus window with your teammates",
+	Long: `Read the free/busy information of the teammates listed under "team" in config,
+find the times when everyone is within work hours and free, and propose a shared
+focus block. With --create the block is added to every calendar you can write to;
+teammates only need to share free/busy information to be included in the search.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if len(cfg.Team.Members) == 0 {
+			return fmt.Errorf("no teammates configured (add them under team.members in config.json)")
+		}
+
+		date, err := cfg.GetPlanningDate()
+		if err != nil {
+			return err
+		}
+		if teamDate != "" {
+			if date, err = time.ParseInLocation(config.DateFormat, teamDate, time.Local); err != nil {
+				return fmt.Errorf("invalid --date (expected YYYY-MM-DD): %w", err)
+			}
+		}
+		length := cfg.Team.FocusLength()
+		if teamMinutes > 0 {
+			length = time.Duration(teamMinutes) * time.Minute
+		}
+
+		members, err := team.Members(cfg, date)
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		client, err := newCalendar(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to authenticate with Google Calendar: %w", err)
+		}
+		cal, ok := client.(team.Calendar)
+		if !ok {
+			return fmt.Errorf("the calendar does not support free/busy queries")
+		}
+
+		result, err := team.Find(ctx, cal, members)
+		if err != nil {
+			return fmt.Errorf("failed to read free/busy information: %w", err)
+		}
+
+		fmt.Printf("👥 Team availability for %s\n", date.Format("Monday, January 2, 2006"))
+		for _, m := range result.Members {
+			fmt.Printf("  %s: %s - %s", m.Name, formatLocal(m.WorkStart), formatLocal(m.WorkEnd))
+			if m.Err != nil {
+				fmt.Printf(" (⚠️  busy time unavailable: %v)", m.Err)
+			} else {
+				fmt.Printf(", busy blocks: %d", len(m.Busy))
+			}
+			fmt.Println()
+		}
+
+		if len(result.Windows) == 0 {
+			fmt.Println("\n😕 No shared free time within everyone's work hours")
+			return nil
+		}
+		fmt.Println("\nShared free time:")
+		for _, w := range result.Windows {
+			fmt.Printf("  %s - %s (%d minutes)\n", formatLocal(w.Start), formatLocal(w.End), int(w.End.Sub(w.Start).Minutes()))
+		}
+
+		block, ok := result.Propose(length)
+		if !ok {
+			fmt.Printf("\n😕 No shared window of at least %d minutes\n", int(team.MinWindow.Minutes()))
+			return nil
+		}
+		fmt.Printf("\n🎯 Proposed %s: %s - %s\n", cfg.Team.Title(), formatLocal(block.Start), formatLocal(block.End))
+		if block.End.Sub(block.Start) < length {
+			fmt.Printf("   (shorter than the %d minutes asked for; this is the longest shared window)\n", int(length.Minutes()))
+		}
+
+		if !teamCreate {
+			fmt.Println("\nRun with --create to add it to the calendars you can write to.")
+			return nil
+		}
+
+		fmt.Println()
+		failed := 0
+		for _, c := range team.Create(ctx, cal, result.Members, block, cfg.Team.Title()) {
+			switch {
+			case c.Err != nil:
+				failed++
+				fmt.Printf("  ❌ %s: %v\n", c.Member.Name, c.Err)
+			case c.Skipped:
+				fmt.Printf("  ⏭️  %s: no write access, ask them to add it\n", c.Member.Name)
+			default:
+				fmt.Printf("  ✅ %s\n", c.Member.Name)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to create the block on %d of %d calendars", failed, len(result.Members))
+		}
+		return nil
+	},
+}
+
+func formatLocal(t time.Time) string {
+	return t.Local().Format(planner.TimeFormat)
+}
+
+func init() {
+	rootCmd.AddCommand(teamCmd)
+	teamCmd.Flags().StringVar(&teamDate, "date", "", "Date to search, as YYYY-MM-DD (default: the planning date)")
+	teamCmd.Flags().IntVar(&teamMinutes, "minutes", 0, "Length of the shared focus block in minutes (default: team.focus_minutes)")
+	teamCmd.Flags().BoolVar(&teamCreate, "create", false, "Create the proposed block on every calendar you can write to")
+}
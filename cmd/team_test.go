@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+)
+
+func TestTeamCommandCreate(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer()
+	defer openAI.Close()
+
+	cfg := testConfig()
+	cfg.Team = config.Team{Members: []config.TeamMember{
+		{Name: "Ana", Calendar: "ana@example.com"},
+		{Name: "Bo", Calendar: "bo@example.com"},
+		{Name: "Cy", Calendar: "cy@example.com"},
+	}}
+	date, _ := cfg.GetPlanningDate()
+	cal.AddMeeting("primary", "Standup", date.Add(9*time.Hour), date.Add(10*time.Hour))
+	cal.AddMeeting("ana@example.com", "1:1", date.Add(11*time.Hour), date.Add(12*time.Hour))
+	cal.SetAccessRole("bo@example.com", "freeBusyReader")
+	cal.SetAccessRole("cy@example.com", "")
+
+	useFakes(t, cfg, cal, openAI)
+	t.Cleanup(func() { teamDate, teamMinutes, teamCreate = "", 0, false })
+
+	rootCmd.SetArgs([]string{"team", "--minutes", "150", "--create"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("team command failed: %v", err)
+	}
+
+	for _, id := range []string{"primary", "ana@example.com"} {
+		events := cal.Events(id)
+		last := events[len(events)-1]
+		if len(events) != 2 || last.Summary != config.DefaultTeamFocusTitle || last.Start.DateTime != date.Add(12*time.Hour).Format(time.RFC3339) {
+			t.Errorf("expected the shared block at 12:00 on %s, got %+v", id, last)
+		}
+	}
+	if events := cal.Events("bo@example.com"); len(events) != 0 {
+		t.Errorf("expected nothing on a calendar without write access, got %d events", len(events))
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Alvkoen/barely-incharge/internal/history"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+	"github.com/Alvkoen/barely-incharge/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tuiTasks string
+	tuiMode  string
+	tuiFresh bool
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Plan your day in a full-screen timeline you can edit before applying",
+	Long: `Generate a plan and show the day as a vertical timeline with meetings, lunch,
+focus and breaks. Move, resize, rename or delete blocks with the keyboard, have
+the AI plan a single gap again, and apply the result to the calendar.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		modeName := strings.TrimSpace(tuiMode)
+		if modeName == "" {
+			modeName = cfg.DefaultMode
+		}
+		selectedMode, err := cfg.GetMode(modeName)
+		if err != nil {
+			return err
+		}
+
+		taskList := planner.ParseTaskList(tuiTasks)
+		if len(taskList) == 0 {
+			return fmt.Errorf("--tasks flag is required")
+		}
+
+		planningDate, err := cfg.GetPlanningDate()
+		if err != nil {
+			return fmt.Errorf("failed to parse planning date: %w", err)
+		}
+		if !tuiFresh {
+			var adjustments []string
+			taskList, adjustments = applyReviews(cfg, planningDate, taskList)
+			for _, note := range adjustments {
+				fmt.Println(note)
+			}
+		}
+
+		ctx := cmd.Context()
+		calClient, err := authenticateCalendar(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		service := planning.NewService(cfg, calClient, newPlanner(cfg))
+
+		day, err := service.PrepareDay(ctx, planningDate)
+		if err != nil {
+			return err
+		}
+
+		record := history.NewRecord(planningDate, cfg.Calendar, selectedMode, taskList)
+
+		fmt.Println("\n🤖 Generating plan with AI...")
+		plan, err := service.Generate(ctx, day, selectedMode, taskList)
+		if err != nil {
+			saveHistory(cfg, record, err)
+			return err
+		}
+
+		// Every apply from the day view is recorded once the screen is closed, so
+		// history output doesn't draw over it
+		var records []*history.Record
+		apply := func(ctx context.Context, edited *planning.Plan) (*planning.Transaction, error) {
+			r := history.NewRecord(planningDate, cfg.Calendar, selectedMode, taskList)
+			r.SetPlan(edited)
+			tx, err := service.Apply(ctx, edited, planning.ApplyOptions{})
+			r.SetTransaction(tx)
+			r.SetError(err)
+			records = append(records, r)
+			return tx, err
+		}
+
+		program := tea.NewProgram(tui.New(ctx, service, plan, apply),
+			tea.WithAltScreen(), tea.WithContext(ctx))
+		final, err := program.Run()
+		if err != nil {
+			return fmt.Errorf("failed to run day view: %w", err)
+		}
+
+		for _, r := range records {
+			saveHistory(cfg, r, nil)
+		}
+
+		tx, applyErr := final.(tui.Model).Result()
+		if tx == nil {
+			fmt.Println("No changes made to the calendar")
+			return nil
+		}
+		fmt.Println("\n📝 Calendar changes:")
+		printTransaction(tx)
+		if applyErr != nil {
+			return applyErr
+		}
+
+		fmt.Println("\n✅ Successfully created all blocks in calendar!")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+	tuiCmd.Flags().StringVarP(&tuiTasks, "tasks", "t", "", "Comma-separated list of tasks to accomplish (required)")
+	tuiCmd.Flags().StringVarP(&tuiMode, "mode", "m", "", "Planning mode: crunch, normal, saver, or a mode defined in config (default from config)")
+	tuiCmd.Flags().BoolVar(&tuiFresh, "fresh", false, "Ignore tasks carried over by review and the task sizes learned from reviews")
+	if err := tuiCmd.MarkFlagRequired("tasks"); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+	"github.com/Alvkoen/barely-incharge/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+// watchLogFile is the log of the changes made by watch, in the data directory.
+const watchLogFile = "watch.log"
+
+const (
+	// channelRenewBefore is how long before it expires a push channel is renewed.
+	channelRenewBefore = time.Hour
+	// channelRenewRetry is how long to wait after a failed renewal before trying again.
+	channelRenewRetry = time.Minute
+)
+
+var (
+	watchInterval    time.Duration
+	watchDryRun      bool
+	watchOnce        bool
+	watchWebhookURL  string
+	watchWebhookAddr string
+)
+
+// eventWatcher is implemented by calendars that support push notifications.
+type eventWatcher interface {
+	WatchEvents(ctx context.Context, calendarID, channelID, address, token string) (*calendar.Channel, error)
+	StopChannel(ctx context.Context, ch *calendar.Channel) error
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Move planned blocks out of the way when meetings are added",
+	Long: `Watch the calendar and re-plan the rest of the day when a meeting is added on
+top of a focus or break block created by Barely In Charge. The conflicting block
+and the blocks after it are pushed past the meeting, shrunk or removed; blocks
+before it are left alone.
+
+The calendar is polled every --interval. With --webhook-url, Google also sends a
+push notification on every change to a local receiver on --webhook-addr; the URL
+must be a public HTTPS address forwarded to it, e.g. by a tunnel. Every change is
+logged to stdout and to watch.log in the data directory.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if watchInterval < time.Minute {
+			return fmt.Errorf("--interval must be at least 1m")
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		ctx := cmd.Context()
+		calClient, err := authenticateCalendar(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		logOut, closeLog, err := openWatchLog(cfg.GetDataDir)
+		if err != nil {
+			return err
+		}
+		defer closeLog()
+		logger := log.New(logOut, "", log.LstdFlags)
+
+		service := planning.NewService(cfg, calClient, newPlanner(cfg))
+		w := watch.New(service, cfg.GetPlanningDate, watchInterval, planning.ReconcileOptions{DryRun: watchDryRun}, logger)
+
+		if watchOnce {
+			changes, err := w.Check(ctx)
+			if err == nil && len(changes) == 0 {
+				fmt.Println("✅ No conflicts with planned blocks")
+			}
+			return err
+		}
+
+		trigger := make(chan struct{}, 1)
+		if watchWebhookURL != "" {
+			stop, err := startWebhook(ctx, calClient, cfg.Calendar, trigger, logger)
+			if err != nil {
+				return err
+			}
+			defer stop()
+		}
+
+		fmt.Printf("\n👀 Watching %s every %s (Ctrl-C to stop)\n", cfg.Calendar, watchInterval)
+		w.Run(ctx, trigger)
+		return nil
+	},
+}
+
+// openWatchLog returns a writer to stdout and the watch log in the data directory.
+func openWatchLog(dataDir func() (string, error)) (io.Writer, func(), error) {
+	dir, err := dataDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, watchLogFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open watch log: %w", err)
+	}
+	closeLog := func() {
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close watch log: %v\n", err)
+		}
+	}
+	return io.MultiWriter(os.Stdout, f), closeLog, nil
+}
+
+// startWebhook serves the notification receiver and registers a push channel for
+// the calendar. The returned function stops both.
+func startWebhook(ctx context.Context, cal planning.Calendar, calendarID string, trigger chan<- struct{}, logger *log.Logger) (func(), error) {
+	watcher, ok := cal.(eventWatcher)
+	if !ok {
+		return nil, fmt.Errorf("push notifications are not supported by this calendar")
+	}
+
+	token, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", watchWebhookAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", watchWebhookAddr, err)
+	}
+	srv := &http.Server{Handler: watch.WebhookHandler(token, trigger), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			logger.Printf("webhook receiver failed: %v", err)
+		}
+	}()
+
+	ch, err := registerChannel(ctx, watcher, calendarID, token)
+	if err != nil {
+		_ = srv.Close()
+		return nil, err
+	}
+	logger.Printf("receiving push notifications on %s until %s", listener.Addr(), ch.Expiration.Local().Format("2006-01-02 15:04"))
+
+	renewCtx, stopRenewing := context.WithCancel(ctx)
+	latest := make(chan *calendar.Channel, 1)
+	go func() {
+		latest <- renewChannel(renewCtx, watcher, calendarID, token, ch, logger)
+	}()
+
+	return func() {
+		stopRenewing()
+		ch := <-latest
+		stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := watcher.StopChannel(stopCtx, ch); err != nil {
+			logger.Printf("Warning: %v", err)
+		}
+		_ = srv.Shutdown(stopCtx)
+	}, nil
+}
+
+// renewChannel registers a new push channel shortly before ch expires and stops
+// the old one, until ctx is done. It returns the last registered channel. While
+// renewal fails, watch keeps polling every --interval.
+func renewChannel(ctx context.Context, watcher eventWatcher, calendarID, token string, ch *calendar.Channel, logger *log.Logger) *calendar.Channel {
+	failed, expired := false, false
+	for {
+		// Short-lived channels are renewed halfway through
+		wait := max(time.Until(ch.Expiration)-channelRenewBefore, time.Until(ch.Expiration)/2)
+		if failed {
+			wait = channelRenewRetry
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ch
+		case <-timer.C:
+		}
+
+		next, err := registerChannel(ctx, watcher, calendarID, token)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ch
+			}
+			failed = true
+			logger.Printf("Warning: failed to renew push notifications: %v", err)
+			if !expired && !time.Now().Before(ch.Expiration) {
+				expired = true
+				logger.Printf("Warning: push notifications expired at %s, only checking every %s until they are renewed", ch.Expiration.Local().Format("2006-01-02 15:04"), watchInterval)
+			}
+			continue
+		}
+
+		stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		if err := watcher.StopChannel(stopCtx, ch); err != nil {
+			logger.Printf("Warning: %v", err)
+		}
+		cancel()
+		ch, failed, expired = next, false, false
+		logger.Printf("renewed push notifications until %s", ch.Expiration.Local().Format("2006-01-02 15:04"))
+	}
+}
+
+// registerChannel registers a push channel with a new ID that sends token with
+// every notification.
+func registerChannel(ctx context.Context, watcher eventWatcher, calendarID, token string) (*calendar.Channel, error) {
+	channelID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	return watcher.WatchEvents(ctx, calendarID, channelID, watchWebhookURL, token)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Minute, "How often to check the calendar")
+	watchCmd.Flags().BoolVar(&watchDryRun, "dry-run", false, "Log the changes without updating the calendar")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "Check once and exit, e.g. from cron")
+	watchCmd.Flags().StringVar(&watchWebhookURL, "webhook-url", "", "Public HTTPS URL forwarded to --webhook-addr, to get push notifications from Google")
+	watchCmd.Flags().StringVar(&watchWebhookAddr, "webhook-addr", "127.0.0.1:8081", "Address of the local push notification receiver")
+}
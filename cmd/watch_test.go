@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+)
+
+func TestWatchOnceMovesConflictingBlocks(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	openAI := aitest.NewServer(aitest.Reply{Content: `{"blocks": [
+		{"type": "focus", "title": "Write docs", "start": "14:00", "end": "15:00"}
+	]}`})
+	defer openAI.Close()
+
+	cfg := testConfig()
+	useFakes(t, cfg, cal, openAI)
+	t.Cleanup(func() { watchOnce = false })
+
+	rootCmd.SetArgs([]string{"plan", "--tasks", "Write docs:L"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("plan command failed: %v", err)
+	}
+
+	date, _ := cfg.GetPlanningDate()
+	cal.AddMeeting("primary", "Design review", date.Add(14*time.Hour), date.Add(14*time.Hour+30*time.Minute))
+
+	rootCmd.SetArgs([]string{"watch", "--once"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("watch command failed: %v", err)
+	}
+
+	for _, e := range cal.Events("primary") {
+		if e.Summary == "Focus time" && !strings.HasPrefix(e.Start.DateTime, "2030-01-07T14:30") {
+			t.Errorf("expected the focus block to start after the meeting, got %s", e.Start.DateTime)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.DataDir, watchLogFile))
+	if err != nil {
+		t.Fatalf("failed to read watch log: %v", err)
+	}
+	if !strings.Contains(string(data), "moved Focus time (Write docs) 14:00 - 15:00 → 14:30 - 15:30 (conflicts with Design review)") {
+		t.Errorf("unexpected watch log: %s", data)
+	}
+}
+
+func TestWebhookRenewsChannelBeforeExpiry(t *testing.T) {
+	cal := calendartest.NewServer()
+	defer cal.Close()
+	cal.SetChannelTTL(400 * time.Millisecond)
+
+	origURL, origAddr := watchWebhookURL, watchWebhookAddr
+	t.Cleanup(func() { watchWebhookURL, watchWebhookAddr = origURL, origAddr })
+	watchWebhookURL, watchWebhookAddr = "https://example.test/hook", "127.0.0.1:0"
+
+	var out strings.Builder
+	logger := log.New(&out, "", 0)
+	stop, err := startWebhook(context.Background(), cal.Client(), "primary", make(chan struct{}, 1), logger)
+	if err != nil {
+		t.Fatalf("startWebhook() error: %v", err)
+	}
+	first := cal.Channels()
+	if len(first) != 1 {
+		t.Fatalf("expected one channel, got %d", len(first))
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	renewed := cal.Channels()
+	stop()
+
+	if len(renewed) != 1 || renewed[0].Id == first[0].Id || renewed[0].Token != first[0].Token {
+		t.Errorf("expected the channel to be replaced by one with the same token, got %+v", renewed)
+	}
+	if !strings.Contains(out.String(), "renewed push notifications until") {
+		t.Errorf("unexpected log: %s", out.String())
+	}
+	if channels := cal.Channels(); len(channels) != 0 {
+		t.Errorf("expected stop to stop the renewed channel, got %d channels", len(channels))
+	}
+}
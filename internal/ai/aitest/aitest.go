@@ -0,0 +1,136 @@
+// Package aitest provides fakes for the AI planner: an in-memory planner and
+// an httptest server that speaks the OpenAI chat completions API.
+package aitest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+)
+
+// StaticPlanner returns the same response for every request and records the requests it got.
+type StaticPlanner struct {
+	Response *ai.PlanResponse
+	Err      error
+
+	mu       sync.Mutex
+	requests []ai.PlanRequest
+}
+
+func (p *StaticPlanner) GeneratePlan(ctx context.Context, req ai.PlanRequest) (*ai.PlanResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, req)
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	return p.Response, nil
+}
+
+// Requests returns the requests received so far.
+func (p *StaticPlanner) Requests() []ai.PlanRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]ai.PlanRequest(nil), p.requests...)
+}
+
+// Reply is a canned answer of the fake OpenAI server. A zero Status means 200 OK,
+// in which case Content is returned as the message content of the only choice.
+// Otherwise Content is returned as the raw response body.
+type Reply struct {
+	Status  int
+	Content string
+}
+
+// Server is a fake OpenAI chat completions endpoint. Replies are returned in
+// order; the last reply is repeated once the queue is exhausted.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	replies  []Reply
+	requests []Request
+}
+
+// Request is a chat completion request received by the Server.
+type Request struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+	// ResponseFormat is the raw response_format of the request, if any.
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+	Authorization  string          `json:"-"`
+}
+
+// NewServer starts a fake OpenAI server. Callers must Close it.
+func NewServer(replies ...Reply) *Server {
+	s := &Server{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Endpoint is the URL to pass to ai.WithEndpoint.
+func (s *Server) Endpoint() string {
+	return s.URL + "/v1/chat/completions"
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Authorization = r.Header.Get("Authorization")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	var reply Reply
+	if len(s.replies) > 0 {
+		reply = s.replies[0]
+		if len(s.replies) > 1 {
+			s.replies = s.replies[1:]
+		}
+	}
+	s.mu.Unlock()
+
+	if reply.Status != 0 && reply.Status != http.StatusOK {
+		w.WriteHeader(reply.Status)
+		_, _ = io.WriteString(w, reply.Content)
+		return
+	}
+
+	resp := map[string]any{
+		"choices": []map[string]any{
+			{"message": map[string]string{"role": "assistant", "content": reply.Content}},
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
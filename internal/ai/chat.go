@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+)
+
+// Conversation is the exchange with the model behind a plan: the first prompt and
+// every change asked since, with the answers. It is never modified; refining a
+// plan returns a new conversation, so a rejected answer can simply be dropped.
+type Conversation struct {
+	messages   []openAIMessage
+	pseudonyms Pseudonyms
+	privacy    config.Privacy
+	// Turns lists the changes asked after the first plan.
+	Turns []Turn
+}
+
+// Turn is one change asked in a conversation.
+type Turn struct {
+	// Instruction is the change as it was sent, after the privacy policy is applied.
+	Instruction string
+	Raw         string
+}
+
+// Prompt returns the first prompt of the conversation.
+func (conv *Conversation) Prompt() string {
+	return conv.messages[0].Content
+}
+
+// RefinePlan asks the model to change the plan of conv as instruction says. The
+// model sees the whole conversation and answers with the full updated plan.
+// Task titles in instruction are replaced by their stand-ins and denylisted
+// words are redacted before it is sent.
+func (c *Client) RefinePlan(ctx context.Context, conv *Conversation, instruction string) (*PlanResponse, error) {
+	hidden, err := conv.hide(instruction)
+	if err != nil {
+		return nil, err
+	}
+	prompts, err := LoadPrompts(c.promptsDir)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := prompts.RenderRefine(hidden)
+	if err != nil {
+		return nil, err
+	}
+	c.showPrompt(prompt)
+
+	messages := append(slices.Clip(conv.messages), openAIMessage{Role: "user", Content: prompt})
+	content, err := c.complete(ctx, messages, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	planResp, err := c.parse(content, conv.pseudonyms)
+	if err != nil {
+		return nil, err
+	}
+	planResp.Prompt = prompt
+	planResp.Conversation = &Conversation{
+		messages:   append(messages, openAIMessage{Role: "assistant", Content: content}),
+		pseudonyms: conv.pseudonyms,
+		privacy:    conv.privacy,
+		Turns:      append(slices.Clip(conv.Turns), Turn{Instruction: hidden, Raw: content}),
+	}
+	return planResp, nil
+}
+
+// hide applies the privacy policy of the conversation to an instruction.
+func (conv *Conversation) hide(instruction string) (string, error) {
+	denylist, err := conv.privacy.DenylistPatterns()
+	if err != nil {
+		return "", err
+	}
+	s := conv.pseudonyms.Hide(instruction)
+	for _, re := range denylist {
+		s = re.ReplaceAllString(s, "[redacted]")
+	}
+	return strings.TrimSpace(s), nil
+}
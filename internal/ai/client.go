@@ -8,28 +8,107 @@ import (
 	"io"
 	"net/http"
 	"os"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
 )
 
 const (
 	openAIURL = "https://api.openai.com/v1/chat/completions"
-	aiModel   = "gpt-5-nano"
+	// DefaultModel is used when no model is configured.
+	DefaultModel = "gpt-5-nano"
+	serviceName  = "OpenAI"
 )
 
 type Client struct {
 	apiKey     string
+	endpoint   string
+	model      string
 	httpClient *http.Client
+	privacy    config.Privacy
+	promptOut  io.Writer
+	promptsDir string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithEndpoint sends requests to url instead of the OpenAI chat completions API.
+func WithEndpoint(url string) Option {
+	return func(c *Client) {
+		c.endpoint = url
+	}
+}
+
+// WithModel selects the model used to generate plans. An empty name keeps the default.
+func WithModel(model string) Option {
+	return func(c *Client) {
+		if model != "" {
+			c.model = model
+		}
+	}
 }
 
-func NewClient(apiKey string) *Client {
-	return &Client{
+// WithHTTPClient replaces the HTTP client used to call the API. By default the
+// client retries transient failures with the default httpx policy.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithPrivacy applies the privacy policy to every request: the prompt is built
+// from redacted data and task titles are restored in the response.
+func WithPrivacy(policy config.Privacy) Option {
+	return func(c *Client) {
+		c.privacy = policy
+	}
+}
+
+// WithPromptWriter writes every prompt to w before it is sent. A nil w writes nothing.
+func WithPromptWriter(w io.Writer) Option {
+	return func(c *Client) {
+		c.promptOut = w
+	}
+}
+
+// WithPromptsDir renders prompts with the template overrides in dir. The
+// directory is read on every request, so edits apply without a restart.
+func WithPromptsDir(dir string) Option {
+	return func(c *Client) {
+		c.promptsDir = dir
+	}
+}
+
+func NewClient(apiKey string, opts ...Option) *Client {
+	c := &Client{
 		apiKey:     apiKey,
-		httpClient: &http.Client{},
+		endpoint:   openAIURL,
+		model:      DefaultModel,
+		httpClient: httpx.NewClient(httpx.DefaultPolicy(config.AITimeout)),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
+	Model          string          `json:"model"`
+	Messages       []openAIMessage `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+// responseFormat asks for an answer that follows a JSON schema.
+type responseFormat struct {
+	Type       string     `json:"type"`
+	JSONSchema jsonSchema `json:"json_schema"`
+}
+
+type jsonSchema struct {
+	Name   string          `json:"name"`
+	Strict bool            `json:"strict"`
+	Schema json.RawMessage `json:"schema"`
 }
 
 type openAIMessage struct {
@@ -45,27 +124,85 @@ type openAIResponse struct {
 	} `json:"choices"`
 }
 
+// Model returns the name of the model used by the client.
+func (c *Client) Model() string {
+	return c.model
+}
+
 func (c *Client) GeneratePlan(ctx context.Context, req PlanRequest) (*PlanResponse, error) {
-	prompt := BuildPrompt(req)
+	redacted, pseudonyms, err := Redact(req, c.privacy)
+	if err != nil {
+		return nil, err
+	}
+	prompts, err := LoadPrompts(c.promptsDir)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := prompts.Render(redacted)
+	if err != nil {
+		return nil, err
+	}
+	c.showPrompt(prompt)
+
+	messages := []openAIMessage{{Role: "user", Content: prompt}}
+	content, err := c.complete(ctx, messages, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	planResp, err := c.parse(content, pseudonyms)
+	if err != nil {
+		return nil, err
+	}
+	planResp.Prompt = prompt
+	planResp.Conversation = &Conversation{
+		messages:   append(messages, openAIMessage{Role: "assistant", Content: content}),
+		pseudonyms: pseudonyms,
+		privacy:    c.privacy,
+	}
+
+	return planResp, nil
+}
 
+// showPrompt writes prompt to the prompt writer, if any.
+func (c *Client) showPrompt(prompt string) {
+	if c.promptOut != nil {
+		fmt.Fprintf(c.promptOut, "----- prompt sent to %s -----\n%s----- end of prompt -----\n", serviceName, prompt)
+	}
+}
+
+// parse parses the model's answer and restores the task titles hidden by the
+// privacy policy.
+func (c *Client) parse(content string, pseudonyms Pseudonyms) (*PlanResponse, error) {
+	planResp, err := ParseResponse(content)
+	if err != nil {
+		return nil, err
+	}
+	for i := range planResp.Blocks {
+		planResp.Blocks[i].Title = pseudonyms.Restore(planResp.Blocks[i].Title)
+	}
+	planResp.Model = c.model
+	planResp.Raw = content
+	return planResp, nil
+}
+
+// complete sends messages to the chat completions API and returns the content of
+// the first choice. A nil format leaves the format of the answer to the prompt.
+func (c *Client) complete(ctx context.Context, messages []openAIMessage, format *responseFormat) (string, error) {
 	payload := openAIRequest{
-		Model: aiModel,
-		Messages: []openAIMessage{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
+		Model:          c.model,
+		Messages:       messages,
+		ResponseFormat: format,
 	}
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", openAIURL, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -73,7 +210,10 @@ func (c *Client) GeneratePlan(ctx context.Context, req PlanRequest) (*PlanRespon
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call OpenAI API: %w", err)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("OpenAI API call canceled: %w", ctx.Err())
+		}
+		return "", httpx.NewNetworkError(serviceName, err)
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
@@ -82,21 +222,24 @@ func (c *Client) GeneratePlan(ctx context.Context, req PlanRequest) (*PlanRespon
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return "", httpx.NewStatusError(serviceName, resp.StatusCode, string(body))
 	}
 
 	var openAIResp openAIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if len(openAIResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in OpenAI response")
+		return "", fmt.Errorf("no choices in OpenAI response")
 	}
 
-	content := openAIResp.Choices[0].Message.Content
+	return openAIResp.Choices[0].Message.Content, nil
+}
 
+// ParseResponse parses the message content returned by the model.
+func ParseResponse(content string) (*PlanResponse, error) {
 	var planResp PlanResponse
 	if err := json.Unmarshal([]byte(content), &planResp); err != nil {
 		return nil, fmt.Errorf("failed to parse AI response as JSON: %w\nResponse: %s", err, content)
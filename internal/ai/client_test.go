@@ -0,0 +1,242 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+func testRequest() ai.PlanRequest {
+	date := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	return ai.PlanRequest{
+		WorkStart: date.Add(9 * time.Hour),
+		WorkEnd:   date.Add(17 * time.Hour),
+		Tasks:     []planner.Task{{Title: "Write docs", Duration: planner.SizeL}},
+		Mode:      config.BuiltinModes()[1],
+	}
+}
+
+func TestGeneratePlan(t *testing.T) {
+	server := aitest.NewServer(aitest.Reply{
+		Content: `{"blocks": [{"type": "focus", "title": "Write docs", "start": "09:00", "end": "10:00"}]}`,
+	})
+	defer server.Close()
+
+	client := ai.NewClient("sk-test", ai.WithEndpoint(server.Endpoint()))
+	resp, err := client.GeneratePlan(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("GeneratePlan() error: %v", err)
+	}
+
+	if len(resp.Blocks) != 1 || resp.Blocks[0].Title != "Write docs" {
+		t.Errorf("unexpected blocks: %+v", resp.Blocks)
+	}
+	if resp.Model != ai.DefaultModel || !strings.Contains(resp.Prompt, "Write docs") || !strings.HasPrefix(resp.Raw, `{"blocks"`) {
+		t.Errorf("expected model, prompt and raw response to be recorded, got %q, %q, %q", resp.Model, resp.Prompt, resp.Raw)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	if requests[0].Authorization != "Bearer sk-test" {
+		t.Errorf("Authorization = %q, want Bearer sk-test", requests[0].Authorization)
+	}
+	if !strings.Contains(requests[0].Messages[0].Content, "Write docs (60 minutes)") {
+		t.Errorf("prompt should list the task, got: %s", requests[0].Messages[0].Content)
+	}
+}
+
+func TestGeneratePlanPrivacy(t *testing.T) {
+	server := aitest.NewServer(aitest.Reply{
+		Content: `{"blocks": [{"type": "focus", "title": "Redacted task 1", "start": "09:00", "end": "10:00"}]}`,
+	})
+	defer server.Close()
+
+	var shown strings.Builder
+	client := ai.NewClient("sk-test", ai.WithEndpoint(server.Endpoint()),
+		ai.WithPrivacy(config.Privacy{TaskTitles: config.PrivacyRedact}),
+		ai.WithPromptWriter(&shown))
+	resp, err := client.GeneratePlan(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("GeneratePlan() error: %v", err)
+	}
+
+	sent := server.Requests()[0].Messages[0].Content
+	if strings.Contains(sent, "Write docs") || !strings.Contains(sent, "Redacted task 1 (60 minutes)") {
+		t.Errorf("expected the task title to be redacted, got: %s", sent)
+	}
+	if !strings.Contains(shown.String(), sent) {
+		t.Errorf("expected the prompt to be shown as sent, got: %s", shown.String())
+	}
+	if resp.Blocks[0].Title != "Write docs" {
+		t.Errorf("expected the title to be restored, got %q", resp.Blocks[0].Title)
+	}
+}
+
+func TestRefinePlan(t *testing.T) {
+	server := aitest.NewServer(
+		aitest.Reply{Content: `{"blocks": [{"type": "focus", "title": "Redacted task 1", "start": "09:00", "end": "10:00"}]}`},
+		aitest.Reply{Content: `{"blocks": [{"type": "focus", "title": "Redacted task 1", "start": "14:00", "end": "15:00"}]}`},
+	)
+	defer server.Close()
+
+	client := ai.NewClient("sk-test", ai.WithEndpoint(server.Endpoint()),
+		ai.WithPrivacy(config.Privacy{TaskTitles: config.PrivacyRedact, Denylist: []string{"acme"}}))
+	first, err := client.GeneratePlan(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("GeneratePlan() error: %v", err)
+	}
+
+	refined, err := client.RefinePlan(context.Background(), first.Conversation, "Move Write docs after the Acme call")
+	if err != nil {
+		t.Fatalf("RefinePlan() error: %v", err)
+	}
+	if refined.Blocks[0].Title != "Write docs" || refined.Blocks[0].Start != "14:00" {
+		t.Errorf("unexpected refined blocks: %+v", refined.Blocks)
+	}
+
+	messages := server.Requests()[1].Messages
+	if len(messages) != 3 || messages[1].Role != "assistant" || messages[1].Content != first.Raw {
+		t.Fatalf("expected the first exchange to be sent again, got %+v", messages)
+	}
+	if change := messages[2].Content; !strings.HasPrefix(change, "Change the plan as follows: Move Redacted task 1 after the [redacted] call\n") {
+		t.Errorf("expected the instruction to be redacted, got: %s", change)
+	}
+
+	conv := refined.Conversation
+	if len(conv.Turns) != 1 || conv.Turns[0].Raw != refined.Raw || conv.Prompt() != first.Prompt {
+		t.Errorf("unexpected conversation: %+v", conv)
+	}
+	if len(first.Conversation.Turns) != 0 {
+		t.Errorf("expected the first conversation to be left unchanged, got %+v", first.Conversation.Turns)
+	}
+}
+
+func TestGeneratePlanErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   aitest.Reply
+		wantErr string
+	}{
+		{"api error", aitest.Reply{Status: http.StatusUnauthorized, Content: "bad key"}, "status 401"},
+		{"invalid json", aitest.Reply{Content: "Sure! Here is your plan"}, "failed to parse AI response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := aitest.NewServer(tt.reply)
+			defer server.Close()
+
+			client := ai.NewClient("sk-test", ai.WithEndpoint(server.Endpoint()))
+			_, err := client.GeneratePlan(context.Background(), testRequest())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GeneratePlan() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGeneratePlanRetriesRateLimits(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantCalls int
+	}{
+		{http.StatusTooManyRequests, 2},
+		// The completion may have been produced and billed already
+		{http.StatusServiceUnavailable, 1},
+	}
+
+	for _, tt := range tests {
+		server := aitest.NewServer(
+			aitest.Reply{Status: tt.status, Content: "overloaded"},
+			aitest.Reply{Content: `{"blocks": []}`},
+		)
+
+		httpClient := httpx.NewClient(httpx.Policy{MaxRetries: 2, BaseDelay: time.Millisecond})
+		client := ai.NewClient("sk-test", ai.WithEndpoint(server.Endpoint()), ai.WithHTTPClient(httpClient))
+		_, _ = client.GeneratePlan(context.Background(), testRequest())
+		if got := len(server.Requests()); got != tt.wantCalls {
+			t.Errorf("status %d: expected %d requests, got %d", tt.status, tt.wantCalls, got)
+		}
+
+		server.Close()
+	}
+}
+
+func TestGeneratePlanClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, httpx.ErrAuth},
+		{http.StatusTooManyRequests, httpx.ErrQuota},
+		{http.StatusBadGateway, httpx.ErrTransient},
+		{http.StatusBadRequest, httpx.ErrBadRequest},
+	}
+
+	for _, tt := range tests {
+		server := aitest.NewServer(aitest.Reply{Status: tt.status, Content: "nope"})
+
+		httpClient := httpx.NewClient(httpx.Policy{MaxRetries: 1, BaseDelay: time.Millisecond})
+		client := ai.NewClient("sk-test", ai.WithEndpoint(server.Endpoint()), ai.WithHTTPClient(httpClient))
+		_, err := client.GeneratePlan(context.Background(), testRequest())
+		if !errors.Is(err, tt.want) {
+			t.Errorf("status %d: error = %v, want %v", tt.status, err, tt.want)
+		}
+
+		server.Close()
+	}
+}
+
+func TestParseTasks(t *testing.T) {
+	server := aitest.NewServer(
+		aitest.Reply{Content: `{"tasks": [
+			{"title": "Finish the RFC", "minutes": 120, "kind": "deep", "after": "", "before": "12:00"},
+			{"title": "Call Bob", "minutes": 15, "kind": "", "after": "15:00", "before": ""}
+		]}`},
+		aitest.Reply{Content: `{"tasks": [{"title": "Call Bob", "minutes": 15, "kind": "", "after": "3pm", "before": ""}]}`},
+	)
+	defer server.Close()
+	hints := ai.TaskHints{WorkStart: "09:00", WorkEnd: "17:00", LunchStart: "12:00", LunchEnd: "13:00"}
+
+	client := ai.NewClient("sk-test", ai.WithEndpoint(server.Endpoint()))
+	tasks, err := client.ParseTasks(context.Background(), "finish the RFC (2h, before lunch), call Bob after 3pm", hints)
+	if err != nil {
+		t.Fatalf("ParseTasks() error: %v", err)
+	}
+	want := []planner.Task{
+		{Title: "Finish the RFC", Duration: 2 * time.Hour, Kind: planner.TaskKindDeep, Before: "12:00"},
+		{Title: "Call Bob", Duration: 15 * time.Minute, After: "15:00"},
+	}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("ParseTasks() = %+v, want %+v", tasks, want)
+	}
+
+	request := server.Requests()[0]
+	if !strings.Contains(string(request.ResponseFormat), `"json_schema"`) || !strings.Contains(request.Messages[0].Content, "call Bob after 3pm") {
+		t.Errorf("expected a schema and the to-do list in the request, got %s\n%s", request.ResponseFormat, request.Messages[0].Content)
+	}
+
+	if _, err := client.ParseTasks(context.Background(), "call Bob after 3pm", hints); err == nil || !strings.Contains(err.Error(), "3pm") {
+		t.Errorf("ParseTasks() error = %v, want an invalid time error", err)
+	}
+
+	private := ai.NewClient("sk-test", ai.WithEndpoint(server.Endpoint()), ai.WithPrivacy(config.Privacy{Denylist: []string{"rfc"}}))
+	if _, err := private.ParseTasks(context.Background(), "finish the RFC", hints); !errors.Is(err, ai.ErrPrivateTasks) {
+		t.Errorf("ParseTasks() error = %v, want ErrPrivateTasks", err)
+	}
+	if got := len(server.Requests()); got != 2 {
+		t.Errorf("expected nothing to be sent for private tasks, got %d requests", got)
+	}
+}
@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenDate is the date canned model responses are placed on.
+var goldenDate = time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+
+// requestFixture is a request file with the privacy policy to apply to it.
+type requestFixture struct {
+	RequestFile
+	Privacy config.Privacy `json:"privacy"`
+}
+
+// checkGolden compares got with the golden file, or rewrites it with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("missing golden file %s (run go test with -update): %v", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("%s does not match golden file %s (run go test with -update to accept)\n--- got ---\n%s\n--- want ---\n%s",
+			name, path, got, want)
+	}
+}
+
+func fixtureNames(t *testing.T, dir, ext string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join("testdata", dir, "*"+ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no fixtures in testdata/%s", dir)
+	}
+
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = strings.TrimSuffix(filepath.Base(f), ext)
+	}
+	return names
+}
+
+func TestPromptGolden(t *testing.T) {
+	for _, name := range fixtureNames(t, "requests", ".json") {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "requests", name+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fixture requestFixture
+			if err := json.Unmarshal(data, &fixture); err != nil {
+				t.Fatalf("invalid fixture: %v", err)
+			}
+			req, err := fixture.PlanRequest(nil)
+			if err != nil {
+				t.Fatalf("invalid fixture: %v", err)
+			}
+
+			req, _, err = Redact(req, fixture.Privacy)
+			if err != nil {
+				t.Fatalf("invalid privacy policy: %v", err)
+			}
+
+			prompt, err := BuildPrompt(req)
+			if err != nil {
+				t.Fatalf("BuildPrompt() error: %v", err)
+			}
+
+			checkGolden(t, "prompt_"+name, prompt)
+		})
+	}
+}
+
+func TestPlanGolden(t *testing.T) {
+	for _, name := range fixtureNames(t, "responses", ".json") {
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", "responses", name+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkGolden(t, "plan_"+name, renderPlan(string(content)))
+		})
+	}
+}
+
+// renderPlan runs a canned model response through parse, validate and
+// TimeBlock conversion and renders the result, or the first error.
+func renderPlan(content string) string {
+	resp, err := ParseResponse(content)
+	if err != nil {
+		return "parse error: " + firstLine(err.Error()) + "\n"
+	}
+	if err := resp.Validate(); err != nil {
+		return "validation error: " + err.Error() + "\n"
+	}
+
+	if len(resp.Blocks) == 0 {
+		return "(no blocks)\n"
+	}
+
+	var sb strings.Builder
+	for _, b := range resp.Blocks {
+		block, err := b.ToTimeBlock(goldenDate)
+		if err != nil {
+			return "conversion error: " + err.Error() + "\n"
+		}
+		fmt.Fprintf(&sb, "%-6s %s-%s %s", block.Type,
+			block.Start.Format(planner.TimeFormat), block.End.Format(planner.TimeFormat), block.Title)
+		if block.Suggested {
+			sb.WriteString(" (suggested)")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// Stand-ins for hidden titles.
+const (
+	busyTitle   = "Busy"
+	bufferTitle = "Buffer"
+)
+
+// Pseudonyms maps the stand-ins used in a prompt back to the real task titles.
+// It never leaves the machine.
+type Pseudonyms map[string]string
+
+// Restore replaces the stand-ins in s with the titles they stand for.
+func (p Pseudonyms) Restore(s string) string {
+	if len(p) == 0 {
+		return s
+	}
+	// Longest first, so "Redacted task 1" does not eat into "Redacted task 12"
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int { return len(b) - len(a) })
+
+	pairs := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k, p[k])
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// Hide replaces the task titles in s with their stand-ins.
+func (p Pseudonyms) Hide(s string) string {
+	if len(p) == 0 {
+		return s
+	}
+	// Longest title first, so "Docs" does not eat into "Write docs"
+	stands := make([]string, 0, len(p))
+	for k := range p {
+		stands = append(stands, k)
+	}
+	slices.SortFunc(stands, func(a, b string) int { return len(p[b]) - len(p[a]) })
+
+	pairs := make([]string, 0, 2*len(stands))
+	for _, k := range stands {
+		pairs = append(pairs, p[k], k)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// Redact applies the privacy policy to req and returns the request to build the
+// prompt from, with the mapping to restore task titles in the response.
+func Redact(req PlanRequest, policy config.Privacy) (PlanRequest, Pseudonyms, error) {
+	denylist, err := policy.DenylistPatterns()
+	if err != nil {
+		return req, nil, err
+	}
+	r := redactor{policy: policy, denylist: denylist, pseudonyms: Pseudonyms{}, stand: map[string]string{}}
+
+	out := req
+	out.Tasks = make([]planner.Task, len(req.Tasks))
+	for i, t := range req.Tasks {
+		t.Title = r.task(t.Title)
+		out.Tasks[i] = t
+	}
+
+	out.BusyBlocks = make([]planner.TimeBlock, len(req.BusyBlocks))
+	for i, b := range req.BusyBlocks {
+		switch b.Type {
+		case planner.BlockTypeLunch, planner.BlockTypeOutOfOffice, planner.BlockTypeBreak:
+		case planner.BlockTypeFocus:
+			b.Title = r.task(b.Title)
+		case planner.BlockTypeBuffer:
+			if r.hideMeeting(b.Title) {
+				b.Title = bufferTitle
+			}
+		default:
+			if r.hideMeeting(b.Title) {
+				b.Title, b.Location = busyTitle, ""
+			}
+		}
+		out.BusyBlocks[i] = b
+	}
+
+	if req.Meetings != nil {
+		out.Meetings = make([]Meeting, len(req.Meetings))
+		for i, m := range req.Meetings {
+			if policy.DropDescriptions || r.hideMeeting(m.Title) || r.denied(m.Description) {
+				m.Description = ""
+			}
+			if r.hideMeeting(m.Title) {
+				m.Title = busyTitle
+			}
+			out.Meetings[i] = m
+		}
+	}
+
+	return out, r.pseudonyms, nil
+}
+
+type redactor struct {
+	policy     config.Privacy
+	denylist   []*regexp.Regexp
+	pseudonyms Pseudonyms
+	// stand maps real titles to their stand-ins.
+	stand map[string]string
+}
+
+func (r *redactor) denied(s string) bool {
+	return slices.ContainsFunc(r.denylist, func(re *regexp.Regexp) bool { return re.MatchString(s) })
+}
+
+func (r *redactor) hideMeeting(title string) bool {
+	return r.policy.MeetingTitles == config.PrivacyAnonymize || r.denied(title)
+}
+
+// task returns the stand-in for a task title, or the title itself when it can
+// be shared. The same title always gets the same stand-in.
+func (r *redactor) task(title string) string {
+	if s, ok := r.stand[title]; ok {
+		return s
+	}
+
+	var s string
+	switch {
+	case r.policy.TaskTitles == config.PrivacyHash:
+		sum := sha256.Sum256([]byte(title))
+		s = "task-" + hex.EncodeToString(sum[:4])
+	case r.policy.TaskTitles == config.PrivacyRedact || r.denied(title):
+		s = fmt.Sprintf("Redacted task %d", len(r.pseudonyms)+1)
+	default:
+		return title
+	}
+
+	r.stand[title] = s
+	r.pseudonyms[s] = title
+	return s
+}
@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+func TestRedact(t *testing.T) {
+	req := PlanRequest{
+		BusyBlocks: []planner.TimeBlock{
+			{Type: planner.BlockTypeMeeting, Title: "1:1 with HR", Location: "Room 4"},
+			{Type: planner.BlockTypeBuffer, Title: "Buffer before 1:1 with HR"},
+			{Type: planner.BlockTypeLunch, Title: "Lunch"},
+			{Type: planner.BlockTypeFocus, Title: "Fix login bug"},
+		},
+		Tasks: []planner.Task{{Title: "Fix login bug"}, {Title: "Write docs"}},
+		Meetings: []Meeting{
+			{Title: "1:1 with HR", Description: "Salary"},
+			{Title: "Standup", Description: "Daily sync"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policy   config.Privacy
+		busy     []string
+		tasks    []string
+		meetings []string
+	}{
+		{
+			name:     "keep",
+			policy:   config.Privacy{},
+			busy:     []string{"1:1 with HR", "Buffer before 1:1 with HR", "Lunch", "Fix login bug"},
+			tasks:    []string{"Fix login bug", "Write docs"},
+			meetings: []string{"1:1 with HR: Salary", "Standup: Daily sync"},
+		},
+		{
+			name:     "anonymize and redact",
+			policy:   config.Privacy{MeetingTitles: config.PrivacyAnonymize, TaskTitles: config.PrivacyRedact, DropDescriptions: true},
+			busy:     []string{"Busy", "Buffer", "Lunch", "Redacted task 1"},
+			tasks:    []string{"Redacted task 1", "Redacted task 2"},
+			meetings: []string{"Busy: ", "Busy: "},
+		},
+		{
+			name:     "hash",
+			policy:   config.Privacy{TaskTitles: config.PrivacyHash},
+			busy:     []string{"1:1 with HR", "Buffer before 1:1 with HR", "Lunch", "task-531e90d2"},
+			tasks:    []string{"task-531e90d2", "task-345b88bf"},
+			meetings: []string{"1:1 with HR: Salary", "Standup: Daily sync"},
+		},
+		{
+			name:     "denylist",
+			policy:   config.Privacy{Denylist: []string{`\bhr\b`, "login"}},
+			busy:     []string{"Busy", "Buffer", "Lunch", "Redacted task 1"},
+			tasks:    []string{"Redacted task 1", "Write docs"},
+			meetings: []string{"Busy: ", "Standup: Daily sync"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, pseudonyms, err := Redact(req, tt.policy)
+			if err != nil {
+				t.Fatalf("Redact() error: %v", err)
+			}
+
+			var busy, tasks, meetings []string
+			for _, b := range got.BusyBlocks {
+				busy = append(busy, b.Title)
+			}
+			for i, task := range got.Tasks {
+				tasks = append(tasks, task.Title)
+				if restored := pseudonyms.Restore(task.Title); restored != req.Tasks[i].Title {
+					t.Errorf("Restore(%q) = %q, want %q", task.Title, restored, req.Tasks[i].Title)
+				}
+			}
+			for _, m := range got.Meetings {
+				meetings = append(meetings, m.Title+": "+m.Description)
+			}
+
+			check := func(what string, got, want []string) {
+				if strings.Join(got, "|") != strings.Join(want, "|") {
+					t.Errorf("%s = %q, want %q", what, got, want)
+				}
+			}
+			check("busy", busy, tt.busy)
+			check("tasks", tasks, tt.tasks)
+			check("meetings", meetings, tt.meetings)
+
+			if req.BusyBlocks[0].Title != "1:1 with HR" || req.Tasks[0].Title != "Fix login bug" {
+				t.Errorf("Redact() must not change the original request")
+			}
+		})
+	}
+}
+
+func TestPseudonymsRestore(t *testing.T) {
+	p := Pseudonyms{"Redacted task 1": "Fix login bug", "Redacted task 12": "Write docs"}
+
+	tests := map[string]string{
+		"Redacted task 1":          "Fix login bug",
+		"Redacted task 12":         "Write docs",
+		"Prep for Redacted task 1": "Prep for Fix login bug",
+		"Short break":              "Short break",
+	}
+	for in, want := range tests {
+		if got := p.Restore(in); got != want {
+			t.Errorf("Restore(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -1,75 +1,314 @@
 package ai
 
 import (
+	"bytes"
+	"embed"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/Alvkoen/barely-incharge/internal/config"
 	"github.com/Alvkoen/barely-incharge/internal/planner"
 )
 
-func BuildPrompt(req PlanRequest) string {
-	var sb strings.Builder
+// Prompt template names. The prompt is rendered from PromptTemplate, which
+// includes the others; each can be overridden on its own.
+const (
+	PromptTemplate      = "prompt.tmpl"
+	SystemTemplate      = "system.tmpl"
+	ModeTemplate        = "mode.tmpl"
+	EnergyTemplate      = "energy.tmpl"
+	SuggestionsTemplate = "suggestions.tmpl"
+	FormatTemplate      = "format.tmpl"
+	// RefineTemplate is rendered on its own for every change asked in a chat.
+	RefineTemplate = "refine.tmpl"
+	// TasksTemplate is rendered on its own to parse tasks written in plain language.
+	TasksTemplate = "tasks.tmpl"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}
+
+var defaultPrompts = mustDefaultPrompts()
+
+// TemplateData is what prompt templates are rendered with. It is derived from a
+// PlanRequest after the privacy policy is applied; times are formatted as HH:MM.
+type TemplateData struct {
+	// WorkStart and WorkEnd are the part of the work day to plan.
+	WorkStart string
+	WorkEnd   string
+	// Busy lists the times that are not available: meetings, lunch, buffers and
+	// blocks that are already planned.
+	Busy  []TemplateBlock
+	Tasks []TemplateTask
+	// Meetings is only set when meeting suggestions are enabled.
+	Meetings []TemplateMeeting
+	// Energy lists the energy windows of the day, if an energy profile is set.
+	Energy []TemplateEnergy
+	// Mode is also the data of the mode template.
+	Mode TemplateMode
+}
+
+// TemplateBlock is a busy time. Type is meeting, lunch, buffer, focus, break or out_of_office.
+type TemplateBlock struct {
+	Type  string
+	Title string
+	Start string
+	End   string
+}
+
+// TemplateTask is a task to schedule. Kind is deep, shallow or empty. After and
+// Before are the times the task must be planned after and finished by, if any.
+type TemplateTask struct {
+	Title   string
+	Minutes int
+	Kind    string
+	After   string
+	Before  string
+}
+
+// TemplateMeeting is a meeting shared for suggestions. Description is on one line.
+type TemplateMeeting struct {
+	Title       string
+	Description string
+	Start       string
+	End         string
+	NeedsNotes  bool
+}
+
+// TemplateEnergy is an energy window. Level is peak, slump or wind_down and
+// Label its readable name, e.g. "Peak energy".
+type TemplateEnergy struct {
+	Level string
+	Label string
+	Start string
+	End   string
+}
+
+// TemplateMode is the planning mode. Zero values mean the mode sets no limit.
+type TemplateMode struct {
+	Name             string
+	Prompt           string
+	MinFocusMinutes  int
+	MaxFocusMinutes  int
+	BreakMinutes     int
+	LongBreakMinutes int
+	LongBreakEvery   int
+	EndEarly         bool
+}
+
+// RefineData is what the refine template is rendered with.
+type RefineData struct {
+	// Instruction is the change asked for, after the privacy policy is applied.
+	Instruction string
+}
+
+// TaskParseData is what the tasks template is rendered with. Times are HH:MM.
+type TaskParseData struct {
+	// Text is the to-do list as written.
+	Text       string
+	WorkStart  string
+	WorkEnd    string
+	LunchStart string
+	LunchEnd   string
+}
+
+// NewTemplateData derives the template data from req.
+func NewTemplateData(req PlanRequest) TemplateData {
+	hhmm := func(t time.Time) string { return t.Format(planner.TimeFormat) }
+
+	data := TemplateData{
+		WorkStart: hhmm(req.WorkStart),
+		WorkEnd:   hhmm(req.WorkEnd),
+		Mode: TemplateMode{
+			Name:             req.Mode.Name,
+			Prompt:           req.Mode.Prompt,
+			MinFocusMinutes:  req.Mode.MinFocusMinutes,
+			MaxFocusMinutes:  req.Mode.MaxFocusMinutes,
+			BreakMinutes:     req.Mode.BreakMinutes,
+			LongBreakMinutes: req.Mode.LongBreakMinutes,
+			LongBreakEvery:   req.Mode.LongBreakEvery,
+			EndEarly:         req.Mode.EndEarly,
+		},
+	}
+	for _, b := range req.BusyBlocks {
+		data.Busy = append(data.Busy, TemplateBlock{Type: b.Type, Title: b.Title, Start: hhmm(b.Start), End: hhmm(b.End)})
+	}
+	for _, t := range req.Tasks {
+		data.Tasks = append(data.Tasks, TemplateTask{
+			Title:   t.Title,
+			Minutes: int(t.Duration.Minutes()),
+			Kind:    t.Kind,
+			After:   t.After,
+			Before:  t.Before,
+		})
+	}
+	for _, m := range req.Meetings {
+		data.Meetings = append(data.Meetings, TemplateMeeting{
+			Title:       m.Title,
+			Description: strings.Join(strings.Fields(m.Description), " "),
+			Start:       hhmm(m.Start),
+			End:         hhmm(m.End),
+			NeedsNotes:  m.NeedsNotes,
+		})
+	}
+	for _, w := range req.Energy {
+		data.Energy = append(data.Energy, TemplateEnergy{Level: w.Level, Label: energyLabel(w.Level), Start: hhmm(w.Start), End: hhmm(w.End)})
+	}
+	return data
+}
+
+// Prompts renders plan prompts from text/template files. The defaults are
+// embedded in the binary; LoadPrompts replaces them with the files of an
+// override directory.
+type Prompts struct {
+	set *template.Template
+	// Overridden lists the templates read from the override directory.
+	Overridden []string
+}
+
+// DefaultPrompts returns the embedded prompt templates.
+func DefaultPrompts() *Prompts {
+	return defaultPrompts
+}
 
-	sb.WriteString("You are a calendar planning assistant. Create a day schedule with focus blocks and breaks.\n\n")
+// LoadPrompts returns the embedded templates with every *.tmpl file in dir
+// replacing the template of the same name. A file named mode-<name>.tmpl
+// replaces mode.tmpl for that mode only. A missing dir is not an error.
+func LoadPrompts(dir string) (*Prompts, error) {
+	if dir == "" {
+		return defaultPrompts, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+	if len(files) == 0 {
+		return defaultPrompts, nil
+	}
 
-	sb.WriteString(fmt.Sprintf("Work hours: %s - %s\n",
-		req.WorkStart.Format(planner.TimeFormat),
-		req.WorkEnd.Format(planner.TimeFormat)))
+	set, err := defaultPrompts.set.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy prompt templates: %w", err)
+	}
+	p := &Prompts{set: set}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		name := filepath.Base(path)
+		if _, err := set.New(name).Parse(string(data)); err != nil {
+			return nil, fmt.Errorf("invalid prompt template %s: %w", path, err)
+		}
+		p.Overridden = append(p.Overridden, name)
+	}
+	sort.Strings(p.Overridden)
 
-	sb.WriteString("\n")
+	// Catch references to unknown fields now rather than on the next plan
+	req := sampleRequest()
+	if _, err := p.Render(req); err != nil {
+		return nil, err
+	}
+	if _, err := p.RenderRefine("Move breaks later"); err != nil {
+		return nil, err
+	}
+	if _, err := p.RenderTasks(TaskParseData{Text: "Write docs (1h)", WorkStart: "09:00", WorkEnd: "17:00", LunchStart: "12:00", LunchEnd: "13:00"}); err != nil {
+		return nil, err
+	}
+	for _, name := range p.Overridden {
+		if mode, ok := strings.CutPrefix(name, "mode-"); ok {
+			req.Mode.Name = strings.TrimSuffix(mode, ".tmpl")
+			if _, err := p.Render(req); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
 
-	sb.WriteString("Busy times (unavailable for scheduling):\n")
-	if len(req.BusyBlocks) == 0 {
-		sb.WriteString("- No busy times\n")
-	} else {
-		for _, block := range req.BusyBlocks {
-			sb.WriteString(fmt.Sprintf("- %s (%s - %s)\n",
-				block.Title,
-				block.Start.Format(planner.TimeFormat),
-				block.End.Format(planner.TimeFormat)))
+// Render builds the prompt for req.
+func (p *Prompts) Render(req PlanRequest) (string, error) {
+	set := p.set
+	if modeSpecific := set.Lookup("mode-" + strings.ToLower(req.Mode.Name) + ".tmpl"); modeSpecific != nil {
+		var err error
+		if set, err = set.Clone(); err != nil {
+			return "", fmt.Errorf("failed to copy prompt templates: %w", err)
 		}
+		if _, err := set.AddParseTree(ModeTemplate, modeSpecific.Tree); err != nil {
+			return "", fmt.Errorf("failed to use %s: %w", modeSpecific.Name(), err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := set.ExecuteTemplate(&buf, PromptTemplate, NewTemplateData(req)); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// RenderRefine builds the message asking for a change to the plan.
+func (p *Prompts) RenderRefine(instruction string) (string, error) {
+	var buf bytes.Buffer
+	if err := p.set.ExecuteTemplate(&buf, RefineTemplate, RefineData{Instruction: instruction}); err != nil {
+		return "", fmt.Errorf("failed to render refine prompt: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// RenderTasks builds the prompt to parse a to-do list into tasks.
+func (p *Prompts) RenderTasks(data TaskParseData) (string, error) {
+	var buf bytes.Buffer
+	if err := p.set.ExecuteTemplate(&buf, TasksTemplate, data); err != nil {
+		return "", fmt.Errorf("failed to render tasks prompt: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// BuildPrompt renders the prompt for req with the embedded templates.
+func BuildPrompt(req PlanRequest) (string, error) {
+	return defaultPrompts.Render(req)
+}
+
+func mustDefaultPrompts() *Prompts {
+	set := template.Must(template.New("").Funcs(templateFuncs).ParseFS(templateFS, "templates/*.tmpl"))
+	return &Prompts{set: set}
+}
+
+// sampleRequest has every part of the template data set.
+func sampleRequest() PlanRequest {
+	start := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	mode := config.BuiltinModes()[0]
+	return PlanRequest{
+		WorkStart:  start,
+		WorkEnd:    start.Add(8 * time.Hour),
+		BusyBlocks: []planner.TimeBlock{{Type: planner.BlockTypeMeeting, Title: "Standup", Start: start.Add(time.Hour), End: start.Add(75 * time.Minute)}},
+		Tasks:      []planner.Task{{Title: "Write docs", Duration: planner.SizeL, Kind: planner.TaskKindDeep, After: "10:00", Before: "16:00"}},
+		Mode:       mode,
+		Energy:     planner.EnergyProfile{{Level: planner.EnergyPeak, Start: start, End: start.Add(2 * time.Hour)}},
+		Meetings:   []Meeting{{Title: "Standup", Description: "Daily sync", Start: start.Add(time.Hour), End: start.Add(75 * time.Minute)}},
 	}
-	sb.WriteString("\n")
-
-	sb.WriteString("Tasks to schedule:\n")
-	for _, task := range req.Tasks {
-		sb.WriteString(fmt.Sprintf("- %s (%d minutes)\n",
-			task.Title,
-			int(task.Duration.Minutes())))
-	}
-	sb.WriteString("\n")
-
-	sb.WriteString(getModeInstructions(req.Mode))
-	sb.WriteString("\n")
-
-	sb.WriteString("IMPORTANT: Return ONLY valid JSON in this exact format with no additional text:\n")
-	sb.WriteString("{\n")
-	sb.WriteString("  \"blocks\": [\n")
-	sb.WriteString("    {\"type\": \"focus\", \"title\": \"Task name\", \"start\": \"HH:MM\", \"end\": \"HH:MM\"},\n")
-	sb.WriteString("    {\"type\": \"break\", \"title\": \"Short break\", \"start\": \"HH:MM\", \"end\": \"HH:MM\"}\n")
-	sb.WriteString("  ]\n")
-	sb.WriteString("}\n\n")
-
-	sb.WriteString("CRITICAL RULES:\n")
-	sb.WriteString("- NEVER schedule anything during busy times listed above - these slots are completely unavailable\n")
-	sb.WriteString("- Your blocks must NOT overlap with each other\n")
-	sb.WriteString(fmt.Sprintf("- All blocks must start and end within work hours (%s - %s)\n",
-		req.WorkStart.Format(planner.TimeFormat),
-		req.WorkEnd.Format(planner.TimeFormat)))
-	sb.WriteString("- Use 24-hour format (HH:MM)\n")
-	sb.WriteString("- Types: \"focus\" for tasks, \"break\" for breaks\n")
-	sb.WriteString("- Return ONLY the JSON, no explanation or markdown\n")
-
-	return sb.String()
-}
-
-func getModeInstructions(mode string) string {
-	switch mode {
-	case "crunch":
-		return "Mode: CRUNCH - Pack as many tasks as possible with minimal breaks (5-10 min). Maximize productivity."
-	case "saver":
-		return "Mode: ENERGY SAVER - User is tired. Add longer breaks (15-20 min), extra padding between tasks, and consider ending early if possible."
+}
+
+func energyLabel(level string) string {
+	switch level {
+	case planner.EnergyPeak:
+		return "Peak energy"
+	case planner.EnergySlump:
+		return "Low energy (slump)"
+	case planner.EnergyWindDown:
+		return "Winding down"
 	default:
-		return "Mode: NORMAL - Balanced approach with regular breaks (10-15 min) following standard productivity practices."
+		return level
 	}
 }
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+)
+
+func TestLoadPrompts(t *testing.T) {
+	write := func(t *testing.T, dir, name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("missing dir", func(t *testing.T) {
+		p, err := LoadPrompts(filepath.Join(t.TempDir(), "nope"))
+		if err != nil || p != DefaultPrompts() {
+			t.Errorf("LoadPrompts() = %v, %v, want the defaults", p, err)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, SystemTemplate, "You plan days for a pirate.\n")
+		write(t, dir, "mode-crunch.tmpl", "Mode: all hands on deck ({{.MaxFocusMinutes}} minutes max)\n")
+		write(t, dir, "notes.txt", "not a template")
+
+		p, err := LoadPrompts(dir)
+		if err != nil {
+			t.Fatalf("LoadPrompts() error: %v", err)
+		}
+		if strings.Join(p.Overridden, ",") != "mode-crunch.tmpl,system.tmpl" {
+			t.Errorf("Overridden = %v", p.Overridden)
+		}
+
+		req := sampleRequest()
+		crunch, err := p.Render(req)
+		if err != nil {
+			t.Fatalf("Render() error: %v", err)
+		}
+		if !strings.HasPrefix(crunch, "You plan days for a pirate.\n\nWork hours: 09:00 - 17:00\n") ||
+			!strings.Contains(crunch, "Mode: all hands on deck (120 minutes max)\n\nIMPORTANT") {
+			t.Errorf("unexpected prompt:\n%s", crunch)
+		}
+
+		req.Mode, _ = (&config.Config{}).GetMode(config.ModeNormal)
+		normal, err := p.Render(req)
+		if err != nil {
+			t.Fatalf("Render() error: %v", err)
+		}
+		if !strings.Contains(normal, "Mode: NORMAL") {
+			t.Errorf("expected the default mode template for other modes, got:\n%s", normal)
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, FormatTemplate, "Answer in JSON until {{.Deadline}}\n")
+		if _, err := LoadPrompts(dir); err == nil || !strings.Contains(err.Error(), "Deadline") {
+			t.Errorf("LoadPrompts() error = %v, want an error about Deadline", err)
+		}
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, ModeTemplate, "{{if .Name}")
+		if _, err := LoadPrompts(dir); err == nil {
+			t.Error("LoadPrompts() expected an error")
+		}
+	})
+}
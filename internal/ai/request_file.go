@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// RequestFile is the JSON form of a PlanRequest, used to render a prompt for a
+// given input without a calendar. Times are HH:MM on Date.
+type RequestFile struct {
+	Date      string               `json:"date"`
+	WorkHours config.TimeRange     `json:"work_hours"`
+	Busy      []RequestBlock       `json:"busy"`
+	Tasks     string               `json:"tasks"`
+	Mode      string               `json:"mode"`
+	Modes     []config.Mode        `json:"modes,omitempty"`
+	Energy    config.EnergyProfile `json:"energy"`
+	Meetings  []RequestMeeting     `json:"meetings,omitempty"`
+}
+
+type RequestBlock struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type RequestMeeting struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	NeedsNotes  bool   `json:"needs_notes,omitempty"`
+}
+
+// PlanRequest converts the file into a request. The mode is looked up in the
+// modes of the file, then in modes, then in the built-in modes.
+func (f RequestFile) PlanRequest(modes []config.Mode) (PlanRequest, error) {
+	date, err := time.Parse(config.DateFormat, f.Date)
+	if err != nil {
+		return PlanRequest{}, fmt.Errorf("invalid date (expected YYYY-MM-DD): %w", err)
+	}
+	on := func(hhmm string) time.Time {
+		t, perr := planner.ParseTimeOnDate(hhmm, date)
+		if perr != nil && err == nil {
+			err = perr
+		}
+		return t
+	}
+
+	cfg := config.Config{Modes: append(append([]config.Mode(nil), f.Modes...), modes...)}
+	mode, err := cfg.GetMode(f.Mode)
+	if err != nil {
+		return PlanRequest{}, err
+	}
+
+	req := PlanRequest{
+		WorkStart: on(f.WorkHours.Start),
+		WorkEnd:   on(f.WorkHours.End),
+		Tasks:     planner.ParseTaskList(f.Tasks),
+		Mode:      mode,
+	}
+	for _, b := range f.Busy {
+		req.BusyBlocks = append(req.BusyBlocks, planner.TimeBlock{
+			Type:  b.Type,
+			Title: b.Title,
+			Start: on(b.Start),
+			End:   on(b.End),
+		})
+	}
+	for _, m := range f.Meetings {
+		req.Meetings = append(req.Meetings, Meeting{
+			Title:       m.Title,
+			Description: m.Description,
+			Start:       on(m.Start),
+			End:         on(m.End),
+			NeedsNotes:  m.NeedsNotes,
+		})
+	}
+	levels := []struct {
+		level  string
+		ranges []config.TimeRange
+	}{
+		{planner.EnergyPeak, f.Energy.Peak},
+		{planner.EnergySlump, f.Energy.Slump},
+		{planner.EnergyWindDown, f.Energy.WindDown},
+	}
+	for _, l := range levels {
+		for _, r := range l.ranges {
+			req.Energy = append(req.Energy, planner.EnergyWindow{Level: l.level, Start: on(r.Start), End: on(r.End)})
+		}
+	}
+
+	if err != nil {
+		return PlanRequest{}, fmt.Errorf("invalid time: %w", err)
+	}
+	return req, nil
+}
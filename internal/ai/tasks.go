@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// ErrPrivateTasks is returned by ParseTasks when the privacy policy does not
+// allow sending task titles as they are.
+var ErrPrivateTasks = errors.New("task titles are not shared under the privacy policy")
+
+// maxTaskMinutes is the longest task accepted from the model.
+const maxTaskMinutes = 8 * 60
+
+// tasksSchema is the JSON schema of the answer to the tasks prompt.
+var tasksSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "tasks": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "title": {"type": "string"},
+          "minutes": {"type": "integer"},
+          "kind": {"type": "string", "enum": ["deep", "shallow", ""]},
+          "after": {"type": "string"},
+          "before": {"type": "string"}
+        },
+        "required": ["title", "minutes", "kind", "after", "before"],
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["tasks"],
+  "additionalProperties": false
+}`)
+
+// TaskHints describes the day tasks are parsed for, so the model can resolve
+// phrases like "before lunch". Times are HH:MM.
+type TaskHints struct {
+	WorkStart  string
+	WorkEnd    string
+	LunchStart string
+	LunchEnd   string
+}
+
+type parsedTasks struct {
+	Tasks []parsedTask `json:"tasks"`
+}
+
+type parsedTask struct {
+	Title   string `json:"title"`
+	Minutes int    `json:"minutes"`
+	Kind    string `json:"kind"`
+	After   string `json:"after"`
+	Before  string `json:"before"`
+}
+
+// ParseTasks asks the model to turn a to-do list written in plain language into
+// tasks with durations and time constraints. The answer must follow a JSON
+// schema and is checked before it is returned. Nothing is sent when the privacy
+// policy hides task titles or text matches the denylist; ErrPrivateTasks is
+// returned instead.
+func (c *Client) ParseTasks(ctx context.Context, text string, hints TaskHints) ([]planner.Task, error) {
+	denylist, err := c.privacy.DenylistPatterns()
+	if err != nil {
+		return nil, err
+	}
+	hidden := c.privacy.TaskTitles != "" && c.privacy.TaskTitles != config.PrivacyKeep
+	denied := slices.ContainsFunc(denylist, func(re *regexp.Regexp) bool { return re.MatchString(text) })
+	if hidden || denied {
+		return nil, ErrPrivateTasks
+	}
+
+	prompts, err := LoadPrompts(c.promptsDir)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := prompts.RenderTasks(TaskParseData{
+		Text:       text,
+		WorkStart:  hints.WorkStart,
+		WorkEnd:    hints.WorkEnd,
+		LunchStart: hints.LunchStart,
+		LunchEnd:   hints.LunchEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.showPrompt(prompt)
+
+	content, err := c.complete(ctx, []openAIMessage{{Role: "user", Content: prompt}}, &responseFormat{
+		Type:       "json_schema",
+		JSONSchema: jsonSchema{Name: "tasks", Strict: true, Schema: tasksSchema},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed parsedTasks
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse AI response as JSON: %w\nResponse: %s", err, content)
+	}
+
+	tasks := make([]planner.Task, 0, len(parsed.Tasks))
+	for i, t := range parsed.Tasks {
+		if err := t.validate(); err != nil {
+			return nil, fmt.Errorf("AI returned an invalid task %d: %w", i+1, err)
+		}
+		tasks = append(tasks, planner.Task{
+			Title:    strings.TrimSpace(t.Title),
+			Duration: time.Duration(t.Minutes) * time.Minute,
+			Kind:     t.Kind,
+			After:    t.After,
+			Before:   t.Before,
+		})
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("AI found no tasks")
+	}
+	return tasks, nil
+}
+
+func (t parsedTask) validate() error {
+	if strings.TrimSpace(t.Title) == "" {
+		return fmt.Errorf("missing title")
+	}
+	if t.Minutes <= 0 || t.Minutes > maxTaskMinutes {
+		return fmt.Errorf("%d minutes is not a task length", t.Minutes)
+	}
+	if t.Kind != "" && t.Kind != planner.TaskKindDeep && t.Kind != planner.TaskKindShallow {
+		return fmt.Errorf("unknown kind %q", t.Kind)
+	}
+	for _, hhmm := range []string{t.After, t.Before} {
+		if hhmm == "" {
+			continue
+		}
+		if _, err := time.Parse(planner.TimeFormat, hhmm); err != nil {
+			return fmt.Errorf("invalid time %q", hhmm)
+		}
+	}
+	return nil
+}
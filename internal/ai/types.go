@@ -2,8 +2,10 @@ package ai
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Alvkoen/barely-incharge/internal/config"
 	"github.com/Alvkoen/barely-incharge/internal/planner"
 )
 
@@ -12,11 +14,35 @@ type PlanRequest struct {
 	WorkEnd    time.Time
 	BusyBlocks []planner.TimeBlock
 	Tasks      []planner.Task
-	Mode       string
+	Mode       config.Mode
+	Energy     planner.EnergyProfile
+	// Meetings are shared with the planner only when suggestions are enabled,
+	// so it can propose prep and follow-up blocks.
+	Meetings []Meeting
+}
+
+// Meeting is a meeting of the day with the details the planner needs to suggest
+// prep and follow-up blocks.
+type Meeting struct {
+	Title       string
+	Description string
+	Start       time.Time
+	End         time.Time
+	// NeedsNotes is set for meetings tagged as needing notes written afterwards.
+	NeedsNotes bool
 }
 
 type PlanResponse struct {
 	Blocks []Block `json:"blocks"`
+
+	// Model, Prompt and Raw describe how the response was produced. They are
+	// set by the Client and are not part of the model's JSON.
+	Model  string `json:"-"`
+	Prompt string `json:"-"`
+	Raw    string `json:"-"`
+	// Conversation is the exchange that led to the response, to ask the model
+	// for changes with Client.RefinePlan.
+	Conversation *Conversation `json:"-"`
 }
 
 type Block struct {
@@ -24,6 +50,42 @@ type Block struct {
 	Title string `json:"title"`
 	Start string `json:"start"`
 	End   string `json:"end"`
+	// Suggested marks blocks the planner proposed on its own.
+	Suggested bool `json:"suggested,omitempty"`
+}
+
+// Validate checks that the response only contains well-formed focus and break
+// blocks. It does not check the blocks against busy times.
+func (r *PlanResponse) Validate() error {
+	for i, b := range r.Blocks {
+		if err := b.Validate(); err != nil {
+			return fmt.Errorf("invalid block %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (b Block) Validate() error {
+	if b.Type != planner.BlockTypeFocus && b.Type != planner.BlockTypeBreak {
+		return fmt.Errorf("unknown type %q", b.Type)
+	}
+	if strings.TrimSpace(b.Title) == "" {
+		return fmt.Errorf("missing title")
+	}
+
+	start, err := time.Parse(planner.TimeFormat, b.Start)
+	if err != nil {
+		return fmt.Errorf("invalid start time: %w", err)
+	}
+	end, err := time.Parse(planner.TimeFormat, b.End)
+	if err != nil {
+		return fmt.Errorf("invalid end time: %w", err)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("end %s is not after start %s", b.End, b.Start)
+	}
+
+	return nil
 }
 
 func (b Block) ToTimeBlock(date time.Time) (planner.TimeBlock, error) {
@@ -38,9 +100,10 @@ func (b Block) ToTimeBlock(date time.Time) (planner.TimeBlock, error) {
 	}
 
 	return planner.TimeBlock{
-		Type:  b.Type,
-		Title: b.Title,
-		Start: startTime,
-		End:   endTime,
+		Type:      b.Type,
+		Title:     b.Title,
+		Start:     startTime,
+		End:       endTime,
+		Suggested: b.Suggested,
 	}, nil
 }
@@ -0,0 +1,30 @@
+package calendartest
+
+import (
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// Date is the day the fake calendars and plans of the tests are on. It is in UTC
+// to keep tests independent of the local time zone.
+var Date = time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+
+// At returns the time hhmm on Date. It panics if hhmm is not a valid time.
+func At(hhmm string) time.Time {
+	return at(hhmm, Date)
+}
+
+// LocalAt returns the time hhmm on the same day as Date in the local time zone,
+// for code that reads dates such as "2030-01-07" as local days.
+func LocalAt(hhmm string) time.Time {
+	return at(hhmm, time.Date(Date.Year(), Date.Month(), Date.Day(), 0, 0, 0, 0, time.Local))
+}
+
+func at(hhmm string, date time.Time) time.Time {
+	t, err := planner.ParseTimeOnDate(hhmm, date)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
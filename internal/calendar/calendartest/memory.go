@@ -0,0 +1,136 @@
+// Package calendartest provides fakes for the calendar: an in-memory calendar
+// and an httptest server that speaks the subset of the Google Calendar API
+// used by calendar.GoogleClient.
+package calendartest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// Memory is an in-memory calendar. Events are kept per calendar ID.
+type Memory struct {
+	// FailOn, when set, is called before an event is created; a non-nil error
+	// is returned from CreateEvent instead of storing the event.
+	FailOn func(event calendar.Event) error
+	// ReadOnly lists calendars that CanWrite reports as not writable.
+	ReadOnly map[string]bool
+	// Private lists calendars whose busy time FreeBusy cannot read.
+	Private map[string]bool
+
+	mu     sync.Mutex
+	nextID int
+	events map[string][]calendar.Event
+}
+
+func NewMemory() *Memory {
+	return &Memory{events: make(map[string][]calendar.Event)}
+}
+
+// Add stores events without going through CreateEvent, e.g. to seed meetings.
+// Events without an ID are assigned one.
+func (m *Memory) Add(calendarID string, events ...calendar.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range events {
+		m.add(calendarID, e)
+	}
+}
+
+func (m *Memory) add(calendarID string, event calendar.Event) string {
+	if event.ID == "" {
+		m.nextID++
+		event.ID = fmt.Sprintf("evt%d", m.nextID)
+	}
+	m.events[calendarID] = append(m.events[calendarID], event)
+	return event.ID
+}
+
+// Events returns all events of a calendar ordered by start time.
+func (m *Memory) Events(calendarID string) []calendar.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := slices.Clone(m.events[calendarID])
+	slices.SortStableFunc(events, func(a, b calendar.Event) int { return a.Start.Compare(b.Start) })
+	return events
+}
+
+func (m *Memory) FetchMeetings(ctx context.Context, calendarID string, start, end time.Time) ([]calendar.Event, error) {
+	var meetings []calendar.Event
+	for _, e := range m.Events(calendarID) {
+		if e.Start.Before(end) && e.End.After(start) {
+			meetings = append(meetings, e)
+		}
+	}
+	return meetings, nil
+}
+
+func (m *Memory) CreateEvent(ctx context.Context, calendarID string, event calendar.Event) (string, error) {
+	if m.FailOn != nil {
+		if err := m.FailOn(event); err != nil {
+			return "", err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event.Managed = event.Type != planner.BlockTypeMeeting
+	return m.add(calendarID, event), nil
+}
+
+// FreeBusy returns the events of each calendar between start and end as busy time.
+// Calendars in Private have Err set.
+func (m *Memory) FreeBusy(ctx context.Context, calendarIDs []string, start, end time.Time) ([]calendar.FreeBusy, error) {
+	result := make([]calendar.FreeBusy, len(calendarIDs))
+	for i, id := range calendarIDs {
+		result[i] = calendar.FreeBusy{CalendarID: id}
+		if m.Private[id] {
+			result[i].Err = fmt.Errorf("free/busy of %s not available", id)
+			continue
+		}
+		events, _ := m.FetchMeetings(ctx, id, start, end)
+		for _, e := range events {
+			result[i].Busy = append(result[i].Busy, planner.TimeBlock{Type: planner.BlockTypeMeeting, Title: "Busy", Start: e.Start, End: e.End})
+		}
+	}
+	return result, nil
+}
+
+// CanWrite reports whether the calendar is not in ReadOnly.
+func (m *Memory) CanWrite(ctx context.Context, calendarID string) (bool, error) {
+	return !m.ReadOnly[calendarID], nil
+}
+
+func (m *Memory) MoveEvent(ctx context.Context, calendarID, eventID string, start, end time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.events[calendarID], func(e calendar.Event) bool { return e.ID == eventID })
+	if i < 0 {
+		return fmt.Errorf("event %s not found", eventID)
+	}
+	m.events[calendarID][i].Start = start
+	m.events[calendarID][i].End = end
+	return nil
+}
+
+func (m *Memory) DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.events[calendarID], func(e calendar.Event) bool { return e.ID == eventID })
+	if i < 0 {
+		return fmt.Errorf("event %s not found", eventID)
+	}
+	m.events[calendarID] = slices.Delete(m.events[calendarID], i, i+1)
+	return nil
+}
@@ -0,0 +1,308 @@
+package calendartest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	gcal "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// Server is a fake Google Calendar API. It supports listing, inserting, moving and
+// deleting events, push notification channels, free/busy queries and the access
+// role of calendars.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	nextID   int
+	events   map[string][]*gcal.Event
+	channels map[string]*gcal.Channel
+	roles    map[string]string
+	updates  map[string]string
+	ttl      time.Duration
+}
+
+// NewServer starts a fake Calendar API server. Callers must Close it.
+func NewServer() *Server {
+	s := &Server{
+		events:   make(map[string][]*gcal.Event),
+		channels: make(map[string]*gcal.Channel),
+		roles:    make(map[string]string),
+		updates:  make(map[string]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /calendars/{calendarID}/events", s.list)
+	mux.HandleFunc("POST /calendars/{calendarID}/events", s.insert)
+	mux.HandleFunc("POST /calendars/{calendarID}/events/watch", s.watch)
+	mux.HandleFunc("POST /channels/stop", s.stop)
+	mux.HandleFunc("PATCH /calendars/{calendarID}/events/{eventID}", s.patch)
+	mux.HandleFunc("DELETE /calendars/{calendarID}/events/{eventID}", s.delete)
+	mux.HandleFunc("POST /freeBusy", s.freeBusy)
+	mux.HandleFunc("GET /users/me/calendarList/{calendarID}", s.calendarListEntry)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Client returns a calendar.GoogleClient talking to this server.
+func (s *Server) Client() *calendar.GoogleClient {
+	service, err := gcal.NewService(context.Background(),
+		option.WithEndpoint(s.URL+"/"),
+		option.WithHTTPClient(s.Server.Client()),
+		option.WithoutAuthentication())
+	if err != nil {
+		panic(fmt.Sprintf("calendartest: creating service: %v", err))
+	}
+	return calendar.NewGoogleClientFromService(service)
+}
+
+// Add seeds an event in a calendar as is.
+func (s *Server) Add(calendarID string, event *gcal.Event) {
+	s.store(calendarID, event)
+}
+
+// AddMeeting seeds a timed event in a calendar.
+func (s *Server) AddMeeting(calendarID, title string, start, end time.Time) {
+	s.store(calendarID, &gcal.Event{
+		Summary: title,
+		Start:   &gcal.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     &gcal.EventDateTime{DateTime: end.Format(time.RFC3339)},
+	})
+}
+
+// SetAccessRole sets the access role of the user to a calendar, e.g. "reader" or
+// "freeBusyReader". Calendars default to "owner". An empty role makes the calendar
+// unknown: free/busy reports it as not found and it is missing from the calendar list.
+func (s *Server) SetAccessRole(calendarID, role string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roles[calendarID] = role
+}
+
+func (s *Server) accessRole(calendarID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if role, ok := s.roles[calendarID]; ok {
+		return role
+	}
+	return calendar.AccessRoleOwner
+}
+
+// Events returns the events stored in a calendar, in insertion order.
+func (s *Server) Events(calendarID string) []*gcal.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.events[calendarID])
+}
+
+func (s *Server) store(calendarID string, event *gcal.Event) *gcal.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	event.Id = fmt.Sprintf("evt%d", s.nextID)
+	s.events[calendarID] = append(s.events[calendarID], event)
+	return event
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	calendarID := r.PathValue("calendarID")
+	timeMin, _ := time.Parse(time.RFC3339, r.URL.Query().Get("timeMin"))
+	timeMax, _ := time.Parse(time.RFC3339, r.URL.Query().Get("timeMax"))
+
+	var items []*gcal.Event
+	for _, e := range s.Events(calendarID) {
+		if e.Start == nil || e.Start.DateTime == "" {
+			items = append(items, e)
+			continue
+		}
+		start, _ := time.Parse(time.RFC3339, e.Start.DateTime)
+		end, _ := time.Parse(time.RFC3339, e.End.DateTime)
+		if (timeMax.IsZero() || start.Before(timeMax)) && (timeMin.IsZero() || end.After(timeMin)) {
+			items = append(items, e)
+		}
+	}
+	slices.SortStableFunc(items, func(a, b *gcal.Event) int {
+		return eventStart(a).Compare(eventStart(b))
+	})
+
+	writeJSON(w, &gcal.Events{Items: items})
+}
+
+func (s *Server) insert(w http.ResponseWriter, r *http.Request) {
+	var event gcal.Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, s.store(r.PathValue("calendarID"), &event))
+}
+
+// patch only supports changing the start and end of an event.
+func (s *Server) patch(w http.ResponseWriter, r *http.Request) {
+	var patch gcal.Event
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calendarID := r.PathValue("calendarID")
+	i := slices.IndexFunc(s.events[calendarID], func(e *gcal.Event) bool { return e.Id == r.PathValue("eventID") })
+	if i < 0 {
+		http.Error(w, `{"error": {"code": 404, "message": "Not Found"}}`, http.StatusNotFound)
+		return
+	}
+	event := s.events[calendarID][i]
+	s.updates[event.Id] = r.URL.Query().Get("sendUpdates")
+	if patch.Start != nil {
+		event.Start = patch.Start
+	}
+	if patch.End != nil {
+		event.End = patch.End
+	}
+	writeJSON(w, event)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calendarID := r.PathValue("calendarID")
+	i := slices.IndexFunc(s.events[calendarID], func(e *gcal.Event) bool { return e.Id == r.PathValue("eventID") })
+	if i < 0 {
+		http.Error(w, `{"error": {"code": 404, "message": "Not Found"}}`, http.StatusNotFound)
+		return
+	}
+	s.events[calendarID] = slices.Delete(s.events[calendarID], i, i+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) freeBusy(w http.ResponseWriter, r *http.Request) {
+	var req gcal.FreeBusyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	timeMin, _ := time.Parse(time.RFC3339, req.TimeMin)
+	timeMax, _ := time.Parse(time.RFC3339, req.TimeMax)
+
+	resp := &gcal.FreeBusyResponse{Calendars: make(map[string]gcal.FreeBusyCalendar)}
+	for _, item := range req.Items {
+		if s.accessRole(item.Id) == "" {
+			resp.Calendars[item.Id] = gcal.FreeBusyCalendar{Errors: []*gcal.Error{{Domain: "global", Reason: "notFound"}}}
+			continue
+		}
+		var busy []*gcal.TimePeriod
+		for _, e := range s.Events(item.Id) {
+			if e.Start == nil || e.Start.DateTime == "" {
+				continue
+			}
+			start, _ := time.Parse(time.RFC3339, e.Start.DateTime)
+			end, _ := time.Parse(time.RFC3339, e.End.DateTime)
+			if start.Before(timeMax) && end.After(timeMin) {
+				busy = append(busy, &gcal.TimePeriod{Start: e.Start.DateTime, End: e.End.DateTime})
+			}
+		}
+		resp.Calendars[item.Id] = gcal.FreeBusyCalendar{Busy: busy}
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) calendarListEntry(w http.ResponseWriter, r *http.Request) {
+	calendarID := r.PathValue("calendarID")
+	role := s.accessRole(calendarID)
+	if role == "" {
+		http.Error(w, `{"error": {"code": 404, "message": "Not Found"}}`, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, &gcal.CalendarListEntry{Id: calendarID, AccessRole: role})
+}
+
+// SentUpdates returns the sendUpdates parameter of the last patch of an event.
+func (s *Server) SentUpdates(eventID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updates[eventID]
+}
+
+// Channels returns the push notification channels that were not stopped.
+func (s *Server) Channels() []*gcal.Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channels := make([]*gcal.Channel, 0, len(s.channels))
+	for _, ch := range s.channels {
+		channels = append(channels, ch)
+	}
+	return channels
+}
+
+// SetChannelTTL sets how long new push notification channels last, a week by default.
+func (s *Server) SetChannelTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ttl = ttl
+}
+
+func (s *Server) watch(w http.ResponseWriter, r *http.Request) {
+	var ch gcal.Channel
+	if err := json.NewDecoder(r.Body).Decode(&ch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch.ResourceId = "resource-" + r.PathValue("calendarID")
+	ttl := s.ttl
+	if ttl == 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	ch.Expiration = time.Now().Add(ttl).UnixMilli()
+	s.channels[ch.Id] = &ch
+	writeJSON(w, &ch)
+}
+
+func (s *Server) stop(w http.ResponseWriter, r *http.Request) {
+	var ch gcal.Channel
+	if err := json.NewDecoder(r.Body).Decode(&ch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.channels, ch.Id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func eventStart(e *gcal.Event) time.Time {
+	if e.Start == nil {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339, e.Start.DateTime)
+	return t
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
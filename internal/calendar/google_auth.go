@@ -4,10 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"os"
 
-	appconfig "github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
@@ -73,7 +72,7 @@ func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
 	return token, nil
 }
 
-func GetClient(ctx context.Context) (*calendar.Service, error) {
+func GetClient(ctx context.Context, policy httpx.Policy) (*calendar.Service, error) {
 	credentials, err := loadCredentials()
 	if err != nil {
 		return nil, err
@@ -95,9 +94,7 @@ func GetClient(ctx context.Context) (*calendar.Service, error) {
 		}
 	}
 
-	baseHTTPClient := &http.Client{
-		Timeout: appconfig.HTTPTimeout,
-	}
+	baseHTTPClient := httpx.NewClient(policy)
 	ctxWithClient := context.WithValue(ctx, oauth2.HTTPClient, baseHTTPClient)
 	tokenSource := oauthConfig.TokenSource(ctxWithClient, token)
 	autoSaveSource := &autoSaveTokenSource{source: tokenSource}
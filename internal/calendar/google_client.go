@@ -2,35 +2,51 @@ package calendar
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
 	"github.com/Alvkoen/barely-incharge/internal/planner"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
 )
 
+const serviceName = "Google Calendar"
+
 type GoogleClient struct {
 	service *calendar.Service
 }
 
-func NewGoogleClient(ctx context.Context) (*GoogleClient, error) {
-	service, err := GetClient(ctx)
+// NewGoogleClient authenticates and returns a client whose requests are
+// retried according to policy.
+func NewGoogleClient(ctx context.Context, policy httpx.Policy) (*GoogleClient, error) {
+	service, err := GetClient(ctx, policy)
 	if err != nil {
 		return nil, err
 	}
-	return &GoogleClient{service: service}, nil
+	return NewGoogleClientFromService(service), nil
+}
+
+// NewGoogleClientFromService wraps an already configured Calendar API service,
+// for example one pointed at a fake server in tests.
+func NewGoogleClientFromService(service *calendar.Service) *GoogleClient {
+	return &GoogleClient{service: service}
 }
 
-func (c *GoogleClient) FetchMeetings(calendarID string, start, end time.Time) ([]Event, error) {
+func (c *GoogleClient) FetchMeetings(ctx context.Context, calendarID string, start, end time.Time) ([]Event, error) {
 	events, err := c.service.Events.List(calendarID).
 		TimeMin(start.Format(time.RFC3339)).
 		TimeMax(end.Format(time.RFC3339)).
 		SingleEvents(true).
 		OrderBy("startTime").
+		Context(ctx).
 		Do()
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch events: %w", err)
+		return nil, fmt.Errorf("failed to fetch events: %w", apiError(ctx, err))
 	}
 
 	meetings := make([]Event, 0, len(events.Items))
@@ -50,11 +66,24 @@ func (c *GoogleClient) FetchMeetings(calendarID string, start, end time.Time) ([
 		}
 
 		meeting := Event{
+			ID:          event.Id,
 			Type:        planner.BlockTypeMeeting,
 			Title:       event.Summary,
 			Description: event.Description,
+			Location:    event.Location,
 			Start:       startTime,
 			End:         endTime,
+			EventType:   event.EventType,
+			Organized:   event.Organizer != nil && event.Organizer.Self,
+		}
+		for _, a := range event.Attendees {
+			if !a.Self && !a.Resource && a.ResponseStatus != "declined" && a.Email != "" {
+				meeting.Attendees = append(meeting.Attendees, a.Email)
+			}
+		}
+		if blockType, ok := managedBlockType(event); ok {
+			meeting.Type = blockType
+			meeting.Managed = true
 		}
 
 		meetings = append(meetings, meeting)
@@ -63,15 +92,18 @@ func (c *GoogleClient) FetchMeetings(calendarID string, start, end time.Time) ([
 	return meetings, nil
 }
 
-func (c *GoogleClient) FetchTodaysMeetings(calendarID string) ([]Event, error) {
+func (c *GoogleClient) FetchTodaysMeetings(ctx context.Context, calendarID string) ([]Event, error) {
 	now := time.Now()
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	endOfDay := startOfDay.Add(24 * time.Hour)
 
-	return c.FetchMeetings(calendarID, startOfDay, endOfDay)
+	return c.FetchMeetings(ctx, calendarID, startOfDay, endOfDay)
 }
 
-func (c *GoogleClient) CreateEvent(calendarID string, event Event) error {
+// CreateEvent inserts event into the calendar and returns the ID of the new event.
+// Events are marked as planned blocks of their type, except meetings, which are
+// created as ordinary events.
+func (c *GoogleClient) CreateEvent(ctx context.Context, calendarID string, event Event) (string, error) {
 	calEvent := &calendar.Event{
 		Summary:     event.Title,
 		Description: event.Description,
@@ -82,11 +114,205 @@ func (c *GoogleClient) CreateEvent(calendarID string, event Event) error {
 			DateTime: event.End.Format(time.RFC3339),
 		},
 	}
+	if event.Type != planner.BlockTypeMeeting {
+		calEvent.ExtendedProperties = &calendar.EventExtendedProperties{
+			Private: map[string]string{
+				PropertyManaged:   "true",
+				PropertyBlockType: event.Type,
+			},
+		}
+	}
+
+	switch event.EventType {
+	case EventTypeFocusTime:
+		calEvent.EventType = EventTypeFocusTime
+		calEvent.FocusTimeProperties = &calendar.EventFocusTimeProperties{
+			AutoDeclineMode: event.AutoDeclineMode,
+			DeclineMessage:  event.DeclineMessage,
+			ChatStatus:      event.ChatStatus,
+		}
+	case EventTypeOutOfOffice:
+		calEvent.EventType = EventTypeOutOfOffice
+		calEvent.OutOfOfficeProperties = &calendar.EventOutOfOfficeProperties{
+			AutoDeclineMode: event.AutoDeclineMode,
+			DeclineMessage:  event.DeclineMessage,
+		}
+	}
+
+	created, err := c.service.Events.Insert(calendarID, calEvent).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to create event: %w", apiError(ctx, err))
+	}
+
+	return created.Id, nil
+}
+
+// DeleteEvent removes an event from the calendar.
+func (c *GoogleClient) DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	if err := c.service.Events.Delete(calendarID, eventID).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to delete event %s: %w", eventID, apiError(ctx, err))
+	}
+	return nil
+}
+
+// MoveEvent changes the start and end of an event, keeping its ID. Attendees are
+// sent an update, as when the event is moved in Google Calendar.
+func (c *GoogleClient) MoveEvent(ctx context.Context, calendarID, eventID string, start, end time.Time) error {
+	patch := &calendar.Event{
+		Start: &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:   &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)},
+	}
+	if _, err := c.service.Events.Patch(calendarID, eventID, patch).SendUpdates("all").Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to move event %s: %w", eventID, apiError(ctx, err))
+	}
+	return nil
+}
+
+// FreeBusy returns the busy time of each calendar between start and end, in the
+// order of calendarIDs. Calendars that cannot be read have Err set; the error
+// returned is for the request as a whole.
+func (c *GoogleClient) FreeBusy(ctx context.Context, calendarIDs []string, start, end time.Time) ([]FreeBusy, error) {
+	items := make([]*calendar.FreeBusyRequestItem, len(calendarIDs))
+	for i, id := range calendarIDs {
+		items[i] = &calendar.FreeBusyRequestItem{Id: id}
+	}
+	resp, err := c.service.Freebusy.Query(&calendar.FreeBusyRequest{
+		TimeMin: start.Format(time.RFC3339),
+		TimeMax: end.Format(time.RFC3339),
+		Items:   items,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query free/busy: %w", apiError(ctx, err))
+	}
+
+	result := make([]FreeBusy, len(calendarIDs))
+	for i, id := range calendarIDs {
+		result[i] = FreeBusy{CalendarID: id}
+		cal, ok := resp.Calendars[id]
+		if !ok {
+			result[i].Err = fmt.Errorf("calendar %s missing from free/busy response", id)
+			continue
+		}
+		if len(cal.Errors) > 0 {
+			reasons := make([]string, len(cal.Errors))
+			for j, e := range cal.Errors {
+				reasons[j] = e.Reason
+			}
+			result[i].Err = fmt.Errorf("free/busy of %s not available (%s)", id, strings.Join(reasons, ", "))
+			continue
+		}
+		for _, period := range cal.Busy {
+			busyStart, err := time.Parse(time.RFC3339, period.Start)
+			if err != nil {
+				continue
+			}
+			busyEnd, err := time.Parse(time.RFC3339, period.End)
+			if err != nil {
+				continue
+			}
+			result[i].Busy = append(result[i].Busy, planner.TimeBlock{
+				Type:  planner.BlockTypeMeeting,
+				Title: "Busy",
+				Start: busyStart,
+				End:   busyEnd,
+			})
+		}
+	}
+	return result, nil
+}
+
+// CanWrite reports whether the user can create events in the calendar. Calendars
+// that are not in the user's calendar list cannot be written to.
+func (c *GoogleClient) CanWrite(ctx context.Context, calendarID string) (bool, error) {
+	entry, err := c.service.CalendarList.Get(calendarID).Context(ctx).Do()
+	if err != nil {
+		var gerr *googleapi.Error
+		if errors.As(err, &gerr) && gerr.Code == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get access to calendar %s: %w", calendarID, apiError(ctx, err))
+	}
+	return entry.AccessRole == AccessRoleOwner || entry.AccessRole == AccessRoleWriter, nil
+}
+
+// Channel is a push notification channel for changes to the events of a calendar.
+type Channel struct {
+	ID         string
+	ResourceID string
+	Expiration time.Time
+}
 
-	_, err := c.service.Events.Insert(calendarID, calEvent).Do()
+// WatchEvents asks Google to send a notification to address, a public HTTPS URL,
+// whenever events in the calendar change. Notifications carry token in the
+// X-Goog-Channel-Token header.
+func (c *GoogleClient) WatchEvents(ctx context.Context, calendarID, channelID, address, token string) (*Channel, error) {
+	created, err := c.service.Events.Watch(calendarID, &calendar.Channel{
+		Id:      channelID,
+		Type:    "web_hook",
+		Address: address,
+		Token:   token,
+	}).Context(ctx).Do()
 	if err != nil {
-		return fmt.Errorf("failed to create event: %w", err)
+		return nil, fmt.Errorf("failed to watch calendar: %w", apiError(ctx, err))
 	}
 
+	return &Channel{
+		ID:         created.Id,
+		ResourceID: created.ResourceId,
+		Expiration: time.UnixMilli(created.Expiration),
+	}, nil
+}
+
+// StopChannel stops notifications for a channel created by WatchEvents.
+func (c *GoogleClient) StopChannel(ctx context.Context, ch *Channel) error {
+	if err := c.service.Channels.Stop(&calendar.Channel{Id: ch.ID, ResourceId: ch.ResourceID}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to stop channel %s: %w", ch.ID, apiError(ctx, err))
+	}
 	return nil
 }
+
+// managedBlockType reports whether event was created by Barely In Charge and
+// returns the type of the planned block.
+func managedBlockType(event *calendar.Event) (string, bool) {
+	if event.ExtendedProperties != nil && event.ExtendedProperties.Private[PropertyManaged] != "" {
+		if blockType := event.ExtendedProperties.Private[PropertyBlockType]; blockType != "" {
+			return blockType, true
+		}
+	}
+
+	// Older events are only recognizable by their description
+	if !strings.Contains(event.Description, managedMarker) {
+		return "", false
+	}
+	switch {
+	case event.EventType == EventTypeOutOfOffice:
+		return planner.BlockTypeOutOfOffice, true
+	case strings.HasPrefix(event.Description, "Break"):
+		return planner.BlockTypeBreak, true
+	default:
+		return planner.BlockTypeFocus, true
+	}
+}
+
+// apiError classifies errors returned by the Calendar API as httpx errors.
+// Cancellation is returned as is.
+func apiError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		message := gerr.Message
+		reasons := make([]string, 0, len(gerr.Errors))
+		for _, item := range gerr.Errors {
+			reasons = append(reasons, item.Reason)
+		}
+		if len(reasons) > 0 {
+			message += " (" + strings.Join(reasons, ", ") + ")"
+		}
+		return httpx.NewStatusError(serviceName, gerr.Code, message)
+	}
+
+	return httpx.NewNetworkError(serviceName, err)
+}
@@ -0,0 +1,241 @@
+package calendar_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	gcal "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+func TestGoogleClientFetchAndCreate(t *testing.T) {
+	server := calendartest.NewServer()
+	defer server.Close()
+
+	day := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	server.AddMeeting("primary", "Standup", day.Add(10*time.Hour), day.Add(10*time.Hour+15*time.Minute))
+	server.AddMeeting("primary", "Tomorrow", day.Add(34*time.Hour), day.Add(35*time.Hour))
+
+	client := server.Client()
+
+	meetings, err := client.FetchMeetings(context.Background(), "primary", day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("FetchMeetings() error: %v", err)
+	}
+	if len(meetings) != 1 || meetings[0].Title != "Standup" || meetings[0].Type != planner.BlockTypeMeeting {
+		t.Fatalf("unexpected meetings: %+v", meetings)
+	}
+
+	id, err := client.CreateEvent(context.Background(), "primary", calendar.Event{
+		Title:           "Focus time",
+		Start:           day.Add(11 * time.Hour),
+		End:             day.Add(12 * time.Hour),
+		EventType:       calendar.EventTypeFocusTime,
+		AutoDeclineMode: "declineOnlyNewConflictingInvitations",
+		ChatStatus:      "doNotDisturb",
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent() error: %v", err)
+	}
+
+	events := server.Events("primary")
+	created := events[len(events)-1]
+	if created.Id != id || created.Summary != "Focus time" || created.EventType != calendar.EventTypeFocusTime {
+		t.Errorf("unexpected created event: %+v", created)
+	}
+	if created.FocusTimeProperties == nil || created.FocusTimeProperties.ChatStatus != "doNotDisturb" {
+		t.Errorf("expected focus time properties, got %+v", created.FocusTimeProperties)
+	}
+
+	if err := client.DeleteEvent(context.Background(), "primary", id); err != nil {
+		t.Fatalf("DeleteEvent() error: %v", err)
+	}
+	if got := len(server.Events("primary")); got != len(events)-1 {
+		t.Errorf("expected the event to be deleted, %d events left", got)
+	}
+	if err := client.DeleteEvent(context.Background(), "primary", id); !errors.Is(err, httpx.ErrBadRequest) {
+		t.Errorf("DeleteEvent() of a missing event error = %v, want %v", err, httpx.ErrBadRequest)
+	}
+}
+
+func TestGoogleClientClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		reason string
+		want   error
+	}{
+		{"rate limited", http.StatusForbidden, "rateLimitExceeded", httpx.ErrQuota},
+		{"forbidden", http.StatusForbidden, "forbidden", httpx.ErrAuth},
+		{"not found", http.StatusNotFound, "notFound", httpx.ErrBadRequest},
+		{"backend error", http.StatusServiceUnavailable, "backendError", httpx.ErrTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"error": {"message": "nope", "errors": [{"reason": "` + tt.reason + `"}]}}`))
+			}))
+			defer server.Close()
+
+			service, err := gcal.NewService(context.Background(),
+				option.WithEndpoint(server.URL+"/"),
+				option.WithHTTPClient(server.Client()),
+				option.WithoutAuthentication())
+			if err != nil {
+				t.Fatalf("NewService() error: %v", err)
+			}
+			client := calendar.NewGoogleClientFromService(service)
+
+			_, err = client.FetchMeetings(context.Background(), "primary", time.Now(), time.Now().Add(time.Hour))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("FetchMeetings() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoogleClientRecognizesManagedEvents(t *testing.T) {
+	server := calendartest.NewServer()
+	defer server.Close()
+
+	day := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	server.AddMeeting("primary", "Standup", day.Add(9*time.Hour), day.Add(9*time.Hour+15*time.Minute))
+	// Created by an older version, before events carried properties
+	server.Add("primary", &gcal.Event{
+		Summary:     "Break",
+		Description: "Break block planned by Barely In Charge: Coffee",
+		Start:       &gcal.EventDateTime{DateTime: day.Add(11 * time.Hour).Format(time.RFC3339)},
+		End:         &gcal.EventDateTime{DateTime: day.Add(11*time.Hour + 15*time.Minute).Format(time.RFC3339)},
+	})
+
+	client := server.Client()
+	_, err := client.CreateEvent(context.Background(), "primary", calendar.Event{
+		Type:  planner.BlockTypeFocus,
+		Title: "Focus time",
+		Start: day.Add(10 * time.Hour),
+		End:   day.Add(11 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent() error: %v", err)
+	}
+
+	events, err := client.FetchMeetings(context.Background(), "primary", day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("FetchMeetings() error: %v", err)
+	}
+
+	want := []struct {
+		blockType string
+		managed   bool
+	}{
+		{planner.BlockTypeMeeting, false},
+		{planner.BlockTypeFocus, true},
+		{planner.BlockTypeBreak, true},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i, e := range events {
+		if e.Type != want[i].blockType || e.Managed != want[i].managed {
+			t.Errorf("%s: type = %s, managed = %v, want %s, %v", e.Title, e.Type, e.Managed, want[i].blockType, want[i].managed)
+		}
+	}
+}
+
+func TestGoogleClientMoveAndWatch(t *testing.T) {
+	server := calendartest.NewServer()
+	defer server.Close()
+
+	day := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	server.AddMeeting("primary", "Standup", day.Add(10*time.Hour), day.Add(10*time.Hour+15*time.Minute))
+	client := server.Client()
+	ctx := context.Background()
+
+	if err := client.MoveEvent(ctx, "primary", "evt1", day.Add(11*time.Hour), day.Add(11*time.Hour+30*time.Minute)); err != nil {
+		t.Fatalf("MoveEvent() error: %v", err)
+	}
+	meetings, err := client.FetchMeetings(ctx, "primary", day, day.Add(24*time.Hour))
+	if err != nil || len(meetings) != 1 {
+		t.Fatalf("FetchMeetings() = %+v, %v", meetings, err)
+	}
+	if meetings[0].ID != "evt1" || !meetings[0].Start.Equal(day.Add(11*time.Hour)) || meetings[0].Title != "Standup" {
+		t.Errorf("unexpected moved event: %+v", meetings[0])
+	}
+	if err := client.MoveEvent(ctx, "primary", "missing", day, day.Add(time.Hour)); !errors.Is(err, httpx.ErrBadRequest) {
+		t.Errorf("MoveEvent() of a missing event error = %v, want ErrBadRequest", err)
+	}
+
+	ch, err := client.WatchEvents(ctx, "primary", "chan-1", "https://example.test/hook", "secret")
+	if err != nil {
+		t.Fatalf("WatchEvents() error: %v", err)
+	}
+	if ch.ID != "chan-1" || ch.ResourceID == "" || !ch.Expiration.After(time.Now()) {
+		t.Errorf("unexpected channel: %+v", ch)
+	}
+	if channels := server.Channels(); len(channels) != 1 || channels[0].Token != "secret" || channels[0].Type != "web_hook" {
+		t.Errorf("unexpected registered channels: %+v", channels)
+	}
+	if err := client.StopChannel(ctx, ch); err != nil {
+		t.Fatalf("StopChannel() error: %v", err)
+	}
+	if channels := server.Channels(); len(channels) != 0 {
+		t.Errorf("expected the channel to be stopped, got %+v", channels)
+	}
+}
+
+func TestGoogleClientFreeBusy(t *testing.T) {
+	server := calendartest.NewServer()
+	defer server.Close()
+
+	day := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	server.AddMeeting("ana@example.com", "1:1", day.Add(10*time.Hour), day.Add(11*time.Hour))
+	server.AddMeeting("ana@example.com", "Tomorrow", day.Add(34*time.Hour), day.Add(35*time.Hour))
+	server.SetAccessRole("ana@example.com", "freeBusyReader")
+	server.SetAccessRole("bo@example.com", "")
+
+	client := server.Client()
+	result, err := client.FreeBusy(context.Background(), []string{"ana@example.com", "bo@example.com"}, day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("FreeBusy() error: %v", err)
+	}
+	if len(result) != 2 || result[0].Err != nil || len(result[0].Busy) != 1 || !result[0].Busy[0].Start.Equal(day.Add(10*time.Hour)) {
+		t.Fatalf("unexpected free/busy: %+v", result)
+	}
+	if result[1].Err == nil {
+		t.Errorf("expected an error for a calendar that is not shared")
+	}
+
+	for id, want := range map[string]bool{"primary": true, "ana@example.com": false, "bo@example.com": false} {
+		got, err := client.CanWrite(context.Background(), id)
+		if err != nil || got != want {
+			t.Errorf("CanWrite(%s) = %v, %v, want %v", id, got, err, want)
+		}
+	}
+
+	if _, err := client.CreateEvent(context.Background(), "primary", calendar.Event{
+		Type:  planner.BlockTypeMeeting,
+		Title: "Team focus time",
+		Start: day.Add(14 * time.Hour),
+		End:   day.Add(16 * time.Hour),
+	}); err != nil {
+		t.Fatalf("CreateEvent() error: %v", err)
+	}
+	meetings, err := client.FetchMeetings(context.Background(), "primary", day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("FetchMeetings() error: %v", err)
+	}
+	if len(meetings) != 1 || meetings[0].Managed {
+		t.Errorf("expected meetings to be created as ordinary events, got %+v", meetings)
+	}
+}
@@ -6,19 +6,70 @@ import (
 	"github.com/Alvkoen/barely-incharge/internal/planner"
 )
 
+// Private extended properties marking events created by Barely In Charge.
+const (
+	PropertyManaged   = "barelyInCharge"
+	PropertyBlockType = "blockType"
+)
+
+// managedMarker is part of the description of every block created by Barely In
+// Charge, including those created before events were marked with properties.
+const managedMarker = "planned by Barely In Charge"
+
+const (
+	EventTypeDefault     = "default"
+	EventTypeFocusTime   = "focusTime"
+	EventTypeOutOfOffice = "outOfOffice"
+)
+
 type Event struct {
+	// ID is the calendar's event ID. It is empty for events not yet created.
+	ID          string
 	Type        string
 	Title       string
 	Description string
+	Location    string
 	Start       time.Time
 	End         time.Time
+	// Managed is set on fetched events that were created by Barely In Charge.
+	// Their Type is the planned block type instead of meeting.
+	Managed bool
+	// Organized is set on fetched events the user organizes, which they can
+	// move for everyone.
+	Organized bool
+	// Attendees lists the email addresses of the other people invited to a
+	// fetched event who have not declined it.
+	Attendees []string
+
+	// EventType is the Google Calendar event type. Focus time and out-of-office
+	// events use the auto-decline and chat status settings below.
+	EventType       string
+	AutoDeclineMode string
+	DeclineMessage  string
+	ChatStatus      string
 }
 
 func (e Event) ToTimeBlock() planner.TimeBlock {
 	return planner.TimeBlock{
-		Type:  e.Type,
-		Title: e.Title,
-		Start: e.Start,
-		End:   e.End,
+		Type:     e.Type,
+		Title:    e.Title,
+		Start:    e.Start,
+		End:      e.End,
+		Location: e.Location,
 	}
 }
+
+// Calendar access roles that allow creating events.
+const (
+	AccessRoleOwner  = "owner"
+	AccessRoleWriter = "writer"
+)
+
+// FreeBusy is the busy time of one calendar.
+type FreeBusy struct {
+	CalendarID string
+	Busy       []planner.TimeBlock
+	// Err is set when the busy time of the calendar could not be read, e.g.
+	// because it is not shared with the user.
+	Err error
+}
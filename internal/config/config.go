@@ -5,28 +5,55 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
 )
 
 const (
-	ModeCrunch  = "crunch"
-	ModeNormal  = "normal"
-	ModeSaver   = "saver"
-	DateFormat  = "2006-01-02"
-	HTTPTimeout = 30 * time.Second
+	ModeCrunch   = "crunch"
+	ModeNormal   = "normal"
+	ModeSaver    = "saver"
+	ModePomodoro = "pomodoro"
+	DateFormat   = "2006-01-02"
+	HTTPTimeout  = 30 * time.Second
+	// AITimeout is the default per-attempt timeout for OpenAI, which needs
+	// longer than calendar calls to produce a completion.
+	AITimeout = 5 * time.Minute
+	// defaultDataDir is the directory under the home directory used for
+	// history and other local state when data_dir is not set.
+	defaultDataDir = ".barely-incharge"
+	// defaultPromptsDir is the directory next to the config file read for
+	// prompt template overrides when prompts_dir is not set.
+	defaultPromptsDir = "prompts"
 )
 
-var ValidModes = []string{ModeCrunch, ModeNormal, ModeSaver}
-
 type Config struct {
-	WorkHours    TimeRange `json:"work_hours"`
-	LunchTime    TimeRange `json:"lunch_time"`
-	Calendar     string    `json:"calendar"`
-	DefaultMode  string    `json:"default_mode"`
-	OpenAIAPIKey string    `json:"openai_api_key"`
-	Date         string    `json:"date"`
+	WorkHours    TimeRange     `json:"work_hours"`
+	LunchTime    TimeRange     `json:"lunch_time"`
+	Calendar     string        `json:"calendar"`
+	DefaultMode  string        `json:"default_mode"`
+	OpenAIAPIKey string        `json:"openai_api_key"`
+	OpenAIModel  string        `json:"openai_model,omitempty"`
+	Date         string        `json:"date"`
+	Modes        []Mode        `json:"modes,omitempty"`
+	Energy       EnergyProfile `json:"energy"`
+	Buffers      Buffers       `json:"buffers"`
+	FocusTime    FocusTime     `json:"focus_time"`
+	OutOfOffice  OutOfOffice   `json:"out_of_office"`
+	HTTP         HTTP          `json:"http"`
+	DataDir      string        `json:"data_dir,omitempty"`
+	PromptsDir   string        `json:"prompts_dir,omitempty"`
+	Targets      Targets       `json:"targets"`
+	Suggestions  Suggestions   `json:"suggestions"`
+	Privacy      Privacy       `json:"privacy"`
+	Team         Team          `json:"team"`
+	Analyze      Analyze       `json:"analyze"`
+	Routines     []Routine     `json:"routines,omitempty"`
 }
 
 type TimeRange struct {
@@ -34,6 +61,400 @@ type TimeRange struct {
 	End   string `json:"end"`
 }
 
+// EnergyProfile describes when during the day the user has the most and the
+// least energy. Deep work is placed in peak windows, shallow work in slumps
+// and the wind-down.
+type EnergyProfile struct {
+	Peak     []TimeRange `json:"peak,omitempty"`
+	Slump    []TimeRange `json:"slump,omitempty"`
+	WindDown []TimeRange `json:"wind_down,omitempty"`
+}
+
+// Buffers configures the padding kept free around meetings, in minutes.
+type Buffers struct {
+	BeforeMeetingMinutes int `json:"before_meeting_minutes"`
+	AfterMeetingMinutes  int `json:"after_meeting_minutes"`
+	MinGapMinutes        int `json:"min_gap_minutes"`
+	TravelMinutes        int `json:"travel_minutes"`
+}
+
+func (b Buffers) BeforeMeeting() time.Duration { return minutes(b.BeforeMeetingMinutes) }
+func (b Buffers) AfterMeeting() time.Duration  { return minutes(b.AfterMeetingMinutes) }
+func (b Buffers) MinGap() time.Duration        { return minutes(b.MinGapMinutes) }
+func (b Buffers) Travel() time.Duration        { return minutes(b.TravelMinutes) }
+
+func (b Buffers) Validate() error {
+	if b.BeforeMeetingMinutes < 0 || b.AfterMeetingMinutes < 0 || b.MinGapMinutes < 0 || b.TravelMinutes < 0 {
+		return fmt.Errorf("invalid buffers: durations must not be negative")
+	}
+	return nil
+}
+
+// HTTP configures timeouts and retries for the OpenAI and Google Calendar APIs.
+// Zero values use the defaults; a negative max_retries disables retries.
+type HTTP struct {
+	TimeoutSeconds   int `json:"timeout_seconds,omitempty"`
+	AITimeoutSeconds int `json:"ai_timeout_seconds,omitempty"`
+	MaxRetries       int `json:"max_retries,omitempty"`
+}
+
+// Timeout returns the per-request timeout for Google Calendar.
+func (h HTTP) Timeout() time.Duration {
+	if h.TimeoutSeconds == 0 {
+		return HTTPTimeout
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+// AITimeout returns the per-request timeout for OpenAI.
+func (h HTTP) AITimeout() time.Duration {
+	if h.AITimeoutSeconds == 0 {
+		return AITimeout
+	}
+	return time.Duration(h.AITimeoutSeconds) * time.Second
+}
+
+// Retries returns the number of retries after a failed request.
+func (h HTTP) Retries() int {
+	switch {
+	case h.MaxRetries == 0:
+		return httpx.DefaultMaxRetries
+	case h.MaxRetries < 0:
+		return 0
+	default:
+		return h.MaxRetries
+	}
+}
+
+func (h HTTP) Validate() error {
+	if h.TimeoutSeconds < 0 {
+		return fmt.Errorf("invalid http.timeout_seconds: must not be negative")
+	}
+	if h.AITimeoutSeconds < 0 {
+		return fmt.Errorf("invalid http.ai_timeout_seconds: must not be negative")
+	}
+	return nil
+}
+
+// Targets are the weekly goals the report compares against. Zero values are not checked.
+type Targets struct {
+	FocusHoursPerWeek        float64 `json:"focus_hours_per_week,omitempty"`
+	MaxMeetingHoursPerWeek   float64 `json:"max_meeting_hours_per_week,omitempty"`
+	MinFreeBlockMinutes      int     `json:"min_free_block_minutes,omitempty"`
+	MaxContextSwitchesPerDay int     `json:"max_context_switches_per_day,omitempty"`
+}
+
+func (t Targets) Validate() error {
+	if t.FocusHoursPerWeek < 0 || t.MaxMeetingHoursPerWeek < 0 || t.MinFreeBlockMinutes < 0 || t.MaxContextSwitchesPerDay < 0 {
+		return fmt.Errorf("invalid targets: values must not be negative")
+	}
+	return nil
+}
+
+// DefaultNotesTag marks meetings that need notes when suggestions.notes_tag is not set.
+const DefaultNotesTag = "#notes"
+
+// Suggestions controls whether meeting titles and descriptions are shared with
+// the planner so it can suggest prep and follow-up blocks. Meetings whose title
+// or description contains the notes tag get a follow-up block for notes.
+type Suggestions struct {
+	Enabled  bool   `json:"enabled"`
+	NotesTag string `json:"notes_tag,omitempty"`
+}
+
+// Tag returns the tag marking meetings that need notes.
+func (s Suggestions) Tag() string {
+	if s.NotesTag == "" {
+		return DefaultNotesTag
+	}
+	return s.NotesTag
+}
+
+// Routine is a personal block planned on the days it applies to, such as going
+// through the inbox every morning. A fixed routine is planned at Time like lunch
+// and skipped when a meeting is in the way; a flexible one is moved to the free
+// slot nearest to Time.
+type Routine struct {
+	Title   string `json:"title"`
+	Minutes int    `json:"minutes"`
+	Time    string `json:"time"`
+	// Weekdays lists the days the routine applies to, e.g. "mon" or "friday".
+	// Empty means every day.
+	Weekdays []string `json:"weekdays,omitempty"`
+	Flexible bool     `json:"flexible,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Duration returns the length of the routine.
+func (r Routine) Duration() time.Duration {
+	return minutes(r.Minutes)
+}
+
+// AppliesOn reports whether the routine is planned on the weekday of date.
+func (r Routine) AppliesOn(date time.Time) bool {
+	if len(r.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range r.Weekdays {
+		if day, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]; ok && day == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Routine) Validate() error {
+	if strings.TrimSpace(r.Title) == "" {
+		return fmt.Errorf("routine has no title")
+	}
+	if r.Minutes <= 0 {
+		return fmt.Errorf("routine %s: minutes must be positive", r.Title)
+	}
+	if _, err := time.Parse(planner.TimeFormat, r.Time); err != nil {
+		return fmt.Errorf("routine %s: invalid time %q (expected HH:MM)", r.Title, r.Time)
+	}
+	for _, d := range r.Weekdays {
+		if _, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]; !ok {
+			return fmt.Errorf("routine %s: invalid weekday %q (expected e.g. mon or monday)", r.Title, d)
+		}
+	}
+	return nil
+}
+
+// DefaultMovableTag marks meetings that may be moved when analyze.movable_tag is not set.
+const DefaultMovableTag = "#movable"
+
+// Analyze controls which meetings the analyze command may suggest moving: the
+// ones the user organizes and those whose title or description contains the
+// movable tag.
+type Analyze struct {
+	MovableTag string `json:"movable_tag,omitempty"`
+}
+
+// Tag returns the tag marking meetings that may be moved.
+func (a Analyze) Tag() string {
+	if a.MovableTag == "" {
+		return DefaultMovableTag
+	}
+	return a.MovableTag
+}
+
+// Defaults for the shared focus block proposed by the team command.
+const (
+	DefaultTeamFocusMinutes = 120
+	DefaultTeamFocusTitle   = "Team focus time"
+)
+
+// Team lists the teammates to find a shared focus window with. Their calendars
+// are read through the FreeBusy API, so they only need to share free/busy
+// information.
+type Team struct {
+	Members      []TeamMember `json:"members,omitempty"`
+	FocusMinutes int          `json:"focus_minutes,omitempty"`
+	FocusTitle   string       `json:"focus_title,omitempty"`
+}
+
+// TeamMember is a teammate. Work hours default to the user's own and are in the
+// member's time zone, an IANA name such as "Europe/Berlin", or local time.
+type TeamMember struct {
+	Name      string    `json:"name"`
+	Calendar  string    `json:"calendar"`
+	WorkHours TimeRange `json:"work_hours,omitzero"`
+	TimeZone  string    `json:"time_zone,omitempty"`
+}
+
+// FocusLength returns the length of the shared focus block to propose.
+func (t Team) FocusLength() time.Duration {
+	if t.FocusMinutes <= 0 {
+		return DefaultTeamFocusMinutes * time.Minute
+	}
+	return time.Duration(t.FocusMinutes) * time.Minute
+}
+
+// Title returns the title of the shared focus block.
+func (t Team) Title() string {
+	if t.FocusTitle == "" {
+		return DefaultTeamFocusTitle
+	}
+	return t.FocusTitle
+}
+
+func (t Team) Validate() error {
+	if t.FocusMinutes < 0 {
+		return fmt.Errorf("team.focus_minutes must not be negative")
+	}
+	for i, m := range t.Members {
+		if strings.TrimSpace(m.Calendar) == "" {
+			return fmt.Errorf("team member %d has no calendar", i+1)
+		}
+		if m.WorkHours != (TimeRange{}) {
+			if err := m.WorkHours.Validate(); err != nil {
+				return fmt.Errorf("invalid work hours for team member %s: %w", m.Label(), err)
+			}
+		}
+		if _, err := m.Location(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Label returns the name of the member, or the calendar when it has none.
+func (m TeamMember) Label() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return m.Calendar
+}
+
+// Location returns the time zone of the member.
+func (m TeamMember) Location() (*time.Location, error) {
+	if m.TimeZone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(m.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone for team member %s: %w", m.Label(), err)
+	}
+	return loc, nil
+}
+
+// Privacy policy values.
+const (
+	PrivacyKeep      = "keep"
+	PrivacyAnonymize = "anonymize"
+	PrivacyHash      = "hash"
+	PrivacyRedact    = "redact"
+)
+
+var (
+	ValidMeetingTitlePolicies = []string{PrivacyKeep, PrivacyAnonymize}
+	ValidTaskTitlePolicies    = []string{PrivacyKeep, PrivacyHash, PrivacyRedact}
+)
+
+// Privacy controls what is sent to the planner. Meeting titles can be replaced
+// by "Busy", task titles by stand-ins that are mapped back locally, and meeting
+// descriptions left out. Meeting and task titles or descriptions matching a
+// denylist pattern are always hidden, whatever the policy. Empty values keep
+// the data as is.
+type Privacy struct {
+	MeetingTitles    string   `json:"meeting_titles,omitempty"`
+	TaskTitles       string   `json:"task_titles,omitempty"`
+	DropDescriptions bool     `json:"drop_descriptions"`
+	Denylist         []string `json:"denylist,omitempty"`
+}
+
+// DenylistPatterns compiles the denylist. Patterns are case-insensitive.
+func (p Privacy) DenylistPatterns() ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(p.Denylist))
+	for _, d := range p.Denylist {
+		re, err := regexp.Compile("(?i)" + d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid privacy.denylist pattern %q: %w", d, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+func (p Privacy) Validate() error {
+	if err := validateOneOf("privacy.meeting_titles", p.MeetingTitles, ValidMeetingTitlePolicies); err != nil {
+		return err
+	}
+	if err := validateOneOf("privacy.task_titles", p.TaskTitles, ValidTaskTitlePolicies); err != nil {
+		return err
+	}
+	_, err := p.DenylistPatterns()
+	return err
+}
+
+// FocusTime controls whether focus blocks are created as native Google
+// Calendar "Focus time" events.
+type FocusTime struct {
+	Enabled         bool   `json:"enabled"`
+	AutoDeclineMode string `json:"auto_decline_mode,omitempty"`
+	DeclineMessage  string `json:"decline_message,omitempty"`
+	ChatStatus      string `json:"chat_status,omitempty"`
+}
+
+// OutOfOffice controls whether the time freed by finishing early (modes with
+// end_early) is blocked as a native Google Calendar "Out of office" event.
+type OutOfOffice struct {
+	Enabled         bool   `json:"enabled"`
+	AutoDeclineMode string `json:"auto_decline_mode,omitempty"`
+	DeclineMessage  string `json:"decline_message,omitempty"`
+}
+
+var (
+	ValidAutoDeclineModes = []string{"declineNone", "declineAllConflictingInvitations", "declineOnlyNewConflictingInvitations"}
+	ValidChatStatuses     = []string{"available", "doNotDisturb"}
+)
+
+func (f FocusTime) Validate() error {
+	if err := validateOneOf("focus_time.auto_decline_mode", f.AutoDeclineMode, ValidAutoDeclineModes); err != nil {
+		return err
+	}
+	return validateOneOf("focus_time.chat_status", f.ChatStatus, ValidChatStatuses)
+}
+
+func (o OutOfOffice) Validate() error {
+	return validateOneOf("out_of_office.auto_decline_mode", o.AutoDeclineMode, ValidAutoDeclineModes)
+}
+
+func validateOneOf(field, value string, valid []string) error {
+	if value != "" && !slices.Contains(valid, value) {
+		return fmt.Errorf("invalid %s: %s (valid values: %s)", field, value, strings.Join(valid, ", "))
+	}
+	return nil
+}
+
+func minutes(n int) time.Duration {
+	return time.Duration(n) * time.Minute
+}
+
+func (r TimeRange) Validate() error {
+	start, err := time.Parse(planner.TimeFormat, r.Start)
+	if err != nil {
+		return fmt.Errorf("invalid start time %q (expected HH:MM)", r.Start)
+	}
+	end, err := time.Parse(planner.TimeFormat, r.End)
+	if err != nil {
+		return fmt.Errorf("invalid end time %q (expected HH:MM)", r.End)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("end time %s must be after start time %s", r.End, r.Start)
+	}
+	return nil
+}
+
+func (p EnergyProfile) Validate() error {
+	groups := []struct {
+		name   string
+		ranges []TimeRange
+	}{
+		{"peak", p.Peak},
+		{"slump", p.Slump},
+		{"wind_down", p.WindDown},
+	}
+	for _, g := range groups {
+		for _, r := range g.ranges {
+			if err := r.Validate(); err != nil {
+				return fmt.Errorf("invalid energy %s window: %w", g.name, err)
+			}
+		}
+	}
+	return nil
+}
+
 func GetConfigPath() (string, error) {
 	execPath, err := os.Executable()
 	if err != nil {
@@ -44,6 +465,47 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(execDir, "config.json"), nil
 }
 
+// GetDataDir returns the directory for local state such as the plan history.
+// A leading "~/" in data_dir is expanded to the home directory.
+func (c *Config) GetDataDir() (string, error) {
+	if c.DataDir != "" {
+		return expandHome(c.DataDir)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, defaultDataDir), nil
+}
+
+// GetPromptsDir returns the directory with prompt template overrides: prompts_dir,
+// or a prompts directory next to the config file.
+func (c *Config) GetPromptsDir() (string, error) {
+	if c.PromptsDir != "" {
+		return expandHome(c.PromptsDir)
+	}
+
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), defaultPromptsDir), nil
+}
+
+// expandHome expands a leading "~/" in path to the home directory.
+func expandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, rest), nil
+}
+
 func Load() (*Config, error) {
 	configPath, err := GetConfigPath()
 	if err != nil {
@@ -66,23 +528,72 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// IsValidMode reports whether mode is one of the built-in modes.
 func IsValidMode(mode string) bool {
-	return slices.Contains(ValidModes, mode)
+	return slices.ContainsFunc(BuiltinModes(), func(m Mode) bool {
+		return m.Name == mode
+	})
 }
 
+// ValidateMode checks mode against the built-in modes only. Use Config.GetMode
+// to also accept modes defined in the config file.
 func ValidateMode(mode string) error {
-	if !IsValidMode(mode) {
-		return fmt.Errorf("invalid mode: %s (valid modes: %s)",
-			mode, strings.Join(ValidModes, ", "))
-	}
-	return nil
+	_, err := (&Config{}).GetMode(mode)
+	return err
 }
 
 func (c *Config) Validate() error {
-	if err := ValidateMode(c.DefaultMode); err != nil {
+	for i, m := range c.Modes {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("invalid mode in config: %w", err)
+		}
+		if slices.ContainsFunc(c.Modes[:i], func(o Mode) bool { return o.Name == m.Name }) {
+			return fmt.Errorf("invalid mode in config: mode %s is defined more than once", m.Name)
+		}
+	}
+
+	if _, err := c.GetMode(c.DefaultMode); err != nil {
 		return fmt.Errorf("invalid default_mode in config: %w", err)
 	}
 
+	if err := c.Energy.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Buffers.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.FocusTime.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.OutOfOffice.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.HTTP.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Targets.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Privacy.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Team.Validate(); err != nil {
+		return err
+	}
+
+	for _, r := range c.Routines {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("invalid routine in config: %w", err)
+		}
+	}
+
 	if c.Date != "" {
 		if _, err := time.Parse(DateFormat, c.Date); err != nil {
 			return fmt.Errorf("invalid date format (expected YYYY-MM-DD): %w", err)
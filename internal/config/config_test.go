@@ -1,8 +1,12 @@
 package config
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
 )
 
 func TestIsValidMode(t *testing.T) {
@@ -14,6 +18,7 @@ func TestIsValidMode(t *testing.T) {
 		{"valid crunch", "crunch", true},
 		{"valid normal", "normal", true},
 		{"valid saver", "saver", true},
+		{"valid pomodoro", "pomodoro", true},
 		{"invalid turbo", "turbo", false},
 		{"invalid empty", "", false},
 		{"invalid uppercase", "CRUNCH", false},
@@ -92,6 +97,14 @@ func TestConfigValidate(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "duplicate mode",
+			config: Config{
+				DefaultMode: "normal",
+				Modes:       []Mode{{Name: "deep", MaxFocusMinutes: 90}, {Name: "deep", MaxFocusMinutes: 120}},
+			},
+			expectErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -198,3 +211,324 @@ func TestGetPlanningDate(t *testing.T) {
 		})
 	}
 }
+
+func TestGetModeWithCustomModes(t *testing.T) {
+	cfg := Config{
+		DefaultMode: "deep",
+		Modes: []Mode{
+			{Name: "deep", MinFocusMinutes: 90, MaxFocusMinutes: 120, BreakMinutes: 15, LongBreakMinutes: 30, LongBreakEvery: 2},
+			{Name: "crunch", MinFocusMinutes: 60, MaxFocusMinutes: 90, BreakMinutes: 10},
+		},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() expected no error but got: %v", err)
+	}
+
+	deep, err := cfg.GetMode("deep")
+	if err != nil {
+		t.Fatalf("GetMode(deep) expected no error but got: %v", err)
+	}
+	if deep.LongBreakEvery != 2 {
+		t.Errorf("deep.LongBreakEvery = %d, want 2", deep.LongBreakEvery)
+	}
+
+	crunch, err := cfg.GetMode("crunch")
+	if err != nil {
+		t.Fatalf("GetMode(crunch) expected no error but got: %v", err)
+	}
+	if crunch.BreakMinutes != 10 {
+		t.Errorf("overridden crunch.BreakMinutes = %d, want 10", crunch.BreakMinutes)
+	}
+
+	if _, err := cfg.GetMode("saver"); err != nil {
+		t.Errorf("GetMode(saver) should still return the built-in mode, got: %v", err)
+	}
+
+	names := cfg.ModeNames()
+	expected := []string{"crunch", "normal", "saver", "pomodoro", "deep"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("ModeNames() = %v, want %v", names, expected)
+	}
+
+	_, err = cfg.GetMode("turbo")
+	if err == nil || !strings.Contains(err.Error(), "deep") {
+		t.Errorf("GetMode(turbo) error should list custom modes, got: %v", err)
+	}
+}
+
+func TestModeValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      Mode
+		expectErr bool
+	}{
+		{"valid mode", Mode{Name: "deep", MinFocusMinutes: 60, MaxFocusMinutes: 120}, false},
+		{"name only", Mode{Name: "minimal"}, false},
+		{"missing name", Mode{MinFocusMinutes: 30}, true},
+		{"min greater than max", Mode{Name: "bad", MinFocusMinutes: 60, MaxFocusMinutes: 30}, true},
+		{"negative break", Mode{Name: "bad", BreakMinutes: -5}, true},
+		{"long break without cadence", Mode{Name: "bad", LongBreakMinutes: 20}, true},
+		{"enforced cadence", Mode{Name: "pomo", MaxFocusMinutes: 25, BreakMinutes: 5, EnforceCadence: true}, false},
+		{"enforced cadence without break", Mode{Name: "bad", MaxFocusMinutes: 25, EnforceCadence: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mode.Validate()
+			if tt.expectErr && err == nil {
+				t.Errorf("Validate() expected error but got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Validate() expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_EnergyProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		energy    EnergyProfile
+		expectErr bool
+	}{
+		{"empty profile", EnergyProfile{}, false},
+		{"valid profile", EnergyProfile{
+			Peak:     []TimeRange{{Start: "09:00", End: "11:30"}},
+			Slump:    []TimeRange{{Start: "13:00", End: "14:00"}},
+			WindDown: []TimeRange{{Start: "16:00", End: "17:00"}},
+		}, false},
+		{"invalid time", EnergyProfile{Peak: []TimeRange{{Start: "9am", End: "11:00"}}}, true},
+		{"end before start", EnergyProfile{Slump: []TimeRange{{Start: "14:00", End: "13:00"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				DefaultMode: "normal",
+				Energy:      tt.energy,
+			}
+			err := cfg.Validate()
+			if tt.expectErr && err == nil {
+				t.Errorf("Validate() expected error but got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Validate() expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_EventTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		focusTime   FocusTime
+		outOfOffice OutOfOffice
+		expectErr   bool
+	}{
+		{"disabled", FocusTime{}, OutOfOffice{}, false},
+		{"valid focus time", FocusTime{Enabled: true, AutoDeclineMode: "declineOnlyNewConflictingInvitations", ChatStatus: "doNotDisturb"}, OutOfOffice{}, false},
+		{"invalid decline mode", FocusTime{Enabled: true, AutoDeclineMode: "declineEverything"}, OutOfOffice{}, true},
+		{"invalid chat status", FocusTime{Enabled: true, ChatStatus: "busy"}, OutOfOffice{}, true},
+		{"invalid out of office decline mode", FocusTime{}, OutOfOffice{Enabled: true, AutoDeclineMode: "nope"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				DefaultMode: "normal",
+				FocusTime:   tt.focusTime,
+				OutOfOffice: tt.outOfOffice,
+			}
+			err := cfg.Validate()
+			if tt.expectErr && err == nil {
+				t.Errorf("Validate() expected error but got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Validate() expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_Privacy(t *testing.T) {
+	tests := []struct {
+		name      string
+		privacy   Privacy
+		expectErr bool
+	}{
+		{"default", Privacy{}, false},
+		{"valid", Privacy{MeetingTitles: "anonymize", TaskTitles: "hash", DropDescriptions: true, Denylist: []string{`\bHR\b`, "customer"}}, false},
+		{"invalid meeting titles", Privacy{MeetingTitles: "hash"}, true},
+		{"invalid task titles", Privacy{TaskTitles: "anonymize"}, true},
+		{"invalid pattern", Privacy{Denylist: []string{"("}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{DefaultMode: "normal", Privacy: tt.privacy}
+			err := cfg.Validate()
+			if tt.expectErr && err == nil {
+				t.Errorf("Validate() expected error but got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Validate() expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_Team(t *testing.T) {
+	tests := []struct {
+		name      string
+		team      Team
+		expectErr bool
+	}{
+		{"default", Team{}, false},
+		{"valid", Team{FocusMinutes: 90, Members: []TeamMember{
+			{Name: "Ana", Calendar: "ana@example.com"},
+			{Calendar: "bo@example.com", WorkHours: TimeRange{Start: "08:00", End: "16:00"}, TimeZone: "Europe/Berlin"},
+		}}, false},
+		{"missing calendar", Team{Members: []TeamMember{{Name: "Ana"}}}, true},
+		{"invalid work hours", Team{Members: []TeamMember{{Calendar: "ana@example.com", WorkHours: TimeRange{Start: "17:00", End: "09:00"}}}}, true},
+		{"invalid time zone", Team{Members: []TeamMember{{Calendar: "ana@example.com", TimeZone: "Mars/Olympus"}}}, true},
+		{"negative focus minutes", Team{FocusMinutes: -30}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{DefaultMode: "normal", Team: tt.team}
+			err := cfg.Validate()
+			if tt.expectErr && err == nil {
+				t.Errorf("Validate() expected error but got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Validate() expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_Routines(t *testing.T) {
+	tests := []struct {
+		name      string
+		routine   Routine
+		expectErr bool
+	}{
+		{"valid", Routine{Title: "Inbox zero", Minutes: 20, Time: "09:00", Weekdays: []string{"Mon", "friday"}}, false},
+		{"missing title", Routine{Minutes: 20, Time: "09:00"}, true},
+		{"no minutes", Routine{Title: "Inbox zero", Time: "09:00"}, true},
+		{"invalid time", Routine{Title: "Inbox zero", Minutes: 20, Time: "9am"}, true},
+		{"invalid weekday", Routine{Title: "Inbox zero", Minutes: 20, Time: "09:00", Weekdays: []string{"funday"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{DefaultMode: "normal", Routines: []Routine{tt.routine}}
+			err := cfg.Validate()
+			if tt.expectErr && err == nil {
+				t.Errorf("Validate() expected error but got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Validate() expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRoutineAppliesOn(t *testing.T) {
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	routine := Routine{Weekdays: []string{"Mon", "wednesday"}}
+	if !routine.AppliesOn(monday) || routine.AppliesOn(monday.AddDate(0, 0, 1)) || !routine.AppliesOn(monday.AddDate(0, 0, 2)) {
+		t.Errorf("expected the routine on Monday and Wednesday only")
+	}
+	if !(Routine{}).AppliesOn(monday.AddDate(0, 0, 5)) {
+		t.Errorf("expected a routine without weekdays to apply every day")
+	}
+}
+
+func TestHTTPDefaults(t *testing.T) {
+	tests := []struct {
+		name          string
+		http          HTTP
+		wantTimeout   time.Duration
+		wantAITimeout time.Duration
+		wantRetries   int
+	}{
+		{"defaults", HTTP{}, HTTPTimeout, AITimeout, httpx.DefaultMaxRetries},
+		{"custom", HTTP{TimeoutSeconds: 10, AITimeoutSeconds: 120, MaxRetries: 5}, 10 * time.Second, 2 * time.Minute, 5},
+		{"retries disabled", HTTP{MaxRetries: -1}, HTTPTimeout, AITimeout, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.http.Timeout(); got != tt.wantTimeout {
+				t.Errorf("Timeout() = %v, want %v", got, tt.wantTimeout)
+			}
+			if got := tt.http.AITimeout(); got != tt.wantAITimeout {
+				t.Errorf("AITimeout() = %v, want %v", got, tt.wantAITimeout)
+			}
+			if got := tt.http.Retries(); got != tt.wantRetries {
+				t.Errorf("Retries() = %d, want %d", got, tt.wantRetries)
+			}
+		})
+	}
+
+	cfg := Config{DefaultMode: "normal", HTTP: HTTP{TimeoutSeconds: -1}}
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Validate() expected error for negative timeout but got nil")
+	}
+}
+
+func TestGetDataDir(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+
+	tests := []struct {
+		dataDir string
+		want    string
+	}{
+		{"", "/home/test/.barely-incharge"},
+		{"~/plans", "/home/test/plans"},
+		{"/var/lib/plans", "/var/lib/plans"},
+	}
+
+	for _, tt := range tests {
+		cfg := Config{DataDir: tt.dataDir}
+		got, err := cfg.GetDataDir()
+		if err != nil {
+			t.Fatalf("GetDataDir() error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("GetDataDir() with data_dir %q = %s, want %s", tt.dataDir, got, tt.want)
+		}
+	}
+}
+
+func TestGetPromptsDir(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+
+	configPath, err := GetConfigPath()
+	if err != nil {
+		t.Fatalf("GetConfigPath() error: %v", err)
+	}
+
+	tests := []struct {
+		promptsDir string
+		want       string
+	}{
+		{"", filepath.Join(filepath.Dir(configPath), "prompts")},
+		{"~/prompts", "/home/test/prompts"},
+		{"/etc/prompts", "/etc/prompts"},
+	}
+
+	for _, tt := range tests {
+		cfg := Config{PromptsDir: tt.promptsDir}
+		got, err := cfg.GetPromptsDir()
+		if err != nil {
+			t.Fatalf("GetPromptsDir() error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("GetPromptsDir() with prompts_dir %q = %s, want %s", tt.promptsDir, got, tt.want)
+		}
+	}
+}
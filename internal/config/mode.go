@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Mode describes how a day should be planned: how long focus blocks last,
+// how long breaks are, and any extra instructions passed to the planner.
+// Durations are expressed in minutes in the config file.
+type Mode struct {
+	Name             string `json:"name"`
+	MinFocusMinutes  int    `json:"min_focus_minutes,omitempty"`
+	MaxFocusMinutes  int    `json:"max_focus_minutes,omitempty"`
+	BreakMinutes     int    `json:"break_minutes,omitempty"`
+	LongBreakMinutes int    `json:"long_break_minutes,omitempty"`
+	LongBreakEvery   int    `json:"long_break_every,omitempty"`
+	EndEarly         bool   `json:"end_early,omitempty"`
+	EnforceCadence   bool   `json:"enforce_cadence,omitempty"`
+	Prompt           string `json:"prompt,omitempty"`
+}
+
+// BuiltinModes returns the modes that are available without any configuration.
+// A mode in the config file with the same name replaces the built-in one as a
+// whole, so an override must set every field it needs.
+func BuiltinModes() []Mode {
+	return []Mode{
+		{
+			Name:            ModeCrunch,
+			MinFocusMinutes: 45,
+			MaxFocusMinutes: 120,
+			BreakMinutes:    5,
+			Prompt:          "Pack as many tasks as possible with minimal breaks. Maximize productivity.",
+		},
+		{
+			Name:            ModeNormal,
+			MinFocusMinutes: 25,
+			MaxFocusMinutes: 90,
+			BreakMinutes:    10,
+			Prompt:          "Balanced approach with regular breaks following standard productivity practices.",
+		},
+		{
+			Name:             ModeSaver,
+			MinFocusMinutes:  25,
+			MaxFocusMinutes:  60,
+			BreakMinutes:     15,
+			LongBreakMinutes: 20,
+			LongBreakEvery:   2,
+			EndEarly:         true,
+			Prompt:           "User is tired. Add longer breaks and extra padding between tasks.",
+		},
+		{
+			Name:             ModePomodoro,
+			MinFocusMinutes:  25,
+			MaxFocusMinutes:  25,
+			BreakMinutes:     5,
+			LongBreakMinutes: 20,
+			LongBreakEvery:   4,
+			EnforceCadence:   true,
+			Prompt:           "Strict Pomodoro technique. Split tasks into whole pomodoros.",
+		},
+	}
+}
+
+func (m Mode) MinFocus() time.Duration  { return minutes(m.MinFocusMinutes) }
+func (m Mode) MaxFocus() time.Duration  { return minutes(m.MaxFocusMinutes) }
+func (m Mode) Break() time.Duration     { return minutes(m.BreakMinutes) }
+func (m Mode) LongBreak() time.Duration { return minutes(m.LongBreakMinutes) }
+
+func (m Mode) Validate() error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("mode name is required")
+	}
+	if m.MinFocusMinutes < 0 || m.MaxFocusMinutes < 0 || m.BreakMinutes < 0 ||
+		m.LongBreakMinutes < 0 || m.LongBreakEvery < 0 {
+		return fmt.Errorf("mode %s: durations must not be negative", m.Name)
+	}
+	if m.MaxFocusMinutes > 0 && m.MinFocusMinutes > m.MaxFocusMinutes {
+		return fmt.Errorf("mode %s: min_focus_minutes (%d) is greater than max_focus_minutes (%d)",
+			m.Name, m.MinFocusMinutes, m.MaxFocusMinutes)
+	}
+	if (m.LongBreakMinutes > 0) != (m.LongBreakEvery > 0) {
+		return fmt.Errorf("mode %s: long_break_minutes and long_break_every must be set together", m.Name)
+	}
+	if m.EnforceCadence && (m.MaxFocusMinutes == 0 || m.BreakMinutes == 0) {
+		return fmt.Errorf("mode %s: enforce_cadence requires max_focus_minutes and break_minutes", m.Name)
+	}
+	return nil
+}
+
+// AvailableModes returns the built-in modes merged with the modes defined in
+// the config. Config modes replace built-ins of the same name entirely, fields
+// left out are not taken from the built-in, and are otherwise appended in the
+// order they are defined.
+func (c *Config) AvailableModes() []Mode {
+	modes := BuiltinModes()
+	for _, custom := range c.Modes {
+		idx := slices.IndexFunc(modes, func(m Mode) bool { return m.Name == custom.Name })
+		if idx >= 0 {
+			modes[idx] = custom
+		} else {
+			modes = append(modes, custom)
+		}
+	}
+	return modes
+}
+
+// ModeNames returns the names of all available modes.
+func (c *Config) ModeNames() []string {
+	modes := c.AvailableModes()
+	names := make([]string, len(modes))
+	for i, m := range modes {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// GetMode looks up a mode by name among the built-in and configured modes.
+func (c *Config) GetMode(name string) (Mode, error) {
+	for _, m := range c.AvailableModes() {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	return Mode{}, fmt.Errorf("invalid mode: %s (valid modes: %s)",
+		name, strings.Join(c.ModeNames(), ", "))
+}
@@ -0,0 +1,142 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+func block(typ, title, start, end string) planner.TimeBlock {
+	return planner.TimeBlock{Type: typ, Title: title, Start: calendartest.At(start), End: calendartest.At(end)}
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		WorkHours:   config.TimeRange{Start: "09:00", End: "17:00"},
+		LunchTime:   config.TimeRange{Start: "12:00", End: "13:00"},
+		Calendar:    "primary",
+		DefaultMode: config.ModeNormal,
+	}
+}
+
+func TestScore(t *testing.T) {
+	plan := &planning.Plan{
+		Day: &planning.Day{
+			WorkStart: calendartest.At("09:00"),
+			WorkEnd:   calendartest.At("17:00"),
+			Busy: []planner.TimeBlock{
+				block(planner.BlockTypeLunch, "Lunch", "12:00", "13:00"),
+				block(planner.BlockTypeMeeting, "Standup", "10:00", "10:15"),
+			},
+		},
+		Mode: config.Mode{Name: "normal", BreakMinutes: 10},
+		Tasks: []planner.Task{
+			{Title: "Write docs", Duration: planner.SizeL},
+			{Title: "Review PRs", Duration: planner.SizeM},
+		},
+		Blocks: []planner.TimeBlock{
+			block(planner.BlockTypeFocus, "Write docs", "09:00", "10:05"),  // overlaps the standup
+			block(planner.BlockTypeBreak, "Short break", "10:15", "10:25"), // adherent
+			block(planner.BlockTypeFocus, "Review PRs", "10:30", "10:45"),  // half the task, 5 min sliver before
+			block(planner.BlockTypeFocus, "Extra", "10:45", "11:00"),       // back-to-back: missed break
+			block(planner.BlockTypeBreak, "Long break", "11:00", "11:50"),  // not adherent
+			block(planner.BlockTypeFocus, "Late work", "16:30", "17:30"),   // out of hours
+			block(planner.BlockTypeLunch, "Lunch", "12:00", "13:00"),       // ignored
+		},
+	}
+
+	m := Score(plan)
+
+	if m.OverlapViolations != 1 {
+		t.Errorf("OverlapViolations = %v, want 1", m.OverlapViolations)
+	}
+	if m.TaskCoverage != 0.75 {
+		t.Errorf("TaskCoverage = %v, want 0.75", m.TaskCoverage)
+	}
+	if math.Abs(m.BreakAdherence-1.0/3) > 1e-9 {
+		t.Errorf("BreakAdherence = %v, want 1/3", m.BreakAdherence)
+	}
+	if m.Fragmentation != 2 {
+		t.Errorf("Fragmentation = %v, want 2 (10:25-10:30 and 11:50-12:00)", m.Fragmentation)
+	}
+	if m.OutOfHours != 1 {
+		t.Errorf("OutOfHours = %v, want 1", m.OutOfHours)
+	}
+}
+
+func TestRunnerReport(t *testing.T) {
+	fake := &aitest.StaticPlanner{Response: &ai.PlanResponse{Blocks: []ai.Block{
+		{Type: "focus", Title: "Write docs", Start: "09:00", End: "10:00"},
+	}}}
+	scenarios, err := LoadScenarios("testdata")
+	if err != nil {
+		t.Fatalf("LoadScenarios() error: %v", err)
+	}
+	if len(scenarios) != 2 {
+		t.Fatalf("expected 2 scenarios, got %d", len(scenarios))
+	}
+
+	runner := &Runner{Config: testConfig(), Planner: fake, Model: "fake", Runs: 2}
+	report, err := runner.Run(context.Background(), scenarios)
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(fake.Requests()) != 4 {
+		t.Errorf("expected 4 planner calls, got %d", len(fake.Requests()))
+	}
+	if report.Failures != 0 {
+		t.Errorf("expected no failures, got %d: %+v", report.Failures, report.Scenarios)
+	}
+	if report.Scenarios[0].Name != "morning standup" || report.Scenarios[1].Name != "no_meetings" {
+		t.Errorf("unexpected scenario names: %q, %q", report.Scenarios[0].Name, report.Scenarios[1].Name)
+	}
+	// The focus block overlaps the standup in the first scenario only
+	if report.Scenarios[0].Metrics.OverlapViolations != 1 || report.Scenarios[1].Metrics.OverlapViolations != 0 {
+		t.Errorf("unexpected overlaps: %+v", report.Scenarios)
+	}
+
+	var table bytes.Buffer
+	if err := report.WriteTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table.String(), "morning standup") || !strings.Contains(table.String(), "TOTAL") {
+		t.Errorf("table output missing rows:\n%s", table.String())
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON report: %v", err)
+	}
+	if decoded.Model != "fake" || decoded.Runs != 2 {
+		t.Errorf("unexpected decoded report: %+v", decoded)
+	}
+}
+
+func TestRunnerRecordsFailures(t *testing.T) {
+	fake := &aitest.StaticPlanner{Err: errors.New("rate limited")}
+	runner := &Runner{Config: testConfig(), Planner: fake, Runs: 3}
+
+	report, err := runner.Run(context.Background(), []Scenario{{Name: "any", Tasks: "Write docs"}})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if report.Failures != 3 || len(report.Scenarios[0].Errors) != 3 {
+		t.Errorf("expected 3 recorded failures, got %+v", report.Scenarios[0])
+	}
+}
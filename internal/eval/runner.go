@@ -0,0 +1,167 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+// Runner runs scenarios through a planner several times and scores the results.
+type Runner struct {
+	Config  *config.Config
+	Planner planning.Planner
+	// Model is only used to label the report.
+	Model string
+	Runs  int
+}
+
+// ScenarioResult holds the averaged metrics of all successful runs of a scenario.
+type ScenarioResult struct {
+	Name     string   `json:"name"`
+	Runs     int      `json:"runs"`
+	Failures int      `json:"failures"`
+	Metrics  Metrics  `json:"metrics"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+type Report struct {
+	Model     string           `json:"model"`
+	Runs      int              `json:"runs_per_scenario"`
+	Scenarios []ScenarioResult `json:"scenarios"`
+	// Aggregate averages the metrics over all successful runs of all scenarios.
+	Aggregate Metrics `json:"aggregate"`
+	Failures  int     `json:"failures"`
+}
+
+// Run evaluates every scenario Runs times. Planner errors are recorded as
+// failures instead of stopping the evaluation; ctx cancellation stops it.
+func (r *Runner) Run(ctx context.Context, scenarios []Scenario) (*Report, error) {
+	runs := max(r.Runs, 1)
+	report := &Report{Model: r.Model, Runs: runs}
+
+	var all []Metrics
+	for _, scenario := range scenarios {
+		result := ScenarioResult{Name: scenario.Name, Runs: runs}
+
+		var scores []Metrics
+		for range runs {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			plan, err := r.runOnce(ctx, scenario)
+			if err != nil {
+				result.Failures++
+				result.Errors = append(result.Errors, err.Error())
+				continue
+			}
+			scores = append(scores, Score(plan))
+		}
+
+		result.Metrics = average(scores)
+		report.Scenarios = append(report.Scenarios, result)
+		report.Failures += result.Failures
+		all = append(all, scores...)
+	}
+
+	report.Aggregate = average(all)
+	return report, nil
+}
+
+func (r *Runner) runOnce(ctx context.Context, scenario Scenario) (*planning.Plan, error) {
+	cfg := scenario.Config(r.Config)
+
+	modeName := scenario.Mode
+	if modeName == "" {
+		modeName = cfg.DefaultMode
+	}
+	mode, err := cfg.GetMode(modeName)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := scenario.PlanningDate()
+	if err != nil {
+		return nil, err
+	}
+	events, err := scenario.Events(date)
+	if err != nil {
+		return nil, err
+	}
+
+	service := planning.NewService(cfg, scenarioCalendar{events: events}, r.Planner)
+	day, err := service.PrepareDay(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.Generate(ctx, day, mode, planner.ParseTaskList(scenario.Tasks))
+}
+
+func average(scores []Metrics) Metrics {
+	if len(scores) == 0 {
+		return Metrics{}
+	}
+
+	var sum Metrics
+	for _, m := range scores {
+		sum.OverlapViolations += m.OverlapViolations
+		sum.TaskCoverage += m.TaskCoverage
+		sum.BreakAdherence += m.BreakAdherence
+		sum.Fragmentation += m.Fragmentation
+		sum.OutOfHours += m.OutOfHours
+	}
+
+	n := float64(len(scores))
+	return Metrics{
+		OverlapViolations: sum.OverlapViolations / n,
+		TaskCoverage:      sum.TaskCoverage / n,
+		BreakAdherence:    sum.BreakAdherence / n,
+		Fragmentation:     sum.Fragmentation / n,
+		OutOfHours:        sum.OutOfHours / n,
+	}
+}
+
+// WriteTable prints the report as an aligned text table.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Model: %s, %d run(s) per scenario\n\n", r.Model, r.Runs)
+	fmt.Fprintln(tw, "SCENARIO\tOK\tOVERLAPS\tCOVERAGE\tBREAKS\tFRAGMENTS\tOUT OF HOURS")
+	for _, s := range r.Scenarios {
+		writeRow(tw, s.Name, s.Runs-s.Failures, s.Runs, s.Metrics)
+	}
+	total := len(r.Scenarios) * r.Runs
+	writeRow(tw, "TOTAL", total-r.Failures, total, r.Aggregate)
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, s := range r.Scenarios {
+		for _, e := range s.Errors {
+			fmt.Fprintf(w, "  %s: %s\n", s.Name, e)
+		}
+	}
+	return nil
+}
+
+func writeRow(w io.Writer, name string, ok, runs int, m Metrics) {
+	fmt.Fprintf(w, "%s\t%d/%d\t%.2f\t%.0f%%\t%.0f%%\t%.2f\t%.2f\n",
+		name, ok, runs,
+		m.OverlapViolations, m.TaskCoverage*100, m.BreakAdherence*100,
+		m.Fragmentation, m.OutOfHours)
+}
+
+// WriteJSON prints the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
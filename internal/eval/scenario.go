@@ -0,0 +1,135 @@
+// Package eval runs recorded planning scenarios through a planner and scores
+// the resulting plans, so prompt and model changes can be compared objectively.
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// defaultDate is used for scenarios without a date. It only needs to be a
+// weekday that is never "today".
+const defaultDate = "2030-01-07"
+
+// Scenario is a recorded planning input: the meetings of a day, the tasks and the mode.
+type Scenario struct {
+	Name      string            `json:"name"`
+	Date      string            `json:"date,omitempty"`
+	WorkHours *config.TimeRange `json:"work_hours,omitempty"`
+	LunchTime *config.TimeRange `json:"lunch_time,omitempty"`
+	Meetings  []Meeting         `json:"meetings"`
+	Tasks     string            `json:"tasks"`
+	Mode      string            `json:"mode"`
+}
+
+type Meeting struct {
+	Title    string `json:"title"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Location string `json:"location,omitempty"`
+}
+
+// LoadScenarios reads all *.json scenario files in dir, sorted by file name.
+// Scenarios without a name are named after their file.
+func LoadScenarios(dir string) ([]Scenario, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no scenario files (*.json) found in %s", dir)
+	}
+
+	scenarios := make([]Scenario, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read scenario: %w", err)
+		}
+
+		var s Scenario
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("failed to parse scenario %s: %w", file, err)
+		}
+		if s.Name == "" {
+			s.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+		}
+		scenarios = append(scenarios, s)
+	}
+
+	return scenarios, nil
+}
+
+// PlanningDate returns the scenario date at midnight in the local timezone.
+func (s Scenario) PlanningDate() (time.Time, error) {
+	cfg := config.Config{Date: s.Date}
+	if cfg.Date == "" {
+		cfg.Date = defaultDate
+	}
+	return cfg.GetPlanningDate()
+}
+
+// Config returns a copy of base with the scenario's work hours, lunch time and date applied.
+func (s Scenario) Config(base *config.Config) *config.Config {
+	cfg := *base
+	if s.WorkHours != nil {
+		cfg.WorkHours = *s.WorkHours
+	}
+	if s.LunchTime != nil {
+		cfg.LunchTime = *s.LunchTime
+	}
+	return &cfg
+}
+
+// Events returns the scenario meetings as calendar events on the given date.
+func (s Scenario) Events(date time.Time) ([]calendar.Event, error) {
+	events := make([]calendar.Event, 0, len(s.Meetings))
+	for _, m := range s.Meetings {
+		start, err := planner.ParseTimeOnDate(m.Start, date)
+		if err != nil {
+			return nil, fmt.Errorf("meeting %q: invalid start time: %w", m.Title, err)
+		}
+		end, err := planner.ParseTimeOnDate(m.End, date)
+		if err != nil {
+			return nil, fmt.Errorf("meeting %q: invalid end time: %w", m.Title, err)
+		}
+		events = append(events, calendar.Event{
+			Type:     planner.BlockTypeMeeting,
+			Title:    m.Title,
+			Location: m.Location,
+			Start:    start,
+			End:      end,
+		})
+	}
+	return events, nil
+}
+
+// scenarioCalendar serves the scenario meetings and discards writes.
+type scenarioCalendar struct {
+	events []calendar.Event
+}
+
+func (c scenarioCalendar) FetchMeetings(ctx context.Context, calendarID string, start, end time.Time) ([]calendar.Event, error) {
+	return c.events, nil
+}
+
+func (c scenarioCalendar) CreateEvent(ctx context.Context, calendarID string, event calendar.Event) (string, error) {
+	return "", nil
+}
+
+func (c scenarioCalendar) MoveEvent(ctx context.Context, calendarID, eventID string, start, end time.Time) error {
+	return nil
+}
+
+func (c scenarioCalendar) DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	return nil
+}
@@ -0,0 +1,154 @@
+package eval
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+const (
+	// breakTolerance is how far a break may be from the mode's break length.
+	breakTolerance = 5 * time.Minute
+	// sliverLength is the length below which a free gap counts as a fragment.
+	sliverLength = 15 * time.Minute
+)
+
+// Metrics scores a plan. Lower is better for counts, higher is better for ratios.
+type Metrics struct {
+	// OverlapViolations counts planned blocks overlapping busy time or each other.
+	OverlapViolations float64 `json:"overlap_violations"`
+	// TaskCoverage is the average share of each task's duration that was scheduled (0-1).
+	TaskCoverage float64 `json:"task_coverage"`
+	// BreakAdherence is the share of breaks matching the mode's break lengths (0-1).
+	// Back-to-back focus blocks count as a missed break when the mode has breaks.
+	BreakAdherence float64 `json:"break_adherence"`
+	// Fragmentation counts free gaps inside work hours too short to use.
+	Fragmentation float64 `json:"fragmentation"`
+	// OutOfHours counts planned blocks outside work hours.
+	OutOfHours float64 `json:"out_of_hours"`
+}
+
+// Score computes the metrics of a generated plan.
+func Score(plan *planning.Plan) Metrics {
+	var planned []planner.TimeBlock
+	for _, b := range plan.Blocks {
+		if b.Type == planner.BlockTypeFocus || b.Type == planner.BlockTypeBreak {
+			planned = append(planned, b)
+		}
+	}
+
+	return Metrics{
+		OverlapViolations: float64(countOverlaps(planned, plan.Day.Busy)),
+		TaskCoverage:      taskCoverage(planned, plan.Tasks),
+		BreakAdherence:    breakAdherence(planned, plan.Mode.Break(), plan.Mode.LongBreak()),
+		Fragmentation:     float64(countSlivers(slices.Concat(planned, plan.Day.Busy), plan.Day.WorkStart, plan.Day.WorkEnd)),
+		OutOfHours:        float64(countOutOfHours(planned, plan.Day.WorkStart, plan.Day.WorkEnd)),
+	}
+}
+
+func overlaps(a, b planner.TimeBlock) bool {
+	return a.Start.Before(b.End) && a.End.After(b.Start)
+}
+
+func countOverlaps(planned, busy []planner.TimeBlock) int {
+	count := 0
+	for i, p := range planned {
+		for _, b := range busy {
+			if overlaps(p, b) {
+				count++
+			}
+		}
+		for _, other := range planned[i+1:] {
+			if overlaps(p, other) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func taskCoverage(planned []planner.TimeBlock, tasks []planner.Task) float64 {
+	if len(tasks) == 0 {
+		return 1
+	}
+
+	total := 0.0
+	for _, task := range tasks {
+		var scheduled time.Duration
+		for _, b := range planned {
+			// Enforced cadences split tasks into "Title (1/3)" blocks
+			if b.Type == planner.BlockTypeFocus &&
+				(b.Title == task.Title || strings.HasPrefix(b.Title, task.Title+" (")) {
+				scheduled += b.End.Sub(b.Start)
+			}
+		}
+		if task.Duration > 0 {
+			total += min(float64(scheduled)/float64(task.Duration), 1)
+		} else {
+			total++
+		}
+	}
+	return total / float64(len(tasks))
+}
+
+func breakAdherence(planned []planner.TimeBlock, breakLen, longBreakLen time.Duration) float64 {
+	if breakLen == 0 {
+		return 1
+	}
+
+	sorted := sortedByStart(planned)
+	adherent, total := 0, 0
+	for i, b := range sorted {
+		switch b.Type {
+		case planner.BlockTypeBreak:
+			total++
+			length := b.End.Sub(b.Start)
+			if within(length, breakLen) || (longBreakLen > 0 && within(length, longBreakLen)) {
+				adherent++
+			}
+		case planner.BlockTypeFocus:
+			if i+1 < len(sorted) && sorted[i+1].Type == planner.BlockTypeFocus && !sorted[i+1].Start.After(b.End) {
+				total++
+			}
+		}
+	}
+
+	if total == 0 {
+		return 1
+	}
+	return float64(adherent) / float64(total)
+}
+
+func within(d, target time.Duration) bool {
+	diff := d - target
+	return diff <= breakTolerance && diff >= -breakTolerance
+}
+
+func countSlivers(blocks []planner.TimeBlock, workStart, workEnd time.Time) int {
+	count := 0
+	for _, gap := range planner.FreeWindows(blocks, workStart, workEnd) {
+		if gap.End.Sub(gap.Start) < sliverLength {
+			count++
+		}
+	}
+	return count
+}
+
+func countOutOfHours(planned []planner.TimeBlock, workStart, workEnd time.Time) int {
+	count := 0
+	for _, b := range planned {
+		if b.Start.Before(workStart) || b.End.After(workEnd) {
+			count++
+		}
+	}
+	return count
+}
+
+func sortedByStart(blocks []planner.TimeBlock) []planner.TimeBlock {
+	sorted := slices.Clone(blocks)
+	slices.SortStableFunc(sorted, func(a, b planner.TimeBlock) int { return a.Start.Compare(b.Start) })
+	return sorted
+}
@@ -0,0 +1,180 @@
+package history
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+func testRecord() *Record {
+	r := NewRecord(calendartest.Date, "primary", config.Mode{Name: "normal"}, []planner.Task{
+		{Title: "Write docs", Duration: planner.SizeL, Kind: planner.TaskKindDeep},
+	})
+
+	plan := &planning.Plan{
+		Response: &ai.PlanResponse{Model: "gpt-test", Prompt: "the prompt", Raw: `{"blocks": []}`},
+		Blocks: []planner.TimeBlock{
+			{Type: planner.BlockTypeFocus, Title: "Write docs", Start: calendartest.Date.Add(9 * time.Hour), End: calendartest.Date.Add(10 * time.Hour)},
+			{Type: planner.BlockTypeBreak, Title: "Break", Start: calendartest.Date.Add(10 * time.Hour), End: calendartest.Date.Add(10*time.Hour + 15*time.Minute)},
+		},
+	}
+	r.SetPlan(plan)
+	r.SetTransaction(&planning.Transaction{Entries: []planning.TransactionEntry{
+		{Event: calendar.Event{ID: "evt1"}},
+		{Err: errors.New("boom")},
+	}})
+	return r
+}
+
+func TestRecordFromPlan(t *testing.T) {
+	r := testRecord()
+
+	if r.Date != "2030-01-07" || r.Mode != "normal" || r.Model != "gpt-test" || r.Prompt != "the prompt" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if len(r.Tasks) != 1 || r.Tasks[0].Minutes != 60 || r.Tasks[0].Kind != planner.TaskKindDeep {
+		t.Errorf("unexpected tasks: %+v", r.Tasks)
+	}
+	if r.Blocks[0].EventID != "evt1" || r.Blocks[0].Status != StatusCreated || r.Blocks[1].Status != StatusFailed {
+		t.Errorf("unexpected blocks: %+v", r.Blocks)
+	}
+	if r.Status() != "applied" {
+		t.Errorf("Status() = %s, want applied", r.Status())
+	}
+
+	r.SetError(errors.New("failed to create 1 of 2 block(s)"))
+	if r.Status() != "failed" {
+		t.Errorf("Status() = %s, want failed", r.Status())
+	}
+}
+
+func TestStoreAppendAndGet(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	store.now = func() time.Time { return calendartest.Date.Add(8 * time.Hour) }
+
+	records, err := store.List()
+	if err != nil || len(records) != 0 {
+		t.Fatalf("List() on an empty store = %v, %v", records, err)
+	}
+
+	for range 2 {
+		if err := store.Append(testRecord()); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	records, err = store.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(records) != 2 || records[0].ID != "1" || records[1].ID != "2" {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+
+	got, err := store.Get("2")
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if !got.CreatedAt.Equal(calendartest.Date.Add(8*time.Hour)) || got.RawResponse != `{"blocks": []}` || got.Blocks[0].EventID != "evt1" {
+		t.Errorf("unexpected record: %+v", got)
+	}
+
+	if _, err := store.Get("3"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a missing record error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreUpdate(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	for range 2 {
+		if err := store.Append(testRecord()); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	record, _ := store.Get("1")
+	record.DeletedAt = calendartest.Date
+	if err := store.Update(record); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+
+	records, err := store.List()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("List() = %v, %v", records, err)
+	}
+	if records[0].Status() != "deleted" || records[1].Status() != "applied" {
+		t.Errorf("unexpected statuses after update: %s, %s", records[0].Status(), records[1].Status())
+	}
+
+	if err := store.Update(Record{ID: "9"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() of a missing record error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreWritesFromSeveralStores(t *testing.T) {
+	// Two stores on one directory stand in for two commands running at once
+	dir := t.TempDir()
+	planStore, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	serveStore, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if err := planStore.Append(testRecord()); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+
+	const appends = 20
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for range appends {
+			if err := planStore.Append(testRecord()); err != nil {
+				t.Errorf("Append() error: %v", err)
+			}
+		}
+	})
+	wg.Go(func() {
+		for range appends {
+			record, err := serveStore.Get("1")
+			if err != nil {
+				t.Errorf("Get() error: %v", err)
+				return
+			}
+			record.DeletedAt = calendartest.Date
+			if err := serveStore.Update(record); err != nil {
+				t.Errorf("Update() error: %v", err)
+			}
+		}
+	})
+	wg.Wait()
+
+	records, err := planStore.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(records) != appends+1 {
+		t.Fatalf("got %d records, want %d", len(records), appends+1)
+	}
+	for i, r := range records {
+		if r.ID != strconv.Itoa(i+1) {
+			t.Errorf("record %d has ID %s, want %d", i, r.ID, i+1)
+		}
+	}
+}
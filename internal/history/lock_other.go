@@ -0,0 +1,9 @@
+//go:build !unix
+
+package history
+
+// lockFile is a no-op where advisory file locks are not available; writes are
+// then only serialized within one process.
+func lockFile(string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,212 @@
+// Package history keeps a local journal of plan runs: what was asked, what the
+// model answered and which calendar events were created.
+package history
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+// Block statuses, from the calendar transaction of a run.
+const (
+	StatusCreated    = "created"
+	StatusFailed     = "failed"
+	StatusSkipped    = "skipped"
+	StatusRolledBack = "rolled_back"
+	StatusDeleted    = "deleted"
+)
+
+// Progress of the focus blocks that were over when the day was planned again.
+const (
+	ProgressDone    = "done"
+	ProgressUnknown = "unknown"
+)
+
+// Record is one plan run.
+type Record struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Date is the planning date (YYYY-MM-DD).
+	Date     string `json:"date"`
+	Calendar string `json:"calendar"`
+	Mode     string `json:"mode"`
+	Model    string `json:"model,omitempty"`
+	Tasks    []Task `json:"tasks"`
+
+	Prompt      string   `json:"prompt,omitempty"`
+	RawResponse string   `json:"raw_response,omitempty"`
+	Blocks      []Block  `json:"blocks,omitempty"`
+	Unscheduled []string `json:"unscheduled,omitempty"`
+	// Chat lists the changes asked for with plan --chat; RawResponse is the
+	// answer to the last one.
+	Chat []ChatTurn `json:"chat,omitempty"`
+
+	// Error is set when the run failed.
+	Error string `json:"error,omitempty"`
+	// DeletedAt is set when the created events were deleted again.
+	DeletedAt time.Time `json:"deleted_at,omitzero"`
+}
+
+type Task struct {
+	Title   string `json:"title"`
+	Minutes int    `json:"minutes"`
+	Kind    string `json:"kind,omitempty"`
+	After   string `json:"after,omitempty"`
+	Before  string `json:"before,omitempty"`
+	// Size is the T-shirt size of a task whose minutes were scaled by learned
+	// size factors. Reviews count the task for this size, not for its minutes.
+	Size string `json:"size,omitempty"`
+}
+
+// ChatTurn is a change asked for after the first plan and the model's answer.
+type ChatTurn struct {
+	Instruction string `json:"instruction"`
+	RawResponse string `json:"raw_response"`
+}
+
+type Block struct {
+	Type    string    `json:"type"`
+	Title   string    `json:"title"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	EventID string    `json:"event_id,omitempty"`
+	Status  string    `json:"status,omitempty"`
+	// Progress is set on elapsed focus blocks kept by a re-plan.
+	Progress string `json:"progress,omitempty"`
+	// Suggested is set on accepted prep and follow-up blocks.
+	Suggested bool `json:"suggested,omitempty"`
+}
+
+// NewRecord starts a record for a plan run with its inputs.
+func NewRecord(date time.Time, calendarID string, mode config.Mode, tasks []planner.Task) *Record {
+	r := &Record{
+		Date:     date.Format(config.DateFormat),
+		Calendar: calendarID,
+		Mode:     mode.Name,
+		Tasks:    make([]Task, len(tasks)),
+	}
+	for i, t := range tasks {
+		r.Tasks[i] = Task{Title: t.Title, Minutes: int(t.Duration.Minutes()), Kind: t.Kind, After: t.After, Before: t.Before, Size: t.Size}
+	}
+	return r
+}
+
+// PlannerTasks returns the tasks of the run as planner tasks.
+func (r *Record) PlannerTasks() []planner.Task {
+	tasks := make([]planner.Task, len(r.Tasks))
+	for i, t := range r.Tasks {
+		tasks[i] = planner.Task{
+			Title:    t.Title,
+			Duration: time.Duration(t.Minutes) * time.Minute,
+			Kind:     t.Kind,
+			After:    t.After,
+			Before:   t.Before,
+			Size:     t.Size,
+		}
+	}
+	return tasks
+}
+
+// SetPlan records the model's answer and the final blocks of a generated plan.
+func (r *Record) SetPlan(plan *planning.Plan) {
+	if plan.Response != nil {
+		r.Model = plan.Response.Model
+		r.Prompt = plan.Response.Prompt
+		r.RawResponse = plan.Response.Raw
+		r.Chat = nil
+		if conv := plan.Response.Conversation; conv != nil {
+			r.Prompt = conv.Prompt()
+			for _, t := range conv.Turns {
+				r.Chat = append(r.Chat, ChatTurn{Instruction: t.Instruction, RawResponse: t.Raw})
+			}
+		}
+	}
+
+	r.Blocks = make([]Block, len(plan.Blocks))
+	for i, b := range plan.Blocks {
+		r.Blocks[i] = Block{Type: b.Type, Title: b.Title, Start: b.Start, End: b.End, Suggested: b.Suggested}
+	}
+
+	r.Unscheduled = nil
+	for _, t := range plan.Unscheduled {
+		r.Unscheduled = append(r.Unscheduled, t.Title)
+	}
+}
+
+// SetTransaction records the calendar event IDs and the outcome of each block.
+// The transaction entries must be in the same order as the plan blocks.
+func (r *Record) SetTransaction(tx *planning.Transaction) {
+	for i, entry := range tx.Entries {
+		if i >= len(r.Blocks) {
+			break
+		}
+		b := &r.Blocks[i]
+		b.EventID = entry.Event.ID
+		switch {
+		case errors.Is(entry.Err, planning.ErrSkipped):
+			b.Status = StatusSkipped
+		case entry.Err != nil:
+			b.Status = StatusFailed
+		case entry.RolledBack:
+			b.Status = StatusRolledBack
+		case entry.Event.ID != "":
+			b.Status = StatusCreated
+		}
+	}
+}
+
+// SetKept puts the blocks kept by a re-plan in front of the planned blocks, so
+// the record covers the whole day. progress maps event IDs to the progress of
+// elapsed focus blocks. It must be called after SetPlan and SetTransaction.
+func (r *Record) SetKept(kept []calendar.Event, progress map[string]string) {
+	blocks := make([]Block, 0, len(kept)+len(r.Blocks))
+	for _, e := range kept {
+		blocks = append(blocks, Block{
+			Type:     e.Type,
+			Title:    planning.BlockTitle(e),
+			Start:    e.Start,
+			End:      e.End,
+			EventID:  e.ID,
+			Status:   StatusCreated,
+			Progress: progress[e.ID],
+		})
+	}
+	r.Blocks = append(blocks, r.Blocks...)
+}
+
+// SetError marks the run as failed.
+func (r *Record) SetError(err error) {
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Status summarizes the outcome of the run.
+func (r *Record) Status() string {
+	switch {
+	case !r.DeletedAt.IsZero():
+		return "deleted"
+	case r.Error != "":
+		return "failed"
+	case len(r.CreatedBlocks()) > 0:
+		return "applied"
+	default:
+		return "planned"
+	}
+}
+
+// CreatedBlocks returns the blocks that were created in the calendar.
+func (r *Record) CreatedBlocks() []Block {
+	var created []Block
+	for _, b := range r.Blocks {
+		if b.Status == StatusCreated {
+			created = append(created, b)
+		}
+	}
+	return created
+}
@@ -0,0 +1,256 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// Review outcomes for a block or a task.
+const (
+	OutcomeDone    = "done"
+	OutcomePartial = "partial"
+	OutcomeSkipped = "skipped"
+)
+
+const (
+	// minSizeSamples is the number of reviewed tasks of a size needed before
+	// its correction factor is used.
+	minSizeSamples = 3
+	// maxSizeSamples limits the correction factor to the most recent tasks of a size.
+	maxSizeSamples = 20
+	minSizeFactor  = 0.5
+	maxSizeFactor  = 2.5
+)
+
+// Review is the end-of-day review of a plan run.
+type Review struct {
+	// RecordID is the plan run that was reviewed.
+	RecordID  string    `json:"record_id"`
+	Date      string    `json:"date"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Blocks []ReviewedBlock `json:"blocks"`
+	Tasks  []TaskOutcome   `json:"tasks"`
+
+	// CarryOver lists the unfinished tasks with their remaining time, to be
+	// added to the plan of CarryOverTo.
+	CarryOver   []Task `json:"carry_over,omitempty"`
+	CarryOverTo string `json:"carry_over_to,omitempty"`
+}
+
+// ReviewedBlock is the answer for one focus block.
+type ReviewedBlock struct {
+	Title          string `json:"title"`
+	Task           string `json:"task,omitempty"`
+	Outcome        string `json:"outcome"`
+	PlannedMinutes int    `json:"planned_minutes"`
+	ActualMinutes  int    `json:"actual_minutes"`
+}
+
+// TaskOutcome aggregates the reviewed blocks of a task.
+type TaskOutcome struct {
+	Task
+	Outcome       string `json:"outcome"`
+	ActualMinutes int    `json:"actual_minutes"`
+}
+
+// FocusBlocks returns the focus blocks of the record that were created in the calendar.
+func (r *Record) FocusBlocks() []Block {
+	var focus []Block
+	for _, b := range r.CreatedBlocks() {
+		if b.Type == planner.BlockTypeFocus {
+			focus = append(focus, b)
+		}
+	}
+	return focus
+}
+
+// TaskFor returns the title of the task a block was planned for, or "" if it
+// matches none. Enforced cadences split tasks into "Title (1/3)" blocks.
+func (r *Record) TaskFor(blockTitle string) string {
+	for _, t := range r.Tasks {
+		if blockTitle == t.Title || strings.HasPrefix(blockTitle, t.Title+" (") {
+			return t.Title
+		}
+	}
+	return ""
+}
+
+// NewReview builds the review of a record from the answers for its focus blocks.
+// Tasks without any done or partial block are carried over in full; partially done
+// tasks are carried over with their remaining time.
+func NewReview(r Record, blocks []ReviewedBlock) (*Review, error) {
+	date, err := time.Parse(config.DateFormat, r.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record date: %w", err)
+	}
+
+	review := &Review{
+		RecordID:    r.ID,
+		Date:        r.Date,
+		Blocks:      blocks,
+		CarryOverTo: nextWorkday(date).Format(config.DateFormat),
+	}
+
+	for _, task := range r.Tasks {
+		outcome := TaskOutcome{Task: task, Outcome: OutcomeSkipped}
+		var outcomes []string
+		for _, b := range blocks {
+			if b.Task != task.Title {
+				continue
+			}
+			outcomes = append(outcomes, b.Outcome)
+			outcome.ActualMinutes += b.ActualMinutes
+		}
+
+		switch {
+		case len(outcomes) > 0 && !slices.ContainsFunc(outcomes, func(o string) bool { return o != OutcomeDone }):
+			outcome.Outcome = OutcomeDone
+		case slices.Contains(outcomes, OutcomeDone) || slices.Contains(outcomes, OutcomePartial):
+			outcome.Outcome = OutcomePartial
+		}
+		review.Tasks = append(review.Tasks, outcome)
+
+		if outcome.Outcome != OutcomeDone {
+			remaining := task
+			remaining.Minutes = max(task.Minutes-outcome.ActualMinutes, int(planner.SizeS.Minutes()))
+			remaining.Size = ""
+			review.CarryOver = append(review.CarryOver, remaining)
+		}
+	}
+
+	if len(review.CarryOver) == 0 {
+		review.CarryOverTo = ""
+	}
+	return review, nil
+}
+
+// AppendReview writes a review to the journal, setting its creation time.
+func (s *Store) AppendReview(review *Review) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.lockPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if review.CreatedAt.IsZero() {
+		review.CreatedAt = s.now()
+	}
+	return appendLine(s.reviewsPath, review)
+}
+
+// Reviews returns the latest review of every date, oldest first.
+func (s *Store) Reviews() ([]Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := readLines[Review](s.reviewsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// A day reviewed twice keeps only its last review
+	latest := make(map[string]int)
+	for i, r := range all {
+		latest[r.Date] = i
+	}
+	reviews := make([]Review, 0, len(latest))
+	for i, r := range all {
+		if latest[r.Date] == i {
+			reviews = append(reviews, r)
+		}
+	}
+	return reviews, nil
+}
+
+// CarryOver returns the tasks carried over to date by the reviews.
+func (s *Store) CarryOver(date time.Time) ([]planner.Task, error) {
+	reviews, err := s.Reviews()
+	if err != nil {
+		return nil, err
+	}
+
+	day := date.Format(config.DateFormat)
+	var tasks []planner.Task
+	for _, r := range reviews {
+		if r.CarryOverTo != day {
+			continue
+		}
+		for _, t := range r.CarryOver {
+			tasks = append(tasks, planner.Task{
+				Title:    t.Title,
+				Duration: time.Duration(t.Minutes) * time.Minute,
+				Kind:     t.Kind,
+			})
+		}
+	}
+	return tasks, nil
+}
+
+// SizeFactor is how much longer than planned the tasks of a T-shirt size take.
+type SizeFactor struct {
+	Factor  float64
+	Samples int
+}
+
+// SizeFactors learns a correction factor per T-shirt size from the done tasks of
+// the reviews. Tasks that were already scaled count for their original size and
+// its standard duration, so that the factors do not drift from one review to the
+// next. Only sizes with enough samples are returned.
+func SizeFactors(reviews []Review) map[string]SizeFactor {
+	type sample struct{ planned, actual int }
+	samples := make(map[string][]sample)
+	for _, r := range reviews {
+		for _, t := range r.Tasks {
+			size, planned := planner.SizeOf(time.Duration(t.Minutes)*time.Minute), t.Minutes
+			if d, ok := planner.SizeDuration(t.Size); ok {
+				size, planned = t.Size, int(d.Minutes())
+			}
+			if t.Outcome != OutcomeDone || size == "" || t.ActualMinutes <= 0 {
+				continue
+			}
+			samples[size] = append(samples[size], sample{planned, t.ActualMinutes})
+		}
+	}
+
+	factors := make(map[string]SizeFactor)
+	for size, s := range samples {
+		if len(s) < minSizeSamples {
+			continue
+		}
+		s = s[max(len(s)-maxSizeSamples, 0):]
+
+		planned, actual := 0, 0
+		for _, x := range s {
+			planned += x.planned
+			actual += x.actual
+		}
+		factor := min(max(float64(actual)/float64(planned), minSizeFactor), maxSizeFactor)
+		factors[size] = SizeFactor{Factor: factor, Samples: len(s)}
+	}
+	return factors
+}
+
+// Factors returns just the factors, as used by planner.ScaleSizes.
+func Factors(factors map[string]SizeFactor) map[string]float64 {
+	plain := make(map[string]float64, len(factors))
+	for size, f := range factors {
+		plain[size] = f.Factor
+	}
+	return plain
+}
+
+func nextWorkday(date time.Time) time.Time {
+	next := date.AddDate(0, 0, 1)
+	for next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,178 @@
+package history
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+func reviewRecord() Record {
+	return Record{
+		ID:   "4",
+		Date: "2030-01-11", // a Friday
+		Tasks: []Task{
+			{Title: "Write docs", Minutes: 60},
+			{Title: "Review PRs", Minutes: 30},
+			{Title: "Inbox", Minutes: 15},
+			{Title: "Unscheduled", Minutes: 30},
+		},
+		Blocks: []Block{
+			{Type: planner.BlockTypeFocus, Title: "Write docs (1/2)", Status: StatusCreated},
+			{Type: planner.BlockTypeBreak, Title: "Short break", Status: StatusCreated},
+			{Type: planner.BlockTypeFocus, Title: "Write docs (2/2)", Status: StatusCreated},
+			{Type: planner.BlockTypeFocus, Title: "Review PRs", Status: StatusCreated},
+			{Type: planner.BlockTypeFocus, Title: "Inbox", Status: StatusRolledBack},
+		},
+	}
+}
+
+func TestNewReview(t *testing.T) {
+	record := reviewRecord()
+	if got := len(record.FocusBlocks()); got != 3 {
+		t.Fatalf("expected 3 created focus blocks, got %d", got)
+	}
+	if got := record.TaskFor("Write docs (2/2)"); got != "Write docs" {
+		t.Errorf("TaskFor() = %q, want Write docs", got)
+	}
+
+	review, err := NewReview(record, []ReviewedBlock{
+		{Title: "Write docs (1/2)", Task: "Write docs", Outcome: OutcomeDone, PlannedMinutes: 30, ActualMinutes: 40},
+		{Title: "Write docs (2/2)", Task: "Write docs", Outcome: OutcomeDone, PlannedMinutes: 30, ActualMinutes: 35},
+		{Title: "Review PRs", Task: "Review PRs", Outcome: OutcomePartial, PlannedMinutes: 30, ActualMinutes: 10},
+	})
+	if err != nil {
+		t.Fatalf("NewReview() error: %v", err)
+	}
+
+	wantOutcomes := map[string]string{
+		"Write docs":  OutcomeDone,
+		"Review PRs":  OutcomePartial,
+		"Inbox":       OutcomeSkipped,
+		"Unscheduled": OutcomeSkipped,
+	}
+	for _, task := range review.Tasks {
+		if task.Outcome != wantOutcomes[task.Title] {
+			t.Errorf("%s: outcome = %s, want %s", task.Title, task.Outcome, wantOutcomes[task.Title])
+		}
+	}
+	if review.Tasks[0].ActualMinutes != 75 {
+		t.Errorf("expected 75 actual minutes for Write docs, got %d", review.Tasks[0].ActualMinutes)
+	}
+
+	if review.CarryOverTo != "2030-01-14" {
+		t.Errorf("CarryOverTo = %s, want the next Monday", review.CarryOverTo)
+	}
+	wantCarry := []Task{{Title: "Review PRs", Minutes: 20}, {Title: "Inbox", Minutes: 15}, {Title: "Unscheduled", Minutes: 30}}
+	if len(review.CarryOver) != len(wantCarry) {
+		t.Fatalf("CarryOver = %+v, want %+v", review.CarryOver, wantCarry)
+	}
+	for i, task := range review.CarryOver {
+		if task != wantCarry[i] {
+			t.Errorf("CarryOver[%d] = %+v, want %+v", i, task, wantCarry[i])
+		}
+	}
+}
+
+func TestStoreReviews(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+
+	first, _ := NewReview(reviewRecord(), nil)
+	second, _ := NewReview(reviewRecord(), []ReviewedBlock{
+		{Task: "Write docs", Outcome: OutcomeDone, ActualMinutes: 60},
+		{Task: "Review PRs", Outcome: OutcomeDone, ActualMinutes: 30},
+	})
+	for _, r := range []*Review{first, second} {
+		if err := store.AppendReview(r); err != nil {
+			t.Fatalf("AppendReview() error: %v", err)
+		}
+	}
+
+	reviews, err := store.Reviews()
+	if err != nil {
+		t.Fatalf("Reviews() error: %v", err)
+	}
+	if len(reviews) != 1 || len(reviews[0].CarryOver) != 2 {
+		t.Fatalf("expected only the latest review of the day, got %+v", reviews)
+	}
+
+	monday := time.Date(2030, 1, 14, 0, 0, 0, 0, time.Local)
+	tasks, err := store.CarryOver(monday)
+	if err != nil {
+		t.Fatalf("CarryOver() error: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Title != "Inbox" || tasks[0].Duration != planner.SizeS {
+		t.Errorf("unexpected carry over: %+v", tasks)
+	}
+	if tasks, _ := store.CarryOver(monday.AddDate(0, 0, 1)); len(tasks) != 0 {
+		t.Errorf("expected no carry over on Tuesday, got %+v", tasks)
+	}
+}
+
+func TestSizeFactors(t *testing.T) {
+	done := func(minutes, actual int) TaskOutcome {
+		return TaskOutcome{Task: Task{Minutes: minutes}, Outcome: OutcomeDone, ActualMinutes: actual}
+	}
+	reviews := []Review{
+		{Tasks: []TaskOutcome{done(30, 40), done(30, 45), done(60, 60)}},
+		{Tasks: []TaskOutcome{done(30, 35), done(60, 200), {Task: Task{Minutes: 30}, Outcome: OutcomePartial, ActualMinutes: 90}}},
+		{Tasks: []TaskOutcome{done(45, 90), done(60, 300)}},
+	}
+
+	factors := SizeFactors(reviews)
+	if len(factors) != 2 {
+		t.Fatalf("expected factors for M and L only, got %+v", factors)
+	}
+	if m := factors["M"]; m.Samples != 3 || math.Abs(m.Factor-120.0/90) > 1e-9 {
+		t.Errorf("M factor = %+v, want 1.33 from 3 samples", m)
+	}
+	if l := factors["L"]; l.Factor != maxSizeFactor {
+		t.Errorf("L factor = %v, want it capped at %v", l.Factor, maxSizeFactor)
+	}
+}
+
+func TestSizeFactorsAcrossReviewRounds(t *testing.T) {
+	// M tasks take twice as long as planned
+	review := func(tasks []planner.Task) Review {
+		r := NewRecord(calendartest.Date, "primary", config.Mode{Name: "normal"}, tasks)
+		r.Date = "2030-01-11"
+		var blocks []ReviewedBlock
+		for _, task := range r.Tasks {
+			blocks = append(blocks, ReviewedBlock{Task: task.Title, Outcome: OutcomeDone, PlannedMinutes: task.Minutes, ActualMinutes: 60})
+		}
+		got, err := NewReview(*r, blocks)
+		if err != nil {
+			t.Fatalf("NewReview() error: %v", err)
+		}
+		return *got
+	}
+	tasks := []planner.Task{
+		{Title: "A", Duration: planner.SizeM},
+		{Title: "B", Duration: planner.SizeM},
+		{Title: "C", Duration: planner.SizeM},
+	}
+
+	reviews := []Review{review(tasks)}
+	first := SizeFactors(reviews)
+	if m := first["M"]; m.Factor != 2 || m.Samples != 3 {
+		t.Fatalf("M factor after one round = %+v, want 2 from 3 samples", m)
+	}
+
+	// The next plan scales the M tasks to 60 minutes; their review must still
+	// count them as M tasks that took twice as long
+	scaled := planner.ScaleSizes(tasks, Factors(first))
+	reviews = append(reviews, review(scaled))
+	second := SizeFactors(reviews)
+	if m := second["M"]; m.Factor != 2 || m.Samples != 6 {
+		t.Errorf("M factor after two rounds = %+v, want 2 from 6 samples", m)
+	}
+	if l, ok := second["L"]; ok {
+		t.Errorf("scaled M tasks must not count as L, got L factor %+v", l)
+	}
+}
@@ -0,0 +1,205 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Journal files inside the data directory.
+const (
+	fileName        = "history.jsonl"
+	reviewsFileName = "reviews.jsonl"
+	lockFileName    = "history.lock"
+)
+
+// ErrNotFound is returned by Get for an unknown record ID.
+var ErrNotFound = errors.New("history record not found")
+
+// Store is an append-only JSON-lines journal of plan runs and their reviews.
+// Writes are serialized with a lock file, so that commands running at the same
+// time (for example plan and serve) do not lose each other's entries.
+type Store struct {
+	path        string
+	reviewsPath string
+	lockPath    string
+	now         func() time.Time
+
+	mu sync.Mutex
+}
+
+// Open returns the store in dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &Store{
+		path:        filepath.Join(dir, fileName),
+		reviewsPath: filepath.Join(dir, reviewsFileName),
+		lockPath:    filepath.Join(dir, lockFileName),
+		now:         time.Now,
+	}, nil
+}
+
+// Path returns the location of the journal file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append assigns the next ID and the creation time to r and writes it to the journal.
+func (s *Store) Append(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.lockPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := readLines[Record](s.path)
+	if err != nil {
+		return err
+	}
+	r.ID = strconv.Itoa(nextID(records))
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = s.now()
+	}
+
+	return appendLine(s.path, r)
+}
+
+// List returns all records, oldest first.
+func (s *Store) List() ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return readLines[Record](s.path)
+}
+
+// Get returns the record with the given ID.
+func (s *Store) Get(id string) (Record, error) {
+	records, err := s.List()
+	if err != nil {
+		return Record{}, err
+	}
+	for _, r := range records {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return Record{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Update replaces the stored record with the same ID as r.
+func (s *Store) Update(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.lockPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := readLines[Record](s.path)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(records, func(existing Record) bool { return existing.ID == r.ID })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, r.ID)
+	}
+	records[i] = r
+
+	// Write a new journal next to the old one and swap it in, so a failure
+	// never leaves a truncated history behind
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), fileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to update history: %w", err)
+	}
+	enc := json.NewEncoder(tmp)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+			return fmt.Errorf("failed to update history: %w", err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to update history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to update history: %w", err)
+	}
+	return nil
+}
+
+func appendLine(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return f.Close()
+}
+
+// readLines decodes a JSON-lines file. A missing file has no entries.
+func readLines[T any](path string) ([]T, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close history file: %v\n", closeErr)
+		}
+	}()
+
+	var entries []T
+	scanner := bufio.NewScanner(f)
+	// Records carry the full prompt and response, which can exceed the default line limit
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry T
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %d: %w", filepath.Base(path), line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+func nextID(records []Record) int {
+	highest := 0
+	for _, r := range records {
+		if id, err := strconv.Atoi(r.ID); err == nil && id > highest {
+			highest = id
+		}
+	}
+	return highest + 1
+}
@@ -0,0 +1,96 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors for the classes of API failures. Use errors.Is to check
+// the class of an *Error.
+var (
+	ErrAuth       = errors.New("authentication failed")
+	ErrQuota      = errors.New("rate limit or quota exceeded")
+	ErrTransient  = errors.New("temporary failure")
+	ErrBadRequest = errors.New("request rejected")
+)
+
+// Error is a classified API error.
+type Error struct {
+	// Service names the API, e.g. "OpenAI" or "Google Calendar".
+	Service string
+	// StatusCode is the HTTP status, or 0 for network errors.
+	StatusCode int
+	Message    string
+	kind       error
+	cause      error
+}
+
+func (e *Error) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Service)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&sb, " API returned status %d", e.StatusCode)
+	} else {
+		sb.WriteString(" API call failed")
+	}
+	if e.Message != "" {
+		sb.WriteString(": " + e.Message)
+	} else if e.cause != nil {
+		sb.WriteString(": " + e.cause.Error())
+	}
+	return sb.String()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Kind returns the sentinel error describing the class of the error.
+func (e *Error) Kind() error {
+	return e.kind
+}
+
+// NewStatusError classifies an HTTP error response.
+func NewStatusError(service string, statusCode int, message string) *Error {
+	return &Error{
+		Service:    service,
+		StatusCode: statusCode,
+		Message:    strings.TrimSpace(message),
+		kind:       classify(statusCode, message),
+	}
+}
+
+// NewNetworkError wraps a transport failure (timeout, connection reset, ...) as transient.
+func NewNetworkError(service string, err error) *Error {
+	return &Error{
+		Service: service,
+		kind:    ErrTransient,
+		cause:   err,
+	}
+}
+
+func classify(statusCode int, message string) error {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return ErrAuth
+	case statusCode == http.StatusForbidden:
+		// Google reports exhausted quotas as 403 with a rateLimitExceeded reason
+		lower := strings.ToLower(message)
+		if strings.Contains(lower, "ratelimit") || strings.Contains(lower, "rate limit") || strings.Contains(lower, "quota") {
+			return ErrQuota
+		}
+		return ErrAuth
+	case statusCode == http.StatusTooManyRequests:
+		return ErrQuota
+	case statusCode == http.StatusRequestTimeout || statusCode >= 500:
+		return ErrTransient
+	default:
+		return ErrBadRequest
+	}
+}
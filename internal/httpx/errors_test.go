@@ -0,0 +1,50 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewStatusError(t *testing.T) {
+	tests := []struct {
+		status  int
+		message string
+		want    error
+	}{
+		{401, "invalid api key", ErrAuth},
+		{403, "The caller does not have permission (forbidden)", ErrAuth},
+		{403, "Rate Limit Exceeded (rateLimitExceeded)", ErrQuota},
+		{429, "slow down", ErrQuota},
+		{408, "", ErrTransient},
+		{500, "oops", ErrTransient},
+		{503, "unavailable", ErrTransient},
+		{400, "bad model", ErrBadRequest},
+		{404, "calendar not found", ErrBadRequest},
+	}
+
+	for _, tt := range tests {
+		err := NewStatusError("OpenAI", tt.status, tt.message)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("NewStatusError(%d, %q) kind = %v, want %v", tt.status, tt.message, err.Kind(), tt.want)
+		}
+	}
+}
+
+func TestErrorWrapping(t *testing.T) {
+	cause := errors.New("connection reset by peer")
+	err := fmt.Errorf("failed to fetch events: %w", NewNetworkError("Google Calendar", cause))
+
+	if !errors.Is(err, ErrTransient) || !errors.Is(err, cause) {
+		t.Errorf("expected %v to be transient and wrap the cause", err)
+	}
+	if !strings.Contains(err.Error(), "Google Calendar API call failed: connection reset by peer") {
+		t.Errorf("unexpected message: %v", err)
+	}
+
+	status := NewStatusError("OpenAI", 401, "bad key\n")
+	if status.Error() != "OpenAI API returned status 401: bad key" {
+		t.Errorf("unexpected message: %q", status.Error())
+	}
+}
@@ -0,0 +1,198 @@
+// Package httpx provides the HTTP layer shared by the OpenAI and Google
+// Calendar clients: per-attempt timeouts, retries with exponential backoff and
+// jitter, Retry-After support, and classified API errors.
+package httpx
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultMaxRetries = 3
+	DefaultBaseDelay  = 500 * time.Millisecond
+	DefaultMaxDelay   = 30 * time.Second
+)
+
+// Policy configures timeouts and retries.
+type Policy struct {
+	// Timeout limits each attempt, including reading the response body.
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on every retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff and any Retry-After delay.
+	MaxDelay time.Duration
+}
+
+// DefaultPolicy returns a policy with the given per-attempt timeout and default retries.
+func DefaultPolicy(timeout time.Duration) Policy {
+	return Policy{
+		Timeout:    timeout,
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultBaseDelay,
+		MaxDelay:   DefaultMaxDelay,
+	}
+}
+
+// NewClient returns an HTTP client that retries transient failures according to p.
+func NewClient(p Policy) *http.Client {
+	return &http.Client{Transport: NewTransport(http.DefaultTransport, p)}
+}
+
+// Transport retries requests that failed with a network error, 408, 429 or 5xx.
+// Requests that are not idempotent, such as a POST that inserts an event or asks
+// for a completion, may already have been processed after such a failure, so
+// they are only retried after a 429. Like net/http, a POST with an
+// Idempotency-Key header counts as idempotent. Requests with a body are only
+// retried if the body can be replayed (GetBody is set).
+type Transport struct {
+	Base   http.RoundTripper
+	Policy Policy
+
+	// sleep waits for d or until ctx is done. Replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewTransport(base http.RoundTripper, p Policy) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base, Policy: p, sleep: sleep}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := t.attempt(req)
+
+		if attempt >= t.Policy.MaxRetries || !retryable(req, resp, err) || ctx.Err() != nil ||
+			(req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = min(after, t.maxDelay())
+			}
+			// Drain so the connection can be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+
+		if err := t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.Policy.Timeout <= 0 {
+		return t.Base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.Policy.Timeout)
+	resp, err := t.Base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns a random delay in [0, BaseDelay * 2^attempt], capped at MaxDelay ("full jitter").
+func (t *Transport) backoff(attempt int) time.Duration {
+	base := t.Policy.BaseDelay
+	if base <= 0 {
+		base = DefaultBaseDelay
+	}
+	ceiling := min(base<<min(attempt, 16), t.maxDelay())
+	return rand.N(ceiling + 1)
+}
+
+func (t *Transport) maxDelay() time.Duration {
+	if t.Policy.MaxDelay <= 0 {
+		return DefaultMaxDelay
+	}
+	return t.Policy.MaxDelay
+}
+
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if !idempotent(req) {
+		return err == nil && resp.StatusCode == http.StatusTooManyRequests
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	default:
+		return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+	}
+}
+
+// idempotent reports whether sending req twice has the same effect as sending
+// it once.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodPatch:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cancelOnClose releases the per-attempt timeout once the body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,217 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client whose transport records backoff delays instead of sleeping.
+func newTestClient(p Policy) (*http.Client, *[]time.Duration) {
+	var delays []time.Duration
+	transport := NewTransport(nil, p)
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return &http.Client{Transport: transport}, &delays
+}
+
+// statusSequence serves the given statuses in order, repeating the last one.
+func statusSequence(t *testing.T, headers http.Header, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		i := int(calls.Add(1)) - 1
+		for k, v := range headers {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(statuses[min(i, len(statuses)-1)])
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestTransportRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		wantStatus int
+		wantCalls  int32
+	}{
+		{"success", []int{200}, 3, 200, 1},
+		{"retries server errors", []int{503, 500, 200}, 3, 200, 3},
+		{"retries rate limits", []int{429, 200}, 3, 200, 2},
+		{"gives up after max retries", []int{502}, 2, 502, 3},
+		{"does not retry bad requests", []int{400, 200}, 3, 400, 1},
+		{"does not retry auth errors", []int{401, 200}, 3, 401, 1},
+		{"retries disabled", []int{503, 200}, 0, 503, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := statusSequence(t, nil, tt.statuses...)
+			client, _ := newTestClient(Policy{MaxRetries: tt.maxRetries})
+
+			req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(`{"a":1}`))
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			if string(body) != `{"a":1}` {
+				t.Errorf("body = %q, want the request body replayed on every attempt", body)
+			}
+		})
+	}
+}
+
+func TestTransportRetriesPostOnlyOnRateLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		header    string
+		wantCalls int32
+	}{
+		{"server error", []int{503, 200}, "", 1},
+		{"request timeout", []int{408, 200}, "", 1},
+		{"rate limit", []int{429, 200}, "", 2},
+		{"idempotency key", []int{503, 200}, "key-1", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := statusSequence(t, nil, tt.statuses...)
+			client, _ := newTestClient(Policy{MaxRetries: 3})
+
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"a":1}`))
+			if tt.header != "" {
+				req.Header.Set("Idempotency-Key", tt.header)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error: %v", err)
+			}
+			_ = resp.Body.Close()
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTransportBackoff(t *testing.T) {
+	server, _ := statusSequence(t, nil, 503)
+	client, delays := newTestClient(Policy{MaxRetries: 4, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond})
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	ceilings := []time.Duration{100, 200, 300, 300}
+	if len(*delays) != len(ceilings) {
+		t.Fatalf("expected %d delays, got %v", len(ceilings), *delays)
+	}
+	for i, d := range *delays {
+		if d < 0 || d > ceilings[i]*time.Millisecond {
+			t.Errorf("delay %d = %v, want between 0 and %v", i, d, ceilings[i]*time.Millisecond)
+		}
+	}
+}
+
+func TestTransportRetryAfter(t *testing.T) {
+	server, _ := statusSequence(t, http.Header{"Retry-After": {"7"}}, 429, 200)
+	client, delays := newTestClient(Policy{MaxRetries: 3, MaxDelay: time.Minute})
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if len(*delays) != 1 || (*delays)[0] != 7*time.Second {
+		t.Errorf("delays = %v, want [7s]", *delays)
+	}
+}
+
+func TestTransportCanceled(t *testing.T) {
+	server, calls := statusSequence(t, nil, 503)
+	client := NewClient(Policy{MaxRetries: 5, BaseDelay: time.Hour, MaxDelay: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	_, err := client.Do(req)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want context.Canceled", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestTransportTimeout(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(Policy{Timeout: 50 * time.Millisecond, MaxRetries: 1})
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("expected the timed out attempt to be retried, got status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"Mon, 07 Jan 2030 09:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 07 Jan 2030 08:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -1,22 +1,32 @@
 package planner
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 const (
-	BlockTypeLunch   = "lunch"
-	BlockTypeMeeting = "meeting"
-	BlockTypeBreak   = "break"
-	BlockTypeFocus   = "focus"
+	BlockTypeLunch       = "lunch"
+	BlockTypeMeeting     = "meeting"
+	BlockTypeBreak       = "break"
+	BlockTypeFocus       = "focus"
+	BlockTypeBuffer      = "buffer"
+	BlockTypeOutOfOffice = "out_of_office"
+	BlockTypeRoutine     = "routine"
 
 	// TimeFormat for HH:MM (24-hour)
 	TimeFormat = "15:04"
 )
 
 type TimeBlock struct {
-	Type  string
-	Title string
-	Start time.Time
-	End   time.Time
+	Type     string
+	Title    string
+	Start    time.Time
+	End      time.Time
+	Location string
+	// Suggested is set on blocks the planner proposed on its own, such as prep
+	// before a meeting. They are only scheduled once the user accepts them.
+	Suggested bool
 }
 
 func (b TimeBlock) GetCalendarTitle() string {
@@ -38,6 +48,10 @@ func (b TimeBlock) GetCalendarDescription() string {
 		return "Break block planned by Barely In Charge: " + b.Title
 	case BlockTypeLunch:
 		return "Lunch break"
+	case BlockTypeRoutine:
+		return "Routine planned by Barely In Charge"
+	case BlockTypeOutOfOffice:
+		return "Finished early, planned by Barely In Charge"
 	default:
 		return ""
 	}
@@ -59,3 +73,26 @@ func ParseTimeOnDate(timeStr string, date time.Time) (time.Time, error) {
 		date.Location(),
 	), nil
 }
+
+// EarlyFinish returns an out-of-office block from the end of the last planned or
+// busy block until the end of the work day. It returns false when nothing is left
+// of the day.
+func EarlyFinish(planned, busy []TimeBlock, workEnd time.Time) (TimeBlock, bool) {
+	var last time.Time
+	for _, b := range append(slices.Clone(planned), busy...) {
+		if b.End.After(last) {
+			last = b.End
+		}
+	}
+
+	if last.IsZero() || !last.Before(workEnd) {
+		return TimeBlock{}, false
+	}
+
+	return TimeBlock{
+		Type:  BlockTypeOutOfOffice,
+		Title: "Out of office",
+		Start: last,
+		End:   workEnd,
+	}, true
+}
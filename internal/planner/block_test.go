@@ -0,0 +1,37 @@
+package planner
+
+import "testing"
+
+func TestEarlyFinish(t *testing.T) {
+	planned := []TimeBlock{
+		{Type: BlockTypeFocus, Title: "Write docs", Start: at("09:00"), End: at("10:00")},
+		{Type: BlockTypeBreak, Title: "Short break", Start: at("10:00"), End: at("10:15")},
+	}
+	busy := []TimeBlock{
+		{Type: BlockTypeLunch, Title: "Lunch", Start: at("12:00"), End: at("13:00")},
+	}
+
+	block, ok := EarlyFinish(planned, busy, at("17:00"))
+	if !ok {
+		t.Fatal("expected an out-of-office block")
+	}
+	if block.Type != BlockTypeOutOfOffice {
+		t.Errorf("Type = %q, want %q", block.Type, BlockTypeOutOfOffice)
+	}
+	if block.Start.Format(TimeFormat) != "13:00" || block.End.Format(TimeFormat) != "17:00" {
+		t.Errorf("block = %s-%s, want 13:00-17:00", block.Start.Format(TimeFormat), block.End.Format(TimeFormat))
+	}
+}
+
+func TestEarlyFinishFullDay(t *testing.T) {
+	planned := []TimeBlock{
+		{Type: BlockTypeFocus, Title: "Write docs", Start: at("15:00"), End: at("17:00")},
+	}
+
+	if _, ok := EarlyFinish(planned, nil, at("17:00")); ok {
+		t.Error("expected no out-of-office block when the plan fills the day")
+	}
+	if _, ok := EarlyFinish(nil, nil, at("17:00")); ok {
+		t.Error("expected no out-of-office block for an empty plan")
+	}
+}
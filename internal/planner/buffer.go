@@ -0,0 +1,93 @@
+package planner
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Buffers holds the padding kept free around meetings.
+type Buffers struct {
+	Before time.Duration
+	After  time.Duration
+	// MinGap is the shortest free slot worth scheduling; shorter gaps are blocked.
+	MinGap time.Duration
+	// Travel is added on both sides of meetings that have a physical location.
+	// Video call links and locations like "Online" don't count; see IsPhysicalLocation.
+	Travel time.Duration
+}
+
+// virtualLocations are location values, in lower case, that mean a meeting is
+// held online. Locations that contain one of virtualHosts are video call links.
+var (
+	virtualLocations = []string{"online", "virtual", "remote", "video call", "phone", "call",
+		"zoom", "google meet", "meet", "microsoft teams", "teams", "webex", "slack huddle"}
+	virtualHosts = []string{"meet.google.com", "zoom.us", "teams.microsoft.com", "teams.live.com",
+		"webex.com", "whereby.com", "gotomeeting.com", "chime.aws", "meet.jit.si"}
+)
+
+// IsPhysicalLocation reports whether location is a place to travel to, rather
+// than empty, a URL or a value such as "Online" or "Zoom".
+func IsPhysicalLocation(location string) bool {
+	loc := strings.ToLower(strings.TrimSpace(location))
+	if loc == "" || slices.Contains(virtualLocations, loc) {
+		return false
+	}
+	if strings.Contains(loc, "://") || strings.HasPrefix(loc, "www.") {
+		return false
+	}
+	return !slices.ContainsFunc(virtualHosts, func(host string) bool { return strings.Contains(loc, host) })
+}
+
+// ApplyBuffers returns the busy blocks extended with buffer blocks before and after
+// every meeting, travel time around meetings with a physical location, and blocks covering
+// free gaps inside work hours that are shorter than MinGap.
+func ApplyBuffers(busy []TimeBlock, b Buffers, workStart, workEnd time.Time) []TimeBlock {
+	result := slices.Clone(busy)
+
+	for _, block := range busy {
+		if block.Type != BlockTypeMeeting {
+			continue
+		}
+
+		before, after := b.Before, b.After
+		if IsPhysicalLocation(block.Location) {
+			before += b.Travel
+			after += b.Travel
+		}
+
+		if before > 0 {
+			result = append(result, TimeBlock{
+				Type:  BlockTypeBuffer,
+				Title: "Buffer before " + block.Title,
+				Start: block.Start.Add(-before),
+				End:   block.Start,
+			})
+		}
+		if after > 0 {
+			result = append(result, TimeBlock{
+				Type:  BlockTypeBuffer,
+				Title: "Buffer after " + block.Title,
+				Start: block.End,
+				End:   block.End.Add(after),
+			})
+		}
+	}
+
+	if b.MinGap > 0 {
+		for _, gap := range FreeWindows(result, workStart, workEnd) {
+			if gap.End.Sub(gap.Start) < b.MinGap {
+				result = append(result, TimeBlock{
+					Type:  BlockTypeBuffer,
+					Title: "Gap too short to use",
+					Start: gap.Start,
+					End:   gap.End,
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(result, func(x, y TimeBlock) int { return x.Start.Compare(y.Start) })
+
+	return result
+}
@@ -0,0 +1,94 @@
+package planner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyBuffers(t *testing.T) {
+	busy := []TimeBlock{
+		{Type: BlockTypeLunch, Title: "Lunch", Start: at("12:00"), End: at("13:00")},
+		{Type: BlockTypeMeeting, Title: "Standup", Start: at("10:00"), End: at("10:15")},
+		{Type: BlockTypeMeeting, Title: "Client visit", Start: at("14:00"), End: at("15:00"), Location: "Office B"},
+	}
+	buffers := Buffers{
+		Before: 5 * time.Minute,
+		After:  10 * time.Minute,
+		Travel: 20 * time.Minute,
+	}
+
+	result := ApplyBuffers(busy, buffers, at("09:00"), at("17:00"))
+
+	expected := []struct {
+		typ, title, start, end string
+	}{
+		{BlockTypeBuffer, "Buffer before Standup", "09:55", "10:00"},
+		{BlockTypeMeeting, "Standup", "10:00", "10:15"},
+		{BlockTypeBuffer, "Buffer after Standup", "10:15", "10:25"},
+		{BlockTypeLunch, "Lunch", "12:00", "13:00"},
+		{BlockTypeBuffer, "Buffer before Client visit", "13:35", "14:00"},
+		{BlockTypeMeeting, "Client visit", "14:00", "15:00"},
+		{BlockTypeBuffer, "Buffer after Client visit", "15:00", "15:30"},
+	}
+
+	if len(result) != len(expected) {
+		t.Fatalf("got %d blocks, want %d: %v", len(result), len(expected), result)
+	}
+	for i, want := range expected {
+		got := result[i]
+		if got.Type != want.typ || got.Title != want.title ||
+			got.Start.Format(TimeFormat) != want.start || got.End.Format(TimeFormat) != want.end {
+			t.Errorf("block %d = %s %q %s-%s, want %s %q %s-%s", i,
+				got.Type, got.Title, got.Start.Format(TimeFormat), got.End.Format(TimeFormat),
+				want.typ, want.title, want.start, want.end)
+		}
+	}
+}
+
+func TestApplyBuffersSkipsTravelForVideoCalls(t *testing.T) {
+	locations := []string{"https://meet.google.com/abc-defg-hij", "Online", "zoom.us/j/123456", "Microsoft Teams", "www.example.com/call"}
+	for _, location := range locations {
+		busy := []TimeBlock{{Type: BlockTypeMeeting, Title: "Sync", Start: at("10:00"), End: at("10:30"), Location: location}}
+		result := ApplyBuffers(busy, Buffers{Before: 5 * time.Minute, Travel: 20 * time.Minute}, at("09:00"), at("17:00"))
+		if got := result[0].Start.Format(TimeFormat); got != "09:55" {
+			t.Errorf("location %q: buffer starts at %s, want 09:55 without travel time", location, got)
+		}
+	}
+
+	if !IsPhysicalLocation("Office B, 3rd floor") {
+		t.Errorf("expected an office to be a physical location")
+	}
+}
+
+func TestApplyBuffersBlocksShortGaps(t *testing.T) {
+	busy := []TimeBlock{
+		{Type: BlockTypeMeeting, Title: "Sync", Start: at("09:00"), End: at("09:30")},
+		{Type: BlockTypeMeeting, Title: "1:1", Start: at("09:50"), End: at("10:30")},
+		{Type: BlockTypeMeeting, Title: "Review", Start: at("16:45"), End: at("17:00")},
+	}
+
+	result := ApplyBuffers(busy, Buffers{MinGap: 25 * time.Minute}, at("09:00"), at("17:00"))
+
+	var gaps []string
+	for _, b := range result {
+		if b.Type == BlockTypeBuffer {
+			gaps = append(gaps, b.Start.Format(TimeFormat)+"-"+b.End.Format(TimeFormat))
+		}
+	}
+
+	if len(gaps) != 1 || gaps[0] != "09:30-09:50" {
+		t.Errorf("short gaps = %v, want [09:30-09:50]", gaps)
+	}
+}
+
+func TestApplyBuffersNoConfig(t *testing.T) {
+	busy := []TimeBlock{
+		{Type: BlockTypeMeeting, Title: "Sync", Start: at("09:00"), End: at("09:30"), Location: "Room 1"},
+	}
+
+	result := ApplyBuffers(busy, Buffers{}, at("09:00"), at("17:00"))
+
+	if len(result) != 1 {
+		t.Errorf("expected busy blocks unchanged without buffers, got %v", result)
+	}
+}
@@ -0,0 +1,42 @@
+package planner
+
+import "time"
+
+const (
+	EnergyPeak     = "peak"
+	EnergySlump    = "slump"
+	EnergyWindDown = "wind_down"
+)
+
+type EnergyWindow struct {
+	Level string
+	Start time.Time
+	End   time.Time
+}
+
+// EnergyProfile lists the windows of the day with a known energy level.
+// Time outside of any window has a neutral level.
+type EnergyProfile []EnergyWindow
+
+// LevelAt returns the energy level at t, or an empty string if t is not in any window.
+func (p EnergyProfile) LevelAt(t time.Time) string {
+	for _, w := range p {
+		if !t.Before(w.Start) && t.Before(w.End) {
+			return w.Level
+		}
+	}
+	return ""
+}
+
+// PreferredKind returns the task kind that suits an energy level best:
+// deep work at peak, shallow work during slumps and the wind-down.
+func PreferredKind(level string) string {
+	switch level {
+	case EnergyPeak:
+		return TaskKindDeep
+	case EnergySlump, EnergyWindDown:
+		return TaskKindShallow
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,159 @@
+package planner
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	hoursPattern   = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?)\s*(?:h|hrs?|hours?)(?:\s*(\d+)\s*(?:m|mins?|minutes?)?)?\b`)
+	minutesPattern = regexp.MustCompile(`(?i)\b(\d+)\s*(?:m|mins?|minutes?)\b`)
+	timePattern    = regexp.MustCompile(`(?i)\b(after|from|before|by|until)\s+(lunch|noon|\d{1,2}(?::\d{2})?\s*(?:am|pm)?)\b`)
+	emptyParens    = regexp.MustCompile(`\(\s*[,;]*\s*\)`)
+)
+
+// ParseNaturalTasks parses tasks written in plain language and separated by
+// commas, semicolons or new lines, e.g. "finish the RFC (2h, before lunch), call
+// Bob after 3pm". It understands durations ("2h", "45m", "1h30", "90 min") and
+// time constraints ("after 15:00", "before 3pm", "by 11", "before lunch"); the
+// rest is the title. Hours without am/pm from 1 to 6 are taken as afternoon
+// hours. Lunch is needed as HH:MM to resolve "lunch". Sizes and kinds after
+// colons work as in ParseTaskList, and tasks without a duration default to M.
+func ParseNaturalTasks(text, lunchStart, lunchEnd string) []Task {
+	var tasks []Task
+	for _, item := range splitOutsideParens(text) {
+		if task, ok := parseNaturalTask(item, lunchStart, lunchEnd); ok {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+func parseNaturalTask(item, lunchStart, lunchEnd string) (Task, bool) {
+	var task Task
+
+	rest := timePattern.ReplaceAllStringFunc(item, func(m string) string {
+		parts := timePattern.FindStringSubmatch(m)
+		word, at := strings.ToLower(parts[1]), strings.ToLower(parts[2])
+		after := word == "after" || word == "from"
+		var hhmm string
+		switch at {
+		case "lunch":
+			hhmm = lunchStart
+			if after {
+				hhmm = lunchEnd
+			}
+		case "noon":
+			hhmm = "12:00"
+		default:
+			var ok bool
+			if hhmm, ok = clockTime(at); !ok {
+				return m
+			}
+		}
+		if hhmm == "" {
+			return m
+		}
+		if after {
+			task.After = hhmm
+		} else {
+			task.Before = hhmm
+		}
+		return ""
+	})
+
+	rest = hoursPattern.ReplaceAllStringFunc(rest, func(m string) string {
+		parts := hoursPattern.FindStringSubmatch(m)
+		hours, _ := strconv.ParseFloat(parts[1], 64)
+		task.Duration += time.Duration(hours * float64(time.Hour))
+		if parts[2] != "" {
+			minutes, _ := strconv.Atoi(parts[2])
+			task.Duration += time.Duration(minutes) * time.Minute
+		}
+		return ""
+	})
+	rest = minutesPattern.ReplaceAllStringFunc(rest, func(m string) string {
+		minutes, _ := strconv.Atoi(minutesPattern.FindStringSubmatch(m)[1])
+		task.Duration += time.Duration(minutes) * time.Minute
+		return ""
+	})
+
+	rest = emptyParens.ReplaceAllString(rest, "")
+	rest = strings.Join(strings.Fields(rest), " ")
+	rest = strings.Trim(rest, " ,;-")
+	if rest == "" {
+		return Task{}, false
+	}
+
+	tagged := parseTask(rest)
+	task.Title = capitalize(tagged.Title)
+	task.Kind = tagged.Kind
+	if task.Duration == 0 {
+		task.Duration = tagged.Duration
+	}
+	return task, true
+}
+
+// clockTime converts "15:00", "3pm", "9:30am" or "3" to HH:MM.
+func clockTime(s string) (string, bool) {
+	s = strings.ReplaceAll(s, " ", "")
+	suffix := ""
+	if strings.HasSuffix(s, "am") || strings.HasSuffix(s, "pm") {
+		suffix, s = s[len(s)-2:], s[:len(s)-2]
+	}
+	hourStr, minuteStr, _ := strings.Cut(s, ":")
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil {
+		return "", false
+	}
+	minute := 0
+	if minuteStr != "" {
+		if minute, err = strconv.Atoi(minuteStr); err != nil {
+			return "", false
+		}
+	}
+
+	switch {
+	case suffix == "pm" && hour < 12:
+		hour += 12
+	case suffix == "am" && hour == 12:
+		hour = 0
+	case suffix == "" && hour >= 1 && hour <= 6:
+		hour += 12
+	}
+	if hour > 23 || minute > 59 {
+		return "", false
+	}
+	return fmt.Sprintf("%02d:%02d", hour, minute), true
+}
+
+// splitOutsideParens splits s on commas, semicolons and new lines that are not
+// inside parentheses.
+func splitOutsideParens(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth = max(depth-1, 0)
+		case ',', ';', '\n':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,179 @@
+package planner
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// partSuffix matches the "(1/3)" added to the titles of a task split over slots.
+var partSuffix = regexp.MustCompile(`\s\(\d+/\d+\)$`)
+
+// TaskTitle returns the task title of a focus block, without the part suffix
+// added when a task is split over several slots.
+func TaskTitle(blockTitle string) string {
+	return partSuffix.ReplaceAllString(blockTitle, "")
+}
+
+// Cadence is a fixed focus/break rhythm such as the Pomodoro technique:
+// Focus-long work slots separated by Break, with a LongBreak after every
+// LongBreakEvery slots.
+type Cadence struct {
+	Focus          time.Duration
+	Break          time.Duration
+	LongBreak      time.Duration
+	LongBreakEvery int
+}
+
+// EnforceCadence rebuilds a plan so it follows the cadence exactly. The order of
+// tasks is taken from the focus blocks of the generated plan, tasks missing from it
+// are appended in their original order. Every task is rounded up to whole focus slots.
+// Slots are laid out in the free time between busy blocks, and the long-break counter
+// restarts in every free window, so the cadence resets around meetings.
+// When a new task is started, a task whose kind suits the energy level at that time
+// is pulled forward. Tasks that do not fit into the day are returned as unscheduled.
+func EnforceCadence(generated []TimeBlock, tasks []Task, busy []TimeBlock, workStart, workEnd time.Time, c Cadence, energy EnergyProfile) ([]TimeBlock, []Task) {
+	if c.Focus <= 0 {
+		return generated, nil
+	}
+
+	queue := orderTasks(generated, tasks)
+	blocks := make([]TimeBlock, 0, len(queue)*2)
+
+	taskIdx, slotIdx := 0, 0
+	slotsFor := func(t Task) int {
+		n := int((t.Duration + c.Focus - 1) / c.Focus)
+		return max(n, 1)
+	}
+
+	for _, window := range FreeWindows(busy, workStart, workEnd) {
+		cursor := window.Start
+		count := 0
+		for taskIdx < len(queue) && !cursor.Add(c.Focus).After(window.End) {
+			if slotIdx == 0 {
+				pullForward(queue[taskIdx:], PreferredKind(energy.LevelAt(cursor)))
+			}
+			task := queue[taskIdx]
+			total := slotsFor(task)
+
+			title := task.Title
+			if total > 1 {
+				title = fmt.Sprintf("%s (%d/%d)", task.Title, slotIdx+1, total)
+			}
+			blocks = append(blocks, TimeBlock{
+				Type:  BlockTypeFocus,
+				Title: title,
+				Start: cursor,
+				End:   cursor.Add(c.Focus),
+			})
+			cursor = cursor.Add(c.Focus)
+			count++
+
+			slotIdx++
+			if slotIdx == total {
+				taskIdx++
+				slotIdx = 0
+			}
+
+			pause, breakTitle := c.Break, "Short break"
+			if c.LongBreakEvery > 0 && count%c.LongBreakEvery == 0 {
+				pause, breakTitle = c.LongBreak, "Long break"
+			}
+			// Only take the break if another slot fits after it in this window.
+			if taskIdx == len(queue) || cursor.Add(pause+c.Focus).After(window.End) {
+				break
+			}
+			if pause > 0 {
+				blocks = append(blocks, TimeBlock{
+					Type:  BlockTypeBreak,
+					Title: breakTitle,
+					Start: cursor,
+					End:   cursor.Add(pause),
+				})
+				cursor = cursor.Add(pause)
+			}
+		}
+	}
+
+	if taskIdx < len(queue) {
+		return blocks, queue[taskIdx:]
+	}
+
+	return blocks, nil
+}
+
+// FreeWindows returns the gaps between busy blocks inside work hours, in order.
+func FreeWindows(busy []TimeBlock, workStart, workEnd time.Time) []TimeBlock {
+	sorted := slices.Clone(busy)
+	slices.SortFunc(sorted, func(a, b TimeBlock) int { return a.Start.Compare(b.Start) })
+
+	var windows []TimeBlock
+	cursor := workStart
+	for _, b := range sorted {
+		if !b.End.After(cursor) {
+			continue
+		}
+		if b.Start.After(cursor) {
+			end := b.Start
+			if end.After(workEnd) {
+				end = workEnd
+			}
+			if end.After(cursor) {
+				windows = append(windows, TimeBlock{Start: cursor, End: end})
+			}
+		}
+		cursor = b.End
+	}
+	if workEnd.After(cursor) {
+		windows = append(windows, TimeBlock{Start: cursor, End: workEnd})
+	}
+
+	return windows
+}
+
+// pullForward moves the first task of the given kind to the front of the queue,
+// keeping the relative order of the others.
+func pullForward(queue []Task, kind string) {
+	if kind == "" {
+		return
+	}
+	idx := slices.IndexFunc(queue, func(t Task) bool { return t.Kind == kind })
+	if idx <= 0 {
+		return
+	}
+	task := queue[idx]
+	copy(queue[1:idx+1], queue[:idx])
+	queue[0] = task
+}
+
+// orderTasks returns tasks in the order their focus blocks first appear in the
+// generated plan, followed by any tasks the plan did not mention.
+func orderTasks(generated []TimeBlock, tasks []Task) []Task {
+	focus := make([]TimeBlock, 0, len(generated))
+	for _, b := range generated {
+		if b.Type == BlockTypeFocus {
+			focus = append(focus, b)
+		}
+	}
+	slices.SortStableFunc(focus, func(a, b TimeBlock) int { return a.Start.Compare(b.Start) })
+
+	ordered := make([]Task, 0, len(tasks))
+	used := make([]bool, len(tasks))
+	for _, b := range focus {
+		for i, t := range tasks {
+			if !used[i] && t.Title == b.Title {
+				ordered = append(ordered, t)
+				used[i] = true
+				break
+			}
+		}
+	}
+	for i, t := range tasks {
+		if !used[i] {
+			ordered = append(ordered, t)
+		}
+	}
+
+	return ordered
+}
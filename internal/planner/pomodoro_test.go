@@ -0,0 +1,210 @@
+package planner
+
+import (
+	"testing"
+	"time"
+)
+
+var testCadence = Cadence{
+	Focus:          25 * time.Minute,
+	Break:          5 * time.Minute,
+	LongBreak:      20 * time.Minute,
+	LongBreakEvery: 4,
+}
+
+func at(hhmm string) time.Time {
+	t, err := ParseTimeOnDate(hhmm, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestEnforceCadenceLongBreakAfterFourth(t *testing.T) {
+	tasks := []Task{{Title: "Write RFC", Duration: 2 * time.Hour}}
+
+	blocks, unscheduled := EnforceCadence(nil, tasks, nil, at("09:00"), at("12:00"), testCadence, nil)
+
+	if len(unscheduled) != 0 {
+		t.Fatalf("expected all tasks scheduled, got unscheduled %v", unscheduled)
+	}
+
+	// 2h rounds up to 5 pomodoros: 4 pomodoros, 3 short breaks, 1 long break, 1 pomodoro
+	expected := []struct {
+		typ, title, start, end string
+	}{
+		{BlockTypeFocus, "Write RFC (1/5)", "09:00", "09:25"},
+		{BlockTypeBreak, "Short break", "09:25", "09:30"},
+		{BlockTypeFocus, "Write RFC (2/5)", "09:30", "09:55"},
+		{BlockTypeBreak, "Short break", "09:55", "10:00"},
+		{BlockTypeFocus, "Write RFC (3/5)", "10:00", "10:25"},
+		{BlockTypeBreak, "Short break", "10:25", "10:30"},
+		{BlockTypeFocus, "Write RFC (4/5)", "10:30", "10:55"},
+		{BlockTypeBreak, "Long break", "10:55", "11:15"},
+		{BlockTypeFocus, "Write RFC (5/5)", "11:15", "11:40"},
+	}
+
+	if len(blocks) != len(expected) {
+		t.Fatalf("got %d blocks, want %d: %v", len(blocks), len(expected), blocks)
+	}
+	for i, want := range expected {
+		got := blocks[i]
+		if got.Type != want.typ || got.Title != want.title ||
+			got.Start.Format(TimeFormat) != want.start || got.End.Format(TimeFormat) != want.end {
+			t.Errorf("block %d = %s %q %s-%s, want %s %q %s-%s", i,
+				got.Type, got.Title, got.Start.Format(TimeFormat), got.End.Format(TimeFormat),
+				want.typ, want.title, want.start, want.end)
+		}
+	}
+}
+
+func TestEnforceCadenceResetsAroundMeetings(t *testing.T) {
+	tasks := []Task{
+		{Title: "A", Duration: SizeS},
+		{Title: "B", Duration: SizeS},
+		{Title: "C", Duration: SizeS},
+		{Title: "D", Duration: SizeS},
+		{Title: "E", Duration: SizeS},
+	}
+	busy := []TimeBlock{
+		{Type: BlockTypeMeeting, Title: "Standup", Start: at("10:00"), End: at("10:30")},
+	}
+
+	blocks, unscheduled := EnforceCadence(nil, tasks, busy, at("09:00"), at("13:00"), testCadence, nil)
+
+	if len(unscheduled) != 0 {
+		t.Fatalf("expected all tasks scheduled, got unscheduled %v", unscheduled)
+	}
+
+	for _, b := range blocks {
+		if b.Start.Before(busy[0].End) && b.End.After(busy[0].Start) {
+			t.Errorf("block %q (%s-%s) overlaps the meeting", b.Title,
+				b.Start.Format(TimeFormat), b.End.Format(TimeFormat))
+		}
+		if b.Title == "Long break" {
+			t.Errorf("cadence should reset after the meeting, got a long break at %s", b.Start.Format(TimeFormat))
+		}
+	}
+
+	// Two pomodoros fit before the meeting, the rest start right after it.
+	if got := blocks[len(blocks)-5].Start.Format(TimeFormat); got != "10:30" {
+		t.Errorf("third pomodoro should start right after the meeting at 10:30, got %s", got)
+	}
+}
+
+func TestEnforceCadenceFollowsGeneratedOrder(t *testing.T) {
+	tasks := []Task{
+		{Title: "First", Duration: SizeS},
+		{Title: "Second", Duration: SizeS},
+	}
+	generated := []TimeBlock{
+		{Type: BlockTypeFocus, Title: "Second", Start: at("09:00"), End: at("09:15")},
+		{Type: BlockTypeFocus, Title: "First", Start: at("09:30"), End: at("09:45")},
+	}
+
+	blocks, _ := EnforceCadence(generated, tasks, nil, at("09:00"), at("17:00"), testCadence, nil)
+
+	if len(blocks) != 3 {
+		t.Fatalf("got %d blocks, want 3: %v", len(blocks), blocks)
+	}
+	if blocks[0].Title != "Second" || blocks[2].Title != "First" {
+		t.Errorf("expected generated order Second, First; got %q, %q", blocks[0].Title, blocks[2].Title)
+	}
+}
+
+func TestEnforceCadenceReportsUnscheduled(t *testing.T) {
+	tasks := []Task{
+		{Title: "Fits", Duration: SizeS},
+		{Title: "Too late", Duration: SizeS},
+	}
+
+	blocks, unscheduled := EnforceCadence(nil, tasks, nil, at("16:00"), at("16:40"), testCadence, nil)
+
+	if len(blocks) != 1 {
+		t.Errorf("got %d blocks, want 1", len(blocks))
+	}
+	if len(unscheduled) != 1 || unscheduled[0].Title != "Too late" {
+		t.Errorf("unscheduled = %v, want [Too late]", unscheduled)
+	}
+}
+
+func TestFreeWindows(t *testing.T) {
+	busy := []TimeBlock{
+		{Start: at("12:00"), End: at("13:00")},
+		{Start: at("08:00"), End: at("09:30")},
+		{Start: at("12:30"), End: at("13:30")},
+		{Start: at("16:30"), End: at("18:00")},
+	}
+
+	windows := FreeWindows(busy, at("09:00"), at("17:00"))
+
+	expected := [][2]string{{"09:30", "12:00"}, {"13:30", "16:30"}}
+	if len(windows) != len(expected) {
+		t.Fatalf("got %d windows, want %d: %v", len(windows), len(expected), windows)
+	}
+	for i, want := range expected {
+		if windows[i].Start.Format(TimeFormat) != want[0] || windows[i].End.Format(TimeFormat) != want[1] {
+			t.Errorf("window %d = %s-%s, want %s-%s", i,
+				windows[i].Start.Format(TimeFormat), windows[i].End.Format(TimeFormat), want[0], want[1])
+		}
+	}
+}
+
+func TestEnforceCadenceHonorsEnergyProfile(t *testing.T) {
+	tasks := []Task{
+		{Title: "Email", Duration: SizeS, Kind: TaskKindShallow},
+		{Title: "Design", Duration: SizeS, Kind: TaskKindDeep},
+		{Title: "Reviews", Duration: SizeS, Kind: TaskKindShallow},
+		{Title: "Prototype", Duration: SizeS, Kind: TaskKindDeep},
+	}
+	energy := EnergyProfile{
+		{Level: EnergyPeak, Start: at("09:00"), End: at("10:00")},
+		{Level: EnergySlump, Start: at("13:00"), End: at("14:00")},
+	}
+	busy := []TimeBlock{
+		{Type: BlockTypeLunch, Title: "Lunch", Start: at("10:00"), End: at("13:00")},
+	}
+
+	blocks, _ := EnforceCadence(nil, tasks, busy, at("09:00"), at("17:00"), testCadence, energy)
+
+	var order []string
+	for _, b := range blocks {
+		if b.Type == BlockTypeFocus {
+			order = append(order, b.Title)
+		}
+	}
+	expected := []string{"Design", "Prototype", "Email", "Reviews"}
+	if len(order) != len(expected) {
+		t.Fatalf("got focus blocks %v, want %v", order, expected)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("got focus blocks %v, want %v", order, expected)
+			break
+		}
+	}
+}
+
+func TestEnergyProfileLevelAt(t *testing.T) {
+	energy := EnergyProfile{
+		{Level: EnergyPeak, Start: at("09:00"), End: at("11:00")},
+		{Level: EnergyWindDown, Start: at("16:00"), End: at("17:00")},
+	}
+
+	tests := []struct {
+		time     string
+		expected string
+	}{
+		{"08:59", ""},
+		{"09:00", EnergyPeak},
+		{"10:59", EnergyPeak},
+		{"11:00", ""},
+		{"16:30", EnergyWindDown},
+	}
+
+	for _, tt := range tests {
+		if got := energy.LevelAt(at(tt.time)); got != tt.expected {
+			t.Errorf("LevelAt(%s) = %q, want %q", tt.time, got, tt.expected)
+		}
+	}
+}
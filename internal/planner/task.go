@@ -13,6 +13,11 @@ const (
 	SizeXL = 90 * time.Minute
 )
 
+const (
+	TaskKindDeep    = "deep"
+	TaskKindShallow = "shallow"
+)
+
 var taskSizes = map[string]time.Duration{
 	"XS": SizeXS,
 	"S":  SizeS,
@@ -24,8 +29,18 @@ var taskSizes = map[string]time.Duration{
 type Task struct {
 	Title    string
 	Duration time.Duration
+	Kind     string
+	// After and Before constrain when the task is planned, as HH:MM. They are
+	// empty when the task can go anywhere in the day.
+	After  string
+	Before string
+	// Size is the T-shirt size the task was given when ScaleSizes changed its
+	// Duration. It is empty for unscaled tasks.
+	Size string
 }
 
+// ParseTaskList parses a comma-separated task list. Each task may carry a T-shirt
+// size and a deep/shallow kind after colons, in any order: "Write RFC:L:deep".
 func ParseTaskList(tasksStr string) []Task {
 	parts := strings.Split(tasksStr, ",")
 	tasks := make([]Task, 0, len(parts))
@@ -36,28 +51,65 @@ func ParseTaskList(tasksStr string) []Task {
 			continue
 		}
 
-		title, duration := parseTask(trimmed)
-		tasks = append(tasks, Task{
-			Title:    title,
-			Duration: duration,
-		})
+		tasks = append(tasks, parseTask(trimmed))
 	}
 
 	return tasks
 }
 
-func parseTask(taskStr string) (string, time.Duration) {
+func parseTask(taskStr string) Task {
 	parts := strings.Split(taskStr, ":")
-	if len(parts) == 1 {
-		return strings.TrimSpace(parts[0]), SizeM
+	task := Task{
+		Title:    strings.TrimSpace(parts[0]),
+		Duration: SizeM,
+	}
+
+	for _, part := range parts[1:] {
+		tag := strings.TrimSpace(part)
+		if duration, ok := taskSizes[strings.ToUpper(tag)]; ok {
+			task.Duration = duration
+			continue
+		}
+		if kind := strings.ToLower(tag); kind == TaskKindDeep || kind == TaskKindShallow {
+			task.Kind = kind
+			continue
+		}
+		return Task{Title: taskStr, Duration: SizeM}
 	}
 
-	title := strings.TrimSpace(parts[0])
-	sizeStr := strings.TrimSpace(strings.ToUpper(parts[1]))
+	return task
+}
+
+// SizeDuration returns the duration of a T-shirt size such as "M".
+func SizeDuration(size string) (time.Duration, bool) {
+	d, ok := taskSizes[strings.ToUpper(size)]
+	return d, ok
+}
 
-	if duration, ok := taskSizes[sizeStr]; ok {
-		return title, duration
+// SizeOf returns the T-shirt size whose duration is d, or "" if d is not a standard size.
+func SizeOf(d time.Duration) string {
+	for name, size := range taskSizes {
+		if size == d {
+			return name
+		}
 	}
+	return ""
+}
 
-	return taskStr, SizeM
+// ScaleSizes returns a copy of tasks where every task with a standard T-shirt size
+// duration is multiplied by the factor for its size, rounded to 5 minutes.
+// Tasks with other durations and sizes without a factor are left unchanged.
+// Scaled tasks keep their original size in Size.
+func ScaleSizes(tasks []Task, factors map[string]float64) []Task {
+	scaled := make([]Task, len(tasks))
+	for i, task := range tasks {
+		scaled[i] = task
+		size := SizeOf(task.Duration)
+		if factor, ok := factors[size]; ok && factor > 0 {
+			d := time.Duration(float64(task.Duration) * factor).Round(5 * time.Minute)
+			scaled[i].Duration = max(d, 5*time.Minute)
+			scaled[i].Size = size
+		}
+	}
+	return scaled
 }
@@ -171,3 +171,112 @@ func TestParseTaskSizes(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTaskKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Task
+	}{
+		{"size and kind", "Write RFC:L:deep", Task{Title: "Write RFC", Duration: SizeL, Kind: TaskKindDeep}},
+		{"kind and size", "Email:shallow:XS", Task{Title: "Email", Duration: SizeXS, Kind: TaskKindShallow}},
+		{"kind only defaults to M", "Reviews:Shallow", Task{Title: "Reviews", Duration: SizeM, Kind: TaskKindShallow}},
+		{"unknown tag keeps whole title", "Write RFC:L:urgent", Task{Title: "Write RFC:L:urgent", Duration: SizeM}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseTaskList(tt.input)
+			if len(result) != 1 {
+				t.Fatalf("Expected 1 task, got %d", len(result))
+			}
+			if !reflect.DeepEqual(result[0], tt.expected) {
+				t.Errorf("ParseTaskList(%q) = %v, want %v", tt.input, result[0], tt.expected)
+			}
+		})
+	}
+}
+
+func TestScaleSizes(t *testing.T) {
+	tasks := []Task{
+		{Title: "A", Duration: SizeM},
+		{Title: "B", Duration: SizeL},
+		{Title: "C", Duration: 45 * time.Minute},
+		{Title: "D", Duration: SizeXS},
+	}
+	factors := map[string]float64{"M": 1.3, "XS": 0.2, "S": 2}
+
+	got := ScaleSizes(tasks, factors)
+	want := []time.Duration{40 * time.Minute, SizeL, 45 * time.Minute, 5 * time.Minute}
+	for i, task := range got {
+		if task.Duration != want[i] {
+			t.Errorf("%s: Duration = %v, want %v", task.Title, task.Duration, want[i])
+		}
+	}
+	if got[0].Size != "M" || got[1].Size != "" || got[2].Size != "" {
+		t.Errorf("expected only scaled tasks to keep their size, got %+v", got)
+	}
+	if tasks[0].Duration != SizeM {
+		t.Errorf("ScaleSizes() must not modify its input")
+	}
+	if SizeOf(SizeXL) != "XL" || SizeOf(45*time.Minute) != "" {
+		t.Errorf("unexpected SizeOf results")
+	}
+}
+
+func TestParseNaturalTasks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Task
+	}{
+		{
+			name:  "durations and constraints",
+			input: "finish the RFC (2h, before lunch), review 3 PRs, call Bob after 3pm",
+			expected: []Task{
+				{Title: "Finish the RFC", Duration: 2 * time.Hour, Before: "12:00"},
+				{Title: "Review 3 PRs", Duration: SizeM},
+				{Title: "Call Bob", Duration: SizeM, After: "15:00"},
+			},
+		},
+		{
+			name:  "minutes and mixed units",
+			input: "inbox 45m; deploy 1h30 after 15:00\nwrite tests for 90 min by 11",
+			expected: []Task{
+				{Title: "Inbox", Duration: 45 * time.Minute},
+				{Title: "Deploy", Duration: 90 * time.Minute, After: "15:00"},
+				{Title: "Write tests for", Duration: 90 * time.Minute, Before: "11:00"},
+			},
+		},
+		{
+			name:  "after lunch and afternoon hours",
+			input: "Plan sprint (after lunch), Retro prep before 4",
+			expected: []Task{
+				{Title: "Plan sprint", Duration: SizeM, After: "13:00"},
+				{Title: "Retro prep", Duration: SizeM, Before: "16:00"},
+			},
+		},
+		{
+			name:  "sizes and kinds after colons",
+			input: "Write RFC:L:deep, Inbox:shallow after noon",
+			expected: []Task{
+				{Title: "Write RFC", Duration: SizeL, Kind: TaskKindDeep},
+				{Title: "Inbox", Duration: SizeM, Kind: TaskKindShallow, After: "12:00"},
+			},
+		},
+		{
+			name:     "empty items",
+			input:    " , (2h), ",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseNaturalTasks(tt.input, "12:00", "13:00")
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseNaturalTasks(%q) = %+v, want %+v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
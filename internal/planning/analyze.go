@@ -0,0 +1,312 @@
+package planning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// FreeBusyReader reads the busy time of other people's calendars. It is
+// implemented by calendar.GoogleClient; Analyze uses it when the calendar has it
+// to keep moves within the free time of the attendees.
+type FreeBusyReader interface {
+	FreeBusy(ctx context.Context, calendarIDs []string, start, end time.Time) ([]calendar.FreeBusy, error)
+}
+
+// Fragmentation describes how the free time of a day is split up by meetings,
+// lunch and buffers.
+type Fragmentation struct {
+	Free    time.Duration
+	Largest time.Duration
+	Windows int
+}
+
+// Score returns the share of free time outside the largest free window, from 0
+// when all free time is in one window to 100 when it is cut into small pieces.
+func (f Fragmentation) Score() int {
+	if f.Free <= 0 {
+		return 0
+	}
+	return int(math.Round(100 * float64(f.Free-f.Largest) / float64(f.Free)))
+}
+
+// better reports whether f leaves a larger focus window than g, or the same one
+// with the rest of the free time in fewer pieces.
+func (f Fragmentation) better(g Fragmentation) bool {
+	if f.Largest != g.Largest {
+		return f.Largest > g.Largest
+	}
+	return f.Windows < g.Windows
+}
+
+// Move is a suggested new time for a meeting.
+type Move struct {
+	Meeting calendar.Event
+	Start   time.Time
+	End     time.Time
+}
+
+func (m Move) String() string {
+	return fmt.Sprintf("%s (%s - %s → %s - %s)", m.Meeting.Title,
+		m.Meeting.Start.Format(planner.TimeFormat), m.Meeting.End.Format(planner.TimeFormat),
+		m.Start.Format(planner.TimeFormat), m.End.Format(planner.TimeFormat))
+}
+
+// Analysis is the result of looking for meeting moves that cluster the meetings
+// of a day.
+type Analysis struct {
+	// Movable lists the meetings that may be moved.
+	Movable []calendar.Event
+	// Moves lists the suggested moves in the order of the meetings.
+	Moves  []Move
+	Before Fragmentation
+	After  Fragmentation
+	// Unchecked lists the attendees of moved meetings whose availability could
+	// not be read, so the new times may not suit them.
+	Unchecked []string
+}
+
+// Analyze suggests moves for the meetings of day that the user organizes or that
+// are tagged as movable, so that meetings are clustered and the largest free
+// window of the day grows. Moves stay within work hours and never overlap lunch,
+// other meetings or the busy time of the meeting's attendees, as far as it can
+// be read. Meetings that already started stay where they are and nothing is moved
+// into the past. Nothing is changed on the calendar; see ApplyMoves.
+func (s *Service) Analyze(ctx context.Context, day *Day) (*Analysis, error) {
+	earliest := day.WorkStart
+	if now := s.now(); now.After(earliest) {
+		earliest = now
+	}
+
+	fixed := append([]planner.TimeBlock{lunchBlock(day)}, day.Routines...)
+	a := &Analysis{}
+	for _, e := range day.Meetings {
+		switch {
+		case e.Managed:
+			continue
+		case s.isMovable(e) && !e.Start.Before(earliest) && !e.End.After(day.WorkEnd):
+			a.Movable = append(a.Movable, e)
+		default:
+			fixed = append(fixed, e.ToTimeBlock())
+		}
+	}
+
+	attendeeBusy, unread, err := s.attendeeBusy(ctx, day, a.Movable)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]planner.TimeBlock, len(a.Movable))
+	for i, e := range a.Movable {
+		blocks[i] = e.ToTimeBlock()
+	}
+	a.Before = s.fragmentation(day, append(slices.Clone(fixed), blocks...))
+
+	// Move one meeting at a time to the position that helps most, until no move
+	// helps. Ties go to the move closest to the meeting's original time.
+	current := a.Before
+	for range 2 * len(blocks) {
+		best, bestIndex, bestBlock := current, -1, planner.TimeBlock{}
+		for i, b := range blocks {
+			others := append(slices.Clone(fixed), slices.Delete(slices.Clone(blocks), i, i+1)...)
+			for _, start := range s.candidateStarts(day, b, slices.Concat(others, attendeeBusy[i])) {
+				moved := b
+				moved.Start, moved.End = start, start.Add(b.End.Sub(b.Start))
+				if moved.Start.Equal(b.Start) || moved.Start.Before(earliest) || moved.End.After(day.WorkEnd) ||
+					overlapsAny(moved, others) || overlapsAny(moved, attendeeBusy[i]) {
+					continue
+				}
+				f := s.fragmentation(day, append(others, moved))
+				closer := bestIndex >= 0 && !best.better(f) &&
+					displacement(moved, a.Movable[i]) < displacement(bestBlock, a.Movable[bestIndex])
+				if f.better(best) || closer {
+					best, bestIndex, bestBlock = f, i, moved
+				}
+			}
+		}
+		if bestIndex < 0 {
+			break
+		}
+		blocks[bestIndex], current = bestBlock, best
+	}
+
+	for i, e := range a.Movable {
+		if blocks[i].Start.Equal(e.Start) {
+			continue
+		}
+		a.Moves = append(a.Moves, Move{Meeting: e, Start: blocks[i].Start, End: blocks[i].End})
+		for _, attendee := range e.Attendees {
+			if unread[attendee] && !slices.Contains(a.Unchecked, attendee) {
+				a.Unchecked = append(a.Unchecked, attendee)
+			}
+		}
+	}
+	slices.Sort(a.Unchecked)
+	a.After = current
+	return a, nil
+}
+
+// attendeeBusy returns the busy time of the attendees of each meeting, apart from
+// the meeting itself, and the attendees whose busy time could not be read. All
+// attendees are unread when the calendar cannot read other calendars.
+func (s *Service) attendeeBusy(ctx context.Context, day *Day, meetings []calendar.Event) ([][]planner.TimeBlock, map[string]bool, error) {
+	busy := make([][]planner.TimeBlock, len(meetings))
+	unread := make(map[string]bool)
+
+	var ids []string
+	for _, m := range meetings {
+		for _, attendee := range m.Attendees {
+			if !slices.Contains(ids, attendee) {
+				ids = append(ids, attendee)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return busy, unread, nil
+	}
+	reader, ok := s.calendar.(FreeBusyReader)
+	if !ok {
+		for _, id := range ids {
+			unread[id] = true
+		}
+		return busy, unread, nil
+	}
+
+	result, err := reader.FreeBusy(ctx, ids, day.WorkStart, day.WorkEnd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check attendee availability: %w", err)
+	}
+	byID := make(map[string][]planner.TimeBlock, len(result))
+	for _, fb := range result {
+		if fb.Err != nil {
+			unread[fb.CalendarID] = true
+			continue
+		}
+		byID[fb.CalendarID] = fb.Busy
+	}
+	for i, m := range meetings {
+		for _, attendee := range m.Attendees {
+			for _, b := range byID[attendee] {
+				busy[i] = append(busy[i], without(b, m.ToTimeBlock())...)
+			}
+		}
+	}
+	return busy, unread, nil
+}
+
+// without returns the parts of b outside of cut. Free/busy merges back-to-back
+// events, so a meeting is cut out of the busy time instead of skipped.
+func without(b, cut planner.TimeBlock) []planner.TimeBlock {
+	if !b.Start.Before(cut.End) || !b.End.After(cut.Start) {
+		return []planner.TimeBlock{b}
+	}
+	var parts []planner.TimeBlock
+	if b.Start.Before(cut.Start) {
+		before := b
+		before.End = cut.Start
+		parts = append(parts, before)
+	}
+	if b.End.After(cut.End) {
+		after := b
+		after.Start = cut.End
+		parts = append(parts, after)
+	}
+	return parts
+}
+
+// ApplyMoves moves the meetings the user organizes on the calendar, which sends
+// their attendees an update. Moves of meetings organized by someone else are not
+// applied, since only the organizer can move a meeting for everyone; they are
+// returned as skipped. When a move fails, the meetings moved so far are moved
+// back, and the error names any that could not be.
+func (s *Service) ApplyMoves(ctx context.Context, moves []Move) ([]Move, error) {
+	var skipped, moved []Move
+	for _, m := range moves {
+		if !m.Meeting.Organized {
+			skipped = append(skipped, m)
+			continue
+		}
+		if err := s.calendar.MoveEvent(ctx, s.cfg.Calendar, m.Meeting.ID, m.Start, m.End); err != nil {
+			err = fmt.Errorf("failed to move %s: %w", m.Meeting.Title, err)
+			if len(moved) == 0 {
+				return skipped, err
+			}
+			if rbErr := s.moveBack(ctx, moved); rbErr != nil {
+				return skipped, errors.Join(err, rbErr)
+			}
+			return skipped, fmt.Errorf("%w (%d moved meeting(s) were moved back)", err, len(moved))
+		}
+		moved = append(moved, m)
+	}
+	return skipped, nil
+}
+
+// moveBack returns moved meetings to their original times. Like rollback, it
+// keeps going after a cancellation of ctx.
+func (s *Service) moveBack(ctx context.Context, moved []Move) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, m := range moved {
+		if err := s.calendar.MoveEvent(ctx, s.cfg.Calendar, m.Meeting.ID, m.Meeting.Start, m.Meeting.End); err != nil {
+			errs = append(errs, fmt.Errorf("failed to move %s back, it is still at %s - %s: %w", m.Meeting.Title,
+				m.Start.Format(planner.TimeFormat), m.End.Format(planner.TimeFormat), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isMovable reports whether e is an ordinary meeting the user organizes or one
+// tagged as movable.
+func (s *Service) isMovable(e calendar.Event) bool {
+	if e.EventType != "" && e.EventType != calendar.EventTypeDefault {
+		return false
+	}
+	tag := strings.ToLower(s.cfg.Analyze.Tag())
+	return e.Organized || strings.Contains(strings.ToLower(e.Title+" "+e.Description), tag)
+}
+
+// candidateStarts returns the start times that put b right next to another busy
+// block, with or without room for buffers, or at either end of the work hours.
+func (s *Service) candidateStarts(day *Day, b planner.TimeBlock, others []planner.TimeBlock) []time.Time {
+	length := b.End.Sub(b.Start)
+	gap := s.buffers().Before + s.buffers().After
+	starts := []time.Time{day.WorkStart, day.WorkEnd.Add(-length)}
+	for _, o := range others {
+		starts = append(starts, o.End, o.Start.Add(-length))
+		if gap > 0 {
+			starts = append(starts, o.End.Add(gap), o.Start.Add(-length-gap))
+		}
+	}
+	return starts
+}
+
+// fragmentation returns how the work hours of day are split up by busy, with
+// meeting buffers applied.
+func (s *Service) fragmentation(day *Day, busy []planner.TimeBlock) Fragmentation {
+	var f Fragmentation
+	busy = planner.ApplyBuffers(busy, s.buffers(), day.WorkStart, day.WorkEnd)
+	for _, w := range planner.FreeWindows(busy, day.WorkStart, day.WorkEnd) {
+		length := w.End.Sub(w.Start)
+		f.Free += length
+		f.Largest = max(f.Largest, length)
+		f.Windows++
+	}
+	return f
+}
+
+func displacement(b planner.TimeBlock, e calendar.Event) time.Duration {
+	d := b.Start.Sub(e.Start)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,197 @@
+package planning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+const (
+	// maxConcurrentWrites bounds the number of calendar inserts in flight.
+	maxConcurrentWrites = 4
+	// rollbackTimeout limits the cleanup after a failed or canceled apply.
+	rollbackTimeout = 30 * time.Second
+)
+
+// ErrSkipped is the error of blocks that were not created because an earlier
+// insert had already failed.
+var ErrSkipped = errors.New("skipped after an earlier failure")
+
+// ApplyOptions controls how a plan is written to the calendar.
+type ApplyOptions struct {
+	// KeepPartial leaves already created events in the calendar when some
+	// inserts fail, instead of deleting them.
+	KeepPartial bool
+}
+
+// Transaction is the log of the calendar writes made for a plan.
+type Transaction struct {
+	CalendarID string
+	// Entries has one entry per plan block, in plan order.
+	Entries []TransactionEntry
+}
+
+// TransactionEntry records what happened to one block.
+type TransactionEntry struct {
+	Block planner.TimeBlock
+	// Event is the event sent to the calendar. Event.ID is set once it was created.
+	Event calendar.Event
+	// Err is the insert error, if the block could not be created. It is
+	// ErrSkipped for blocks not attempted after an earlier failure.
+	Err error
+	// Interrupted is set when the insert was canceled while its request was in
+	// flight. The calendar may have created the event anyway; its ID is unknown,
+	// so it cannot be rolled back.
+	Interrupted bool
+	// RolledBack is set when the created event was deleted again.
+	RolledBack bool
+	// Reused is set when an existing event was kept or moved for the block
+	// instead of creating one.
+	Reused bool
+}
+
+// Created returns the entries whose events are in the calendar.
+func (t *Transaction) Created() []TransactionEntry {
+	var created []TransactionEntry
+	for _, e := range t.Entries {
+		if e.Event.ID != "" && !e.RolledBack {
+			created = append(created, e)
+		}
+	}
+	return created
+}
+
+// Failed returns the entries whose insert failed.
+func (t *Transaction) Failed() []TransactionEntry {
+	var failed []TransactionEntry
+	for _, e := range t.Entries {
+		if e.Err != nil && !errors.Is(e.Err, ErrSkipped) {
+			failed = append(failed, e)
+		}
+	}
+	return failed
+}
+
+// Skipped returns the entries that were not attempted after an earlier failure.
+func (t *Transaction) Skipped() []TransactionEntry {
+	var skipped []TransactionEntry
+	for _, e := range t.Entries {
+		if errors.Is(e.Err, ErrSkipped) {
+			skipped = append(skipped, e)
+		}
+	}
+	return skipped
+}
+
+// Interrupted returns the entries whose insert was canceled in flight.
+func (t *Transaction) Interrupted() []TransactionEntry {
+	var interrupted []TransactionEntry
+	for _, e := range t.Entries {
+		if e.Interrupted {
+			interrupted = append(interrupted, e)
+		}
+	}
+	return interrupted
+}
+
+// Apply creates the plan's blocks in the calendar, a few at a time. If any insert
+// fails, no new inserts are started and the events created so far are deleted
+// again, unless opts.KeepPartial is set; then the other blocks are still created.
+// The returned transaction always describes what is left in the calendar.
+func (s *Service) Apply(ctx context.Context, plan *Plan, opts ApplyOptions) (*Transaction, error) {
+	tx := &Transaction{
+		CalendarID: s.cfg.Calendar,
+		Entries:    make([]TransactionEntry, len(plan.Blocks)),
+	}
+
+	// stop is canceled on the first failure. Inserts already in flight keep ctx,
+	// so that their events are known and can be rolled back.
+	stop, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentWrites)
+	for i, block := range plan.Blocks {
+		entry := &tx.Entries[i]
+		entry.Block = block
+		entry.Event = ToCalendarEvent(block, s.cfg)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-stop.Done():
+			}
+
+			if err := ctx.Err(); err != nil {
+				entry.Err = err
+				return
+			}
+			if stop.Err() != nil {
+				entry.Err = ErrSkipped
+				return
+			}
+			id, err := s.calendar.CreateEvent(ctx, tx.CalendarID, entry.Event)
+			if err != nil {
+				entry.Err = err
+				entry.Interrupted = ctx.Err() != nil
+				if !opts.KeepPartial {
+					cancel()
+				}
+				return
+			}
+			entry.Event.ID = id
+		}()
+	}
+	wg.Wait()
+
+	failed := tx.Failed()
+	if len(failed) == 0 {
+		return tx, nil
+	}
+
+	err := fmt.Errorf("failed to create %d of %d block(s), first '%s': %w",
+		len(failed), len(tx.Entries), failed[0].Block.Title, failed[0].Err)
+	if skipped := len(tx.Skipped()); skipped > 0 {
+		err = fmt.Errorf("%w; %d more skipped", err, skipped)
+	}
+	if interrupted := len(tx.Interrupted()); interrupted > 0 {
+		err = fmt.Errorf("%w; %d block(s) canceled mid-request may still be in the calendar", err, interrupted)
+	}
+	if opts.KeepPartial {
+		return tx, err
+	}
+
+	if rbErr := s.rollback(ctx, tx); rbErr != nil {
+		return tx, errors.Join(err, rbErr)
+	}
+	return tx, fmt.Errorf("%w (created blocks were removed again)", err)
+}
+
+// rollback deletes the events created by tx. It keeps going after a cancellation
+// of ctx so that an interrupted run does not leave half a plan behind.
+func (s *Service) rollback(ctx context.Context, tx *Transaction) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := range tx.Entries {
+		entry := &tx.Entries[i]
+		if entry.Event.ID == "" {
+			continue
+		}
+		if err := s.calendar.DeleteEvent(ctx, tx.CalendarID, entry.Event.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to roll back '%s': %w", entry.Block.Title, err))
+			continue
+		}
+		entry.RolledBack = true
+	}
+	return errors.Join(errs...)
+}
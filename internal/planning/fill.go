@@ -0,0 +1,105 @@
+package planning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// ErrNothingToFill is returned by Fill when the blocks outside the window
+// already cover all tasks.
+var ErrNothingToFill = errors.New("all tasks are already scheduled")
+
+// Fill asks the planner for new blocks between start and end only, for example to
+// regenerate one gap of a plan that was adjusted by hand. Blocks outside the window
+// are kept as busy time and the tasks they cover are shortened accordingly. Modes
+// that enforce a cadence get it in the window too. It returns the new blocks of
+// the window.
+func (s *Service) Fill(ctx context.Context, day *Day, mode config.Mode, tasks []planner.Task, blocks []planner.TimeBlock, start, end time.Time) ([]planner.TimeBlock, error) {
+	busy := slices.Clone(day.Busy)
+	var kept []planner.TimeBlock
+	for _, b := range blocks {
+		if b.Start.Before(end) && b.End.After(start) {
+			continue
+		}
+		kept = append(kept, b)
+	}
+	busy = append(busy, kept...)
+
+	remaining := remainingTasks(tasks, kept)
+	if len(remaining) == 0 {
+		return nil, ErrNothingToFill
+	}
+
+	resp, err := s.planner.GeneratePlan(ctx, ai.PlanRequest{
+		WorkStart:  start,
+		WorkEnd:    end,
+		BusyBlocks: busy,
+		Tasks:      remaining,
+		Mode:       mode,
+		Energy:     day.Energy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.Validate(); err != nil {
+		return nil, fmt.Errorf("AI returned an invalid plan: %w", err)
+	}
+	generated, err := ParseBlocks(resp.Blocks, day.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AI blocks: %w", err)
+	}
+
+	// The planner sees the whole day, so drop anything it put outside the window
+	// or on top of busy time
+	var filled []planner.TimeBlock
+	for _, b := range generated {
+		if b.Start.Before(start) || b.End.After(end) {
+			continue
+		}
+		if slices.ContainsFunc(busy, func(o planner.TimeBlock) bool { return b.Start.Before(o.End) && b.End.After(o.Start) }) {
+			continue
+		}
+		filled = append(filled, b)
+	}
+	if mode.EnforceCadence {
+		filled, _ = planner.EnforceCadence(filled, remaining, busy, start, end, cadence(mode), day.Energy)
+	}
+	return filled, nil
+}
+
+// cadence returns the focus/break rhythm of a mode that enforces one.
+func cadence(mode config.Mode) planner.Cadence {
+	return planner.Cadence{
+		Focus:          mode.MaxFocus(),
+		Break:          mode.Break(),
+		LongBreak:      mode.LongBreak(),
+		LongBreakEvery: mode.LongBreakEvery,
+	}
+}
+
+// remainingTasks returns the tasks with the time already planned for them in
+// blocks subtracted. Tasks that are fully planned are left out.
+func remainingTasks(tasks []planner.Task, blocks []planner.TimeBlock) []planner.Task {
+	planned := map[string]time.Duration{}
+	for _, b := range blocks {
+		if b.Type == planner.BlockTypeFocus {
+			planned[planner.TaskTitle(b.Title)] += b.End.Sub(b.Start)
+		}
+	}
+
+	var remaining []planner.Task
+	for _, t := range tasks {
+		if left := t.Duration - planned[t.Title]; left > 0 {
+			t.Duration = left
+			remaining = append(remaining, t)
+		}
+	}
+	return remaining
+}
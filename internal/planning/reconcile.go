@@ -0,0 +1,263 @@
+package planning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// Kinds of changes made by Reconcile.
+const (
+	ChangeMoved   = "moved"
+	ChangeShrunk  = "shrunk"
+	ChangeRemoved = "removed"
+)
+
+// reconcileStep is the granularity of the current time when reconciling today.
+const reconcileStep = 5 * time.Minute
+
+// minBlockLength is the shortest a block is shrunk to before it is removed instead.
+var minBlockLength = map[string]time.Duration{
+	planner.BlockTypeFocus: 15 * time.Minute,
+	planner.BlockTypeBreak: 5 * time.Minute,
+}
+
+// Change is one adjustment of a block created by Barely In Charge.
+type Change struct {
+	Kind string
+	// Event is the block as it was in the calendar.
+	Event calendar.Event
+	// Start and End are the new times; they are zero for removed blocks.
+	Start time.Time
+	End   time.Time
+	// Conflict is the title of the meeting the block overlapped, if any.
+	Conflict string
+	// Err is set when the calendar could not be updated.
+	Err error
+}
+
+func (c Change) String() string {
+	from := fmt.Sprintf("%s - %s", c.Event.Start.Format(planner.TimeFormat), c.Event.End.Format(planner.TimeFormat))
+	s := fmt.Sprintf("%s %s %s", c.Kind, EventLabel(c.Event), from)
+	if c.Kind != ChangeRemoved {
+		s += fmt.Sprintf(" → %s - %s", c.Start.Format(planner.TimeFormat), c.End.Format(planner.TimeFormat))
+	}
+	if c.Conflict != "" {
+		s += fmt.Sprintf(" (conflicts with %s)", c.Conflict)
+	}
+	if c.Err != nil {
+		s += fmt.Sprintf(": %v", c.Err)
+	}
+	return s
+}
+
+// EventLabel names a block created by Barely In Charge by its title and task,
+// e.g. "Focus time (Write docs)".
+func EventLabel(e calendar.Event) string {
+	if title := BlockTitle(e); title != e.Title {
+		return fmt.Sprintf("%s (%s)", e.Title, title)
+	}
+	return e.Title
+}
+
+// BlockTitle returns the title of the planned block behind an event created by
+// Barely In Charge. Focus and break events are titled by type and carry the
+// block title at the end of their description.
+func BlockTitle(e calendar.Event) string {
+	if e.Type != planner.BlockTypeFocus && e.Type != planner.BlockTypeBreak {
+		return e.Title
+	}
+	if i := strings.LastIndex(e.Description, ": "); i >= 0 {
+		return e.Description[i+2:]
+	}
+	return e.Title
+}
+
+// ReconcileOptions controls how conflicts are resolved.
+type ReconcileOptions struct {
+	// DryRun computes the changes without updating the calendar.
+	DryRun bool
+}
+
+// Reconcile resolves conflicts between new meetings and the focus and break blocks
+// created by Barely In Charge on date. Only the tail of the day from the first
+// conflicting block on is planned again: blocks keep their order and length and
+// are pushed later past meetings, shrunk when they no longer fit, or removed when
+// too short. Blocks are never moved earlier and blocks in the past are left alone.
+// Lunch and out-of-office blocks stay where they are.
+func (s *Service) Reconcile(ctx context.Context, date time.Time, opts ReconcileOptions) ([]Change, error) {
+	workStart, err := planner.ParseTimeOnDate(s.cfg.WorkHours.Start, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid work start time: %w", err)
+	}
+	workEnd, err := planner.ParseTimeOnDate(s.cfg.WorkHours.End, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid work end time: %w", err)
+	}
+
+	from := workStart
+	if now := s.now(); sameDate(date, now) && now.After(from) {
+		from = ceilTime(now, reconcileStep)
+	}
+	if !from.Before(workEnd) {
+		return nil, nil
+	}
+
+	events, err := s.calendar.FetchMeetings(ctx, s.cfg.Calendar, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch meetings: %w", err)
+	}
+
+	var meetings, busy []planner.TimeBlock
+	var movable []calendar.Event
+	for _, e := range events {
+		switch {
+		case !e.Managed:
+			meetings = append(meetings, e.ToTimeBlock())
+		case e.Type == planner.BlockTypeFocus || e.Type == planner.BlockTypeBreak:
+			movable = append(movable, e)
+		default:
+			busy = append(busy, e.ToTimeBlock())
+		}
+	}
+	busy = append(busy, meetings...)
+	slices.SortStableFunc(meetings, func(a, b planner.TimeBlock) int { return a.Start.Compare(b.Start) })
+	slices.SortStableFunc(movable, func(a, b calendar.Event) int { return a.Start.Compare(b.Start) })
+
+	// The tail starts at the first block still ahead of us that overlaps a meeting
+	tailStart := time.Time{}
+	for _, e := range movable {
+		if e.End.After(from) && conflict(e, meetings) != nil {
+			tailStart = e.Start
+			break
+		}
+	}
+	if tailStart.IsZero() {
+		return nil, nil
+	}
+	if tailStart.Before(from) {
+		tailStart = from
+	}
+
+	var changes []Change
+	var tail []calendar.Event
+	for _, e := range movable {
+		switch {
+		case !e.Start.Before(tailStart):
+			tail = append(tail, e)
+		case e.End.After(tailStart):
+			// In progress: end it when the meeting starts, but not in the past
+			end := e.End
+			if m := conflict(e, meetings); m != nil {
+				end = m.Start
+				if end.Before(from) {
+					end = from
+				}
+				changes = append(changes, Change{Kind: ChangeShrunk, Event: e, Start: e.Start, End: end, Conflict: m.Title})
+			}
+			busy = append(busy, planner.TimeBlock{Type: e.Type, Title: e.Title, Start: e.Start, End: end})
+		default:
+			busy = append(busy, e.ToTimeBlock())
+		}
+	}
+
+	windows := planner.FreeWindows(planner.ApplyBuffers(busy, s.buffers(), tailStart, workEnd), tailStart, workEnd)
+	changes = append(changes, packTail(tail, windows, meetings)...)
+
+	if opts.DryRun {
+		return changes, nil
+	}
+	return changes, s.applyChanges(ctx, changes)
+}
+
+// packTail places the blocks in order in the free windows, never earlier than
+// they were. A block that fits nowhere whole is shrunk into the first window with
+// room for its minimum length, or removed.
+func packTail(tail []calendar.Event, windows []planner.TimeBlock, meetings []planner.TimeBlock) []Change {
+	var changes []Change
+	w := 0
+	cursor := time.Time{}
+
+	for _, e := range tail {
+		length := e.End.Sub(e.Start)
+		change := Change{Kind: ChangeRemoved, Event: e}
+		if m := conflict(e, meetings); m != nil {
+			change.Conflict = m.Title
+		}
+
+		placed := false
+		for i := w; i < len(windows) && !placed; i++ {
+			start := latest(windows[i].Start, cursor, e.Start)
+			if windows[i].End.Sub(start) >= length {
+				change.Start, change.End = start, start.Add(length)
+				w, placed = i, true
+			}
+		}
+		for i := w; i < len(windows) && !placed; i++ {
+			start := latest(windows[i].Start, cursor, e.Start)
+			if windows[i].End.Sub(start) >= minBlockLength[e.Type] {
+				change.Kind = ChangeShrunk
+				change.Start, change.End = start, windows[i].End
+				w, placed = i, true
+			}
+		}
+
+		if placed {
+			cursor = change.End
+			if change.Start.Equal(e.Start) && change.End.Equal(e.End) {
+				continue
+			}
+			if change.Kind != ChangeShrunk {
+				change.Kind = ChangeMoved
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// applyChanges writes the changes to the calendar and records the error of each.
+func (s *Service) applyChanges(ctx context.Context, changes []Change) error {
+	var errs []error
+	for i := range changes {
+		c := &changes[i]
+		if c.Kind == ChangeRemoved {
+			c.Err = s.calendar.DeleteEvent(ctx, s.cfg.Calendar, c.Event.ID)
+		} else {
+			c.Err = s.calendar.MoveEvent(ctx, s.cfg.Calendar, c.Event.ID, c.Start, c.End)
+		}
+		if c.Err != nil {
+			errs = append(errs, fmt.Errorf("failed to update '%s': %w", EventLabel(c.Event), c.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// conflict returns the first meeting that overlaps e.
+func conflict(e calendar.Event, meetings []planner.TimeBlock) *planner.TimeBlock {
+	for i, m := range meetings {
+		if e.Start.Before(m.End) && e.End.After(m.Start) {
+			return &meetings[i]
+		}
+	}
+	return nil
+}
+
+func latest(times ...time.Time) time.Time {
+	return slices.MaxFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+}
+
+// ceilTime rounds t up to a multiple of d.
+func ceilTime(t time.Time, d time.Duration) time.Time {
+	if r := t.Truncate(d); !r.Equal(t) {
+		return r.Add(d)
+	}
+	return t
+}
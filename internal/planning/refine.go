@@ -0,0 +1,133 @@
+package planning
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// Kinds of block differences between two plans.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffMoved   = "moved"
+)
+
+// Refine asks the planner to change plan as instruction says and returns the new
+// plan. The answer is checked like a generated plan and, as the planner saw the
+// earlier plan, also rejected when a block falls outside work hours or overlaps
+// busy time or another block. plan is left unchanged either way.
+func (s *Service) Refine(ctx context.Context, plan *Plan, instruction string) (*Plan, error) {
+	refiner, ok := s.planner.(Refiner)
+	if !ok || plan.Response == nil || plan.Response.Conversation == nil {
+		return nil, fmt.Errorf("the planner does not support changing a plan")
+	}
+
+	resp, err := refiner.RefinePlan(ctx, plan.Response.Conversation, instruction)
+	if err != nil {
+		return nil, err
+	}
+	refined, err := s.newPlan(plan.Day, plan.Mode, plan.Tasks, resp)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFits(refined.Day, refined.Generated); err != nil {
+		return nil, fmt.Errorf("AI returned an invalid plan: %w", err)
+	}
+	return refined, nil
+}
+
+// checkFits returns an error for the first planned block outside the work hours
+// of day, over its busy time or over another block. Suggestions are left out, as
+// Generate drops the ones that do not fit.
+func checkFits(day *Day, blocks []planner.TimeBlock) error {
+	var planned []planner.TimeBlock
+	for _, b := range blocks {
+		if b.Suggested {
+			continue
+		}
+		span := fmt.Sprintf("%s (%s - %s)", b.Title, b.Start.Format(planner.TimeFormat), b.End.Format(planner.TimeFormat))
+		if b.Start.Before(day.WorkStart) || b.End.After(day.WorkEnd) {
+			return fmt.Errorf("%s is outside work hours", span)
+		}
+		overlaps := func(o planner.TimeBlock) bool { return b.Start.Before(o.End) && b.End.After(o.Start) }
+		if i := slices.IndexFunc(day.Busy, overlaps); i >= 0 {
+			return fmt.Errorf("%s overlaps %s", span, day.Busy[i].Title)
+		}
+		if i := slices.IndexFunc(planned, overlaps); i >= 0 {
+			return fmt.Errorf("%s overlaps %s", span, planned[i].Title)
+		}
+		planned = append(planned, b)
+	}
+	return nil
+}
+
+// BlockDiff is a block that differs between two plans.
+type BlockDiff struct {
+	Kind string
+	// Before is the block in the old plan; it is zero for added blocks.
+	Before planner.TimeBlock
+	// After is the block in the new plan; it is zero for removed blocks.
+	After planner.TimeBlock
+}
+
+func (d BlockDiff) String() string {
+	span := func(b planner.TimeBlock) string {
+		return fmt.Sprintf("%s - %s", b.Start.Format(planner.TimeFormat), b.End.Format(planner.TimeFormat))
+	}
+	switch d.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("+ %s %s (%s)", d.After.Type, d.After.Title, span(d.After))
+	case DiffRemoved:
+		return fmt.Sprintf("- %s %s (%s)", d.Before.Type, d.Before.Title, span(d.Before))
+	default:
+		return fmt.Sprintf("~ %s %s (%s → %s)", d.After.Type, d.After.Title, span(d.Before), span(d.After))
+	}
+}
+
+// DiffBlocks lists the blocks added, removed and moved from before to after. A
+// block is matched with the first block of the other plan with the same type and
+// title, so a task split in two shows one moved and one added block. The result is
+// in time order; blocks that did not change are left out.
+func DiffBlocks(before, after []planner.TimeBlock) []BlockDiff {
+	used := make([]bool, len(before))
+	var diffs []BlockDiff
+	for _, b := range after {
+		i := matchBlock(before, used, b)
+		if i < 0 {
+			diffs = append(diffs, BlockDiff{Kind: DiffAdded, After: b})
+			continue
+		}
+		used[i] = true
+		if !before[i].Start.Equal(b.Start) || !before[i].End.Equal(b.End) {
+			diffs = append(diffs, BlockDiff{Kind: DiffMoved, Before: before[i], After: b})
+		}
+	}
+	for i, b := range before {
+		if !used[i] {
+			diffs = append(diffs, BlockDiff{Kind: DiffRemoved, Before: b})
+		}
+	}
+
+	at := func(d BlockDiff) planner.TimeBlock {
+		if d.Kind == DiffRemoved {
+			return d.Before
+		}
+		return d.After
+	}
+	slices.SortStableFunc(diffs, func(a, b BlockDiff) int { return at(a).Start.Compare(at(b).Start) })
+	return diffs
+}
+
+// matchBlock returns the index of the first unused block with the type and title
+// of block, or -1.
+func matchBlock(blocks []planner.TimeBlock, used []bool, block planner.TimeBlock) int {
+	for i, b := range blocks {
+		if !used[i] && b.Type == block.Type && b.Title == block.Title {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,173 @@
+package planning
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// Replan is a day being planned again from a point in time. Blocks created by
+// Barely In Charge that started before From are kept; the ones starting later are
+// replaced by the new plan.
+type Replan struct {
+	Day  *Day
+	From time.Time
+	// Kept holds the past and in-progress blocks, in calendar order.
+	Kept []calendar.Event
+	// Replaced holds the future blocks, in calendar order.
+	Replaced []calendar.Event
+}
+
+// Elapsed returns the kept focus blocks that were over by From.
+func (r *Replan) Elapsed() []calendar.Event {
+	var elapsed []calendar.Event
+	for _, e := range r.Kept {
+		if e.Type == planner.BlockTypeFocus && !e.End.After(r.From) {
+			elapsed = append(elapsed, e)
+		}
+	}
+	return elapsed
+}
+
+// Remaining returns what is left of tasks after the kept focus blocks. Tasks in
+// done are left out entirely; the others are shortened by the focus time already
+// spent or in progress on them.
+func (r *Replan) Remaining(tasks []planner.Task, done map[string]bool) []planner.Task {
+	var open []planner.Task
+	for _, t := range tasks {
+		if !done[t.Title] {
+			open = append(open, t)
+		}
+	}
+
+	kept := make([]planner.TimeBlock, 0, len(r.Kept))
+	for _, e := range r.Kept {
+		block := e.ToTimeBlock()
+		block.Title = BlockTitle(e)
+		kept = append(kept, block)
+	}
+	return remainingTasks(open, kept)
+}
+
+// PrepareReplan prepares date for planning again from from on. The blocks created
+// by Barely In Charge that start at or after from are left out of the busy time so
+// the planner can use their slots. A from in the past of today is moved up to the
+// current time, so that blocks already over are kept.
+func (s *Service) PrepareReplan(ctx context.Context, date, from time.Time) (*Replan, error) {
+	day, err := s.PrepareDay(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+
+	if day.StartAdjusted && from.Before(day.WorkStart) {
+		from = day.WorkStart
+	}
+	if start := ceilTime(from, reconcileStep); start.After(day.WorkStart) {
+		day.WorkStart = start
+		day.StartAdjusted = true
+	}
+	if !day.WorkStart.Before(day.WorkEnd) {
+		return nil, fmt.Errorf("no time left in work day to plan after %s", from.Format(planner.TimeFormat))
+	}
+
+	r := &Replan{Day: day, From: from}
+	var meetings []calendar.Event
+	for _, e := range day.Meetings {
+		switch {
+		case !e.Managed:
+			meetings = append(meetings, e)
+		case e.Start.Before(from):
+			r.Kept = append(r.Kept, e)
+			meetings = append(meetings, e)
+		default:
+			r.Replaced = append(r.Replaced, e)
+		}
+	}
+	byStart := func(a, b calendar.Event) int { return a.Start.Compare(b.Start) }
+	slices.SortStableFunc(r.Kept, byStart)
+	slices.SortStableFunc(r.Replaced, byStart)
+
+	day.Meetings = meetings
+	if day.Routines, day.RoutineConflicts, err = s.placeRoutines(day); err != nil {
+		return nil, err
+	}
+	day.Busy = s.busyBlocks(day)
+	return r, nil
+}
+
+// ApplyReplan writes plan in place of the replaced blocks. A replaced event with
+// the same type and title as a new block is moved to the block's time rather than
+// deleted and created again, so its ID and title stay the same. New blocks are
+// created first and nothing else is changed if that fails; the replaced events
+// left over are deleted last. The transaction has one entry per plan block, and
+// the changes list the moved and removed events.
+func (s *Service) ApplyReplan(ctx context.Context, r *Replan, plan *Plan) (*Transaction, []Change, error) {
+	tx := &Transaction{
+		CalendarID: s.cfg.Calendar,
+		Entries:    make([]TransactionEntry, len(plan.Blocks)),
+	}
+
+	used := make([]bool, len(r.Replaced))
+	var created, moved []int
+	var changes []Change
+	for i, block := range plan.Blocks {
+		j := matchReplaced(r.Replaced, used, block)
+		if j < 0 {
+			created = append(created, i)
+			continue
+		}
+		used[j] = true
+		event := r.Replaced[j]
+		tx.Entries[i] = TransactionEntry{Block: block, Event: event, Reused: true}
+		if !event.Start.Equal(block.Start) || !event.End.Equal(block.End) {
+			changes = append(changes, Change{Kind: ChangeMoved, Event: event, Start: block.Start, End: block.End})
+			moved = append(moved, i)
+		}
+	}
+
+	if len(created) > 0 {
+		sub := &Plan{Day: plan.Day, Mode: plan.Mode, Tasks: plan.Tasks}
+		for _, i := range created {
+			sub.Blocks = append(sub.Blocks, plan.Blocks[i])
+		}
+		subTx, err := s.Apply(ctx, sub, ApplyOptions{})
+		for k, i := range created {
+			tx.Entries[i] = subTx.Entries[k]
+		}
+		if err != nil {
+			// The replaced events were not touched
+			return tx, nil, err
+		}
+	}
+
+	for j, e := range r.Replaced {
+		if !used[j] {
+			changes = append(changes, Change{Kind: ChangeRemoved, Event: e})
+		}
+	}
+
+	err := s.applyChanges(ctx, changes)
+	for k, i := range moved {
+		if changes[k].Err != nil {
+			tx.Entries[i].Err = changes[k].Err
+			continue
+		}
+		tx.Entries[i].Event.Start, tx.Entries[i].Event.End = changes[k].Start, changes[k].End
+	}
+	return tx, changes, err
+}
+
+// matchReplaced returns the index of the first unused replaced event with the
+// type and title of block, or -1.
+func matchReplaced(replaced []calendar.Event, used []bool, block planner.TimeBlock) int {
+	for j, e := range replaced {
+		if !used[j] && e.Type == block.Type && BlockTitle(e) == block.Title {
+			return j
+		}
+	}
+	return -1
+}
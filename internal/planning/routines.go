@@ -0,0 +1,98 @@
+package planning
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// placeRoutines returns the configured routines that apply on day as blocks.
+// Fixed routines are placed at their time first; those that overlap lunch or an
+// earlier fixed routine are returned as conflicts instead. Flexible ones then go
+// to the free slot within work hours closest to their time, and are left out
+// when no slot is long enough. Routines already on the calendar, e.g. from an
+// earlier plan, are left out too.
+func (s *Service) placeRoutines(day *Day) ([]planner.TimeBlock, []planner.TimeBlock, error) {
+	var placed, conflicts []planner.TimeBlock
+	for _, r := range slices.Concat(s.routines(false), s.routines(true)) {
+		if !r.AppliesOn(day.Date) || hasRoutine(day.Meetings, r.Title) {
+			continue
+		}
+		start, err := planner.ParseTimeOnDate(r.Time, day.Date)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid time for routine %s: %w", r.Title, err)
+		}
+		block := planner.TimeBlock{
+			Type:  planner.BlockTypeRoutine,
+			Title: r.Title,
+			Start: start,
+			End:   start.Add(r.Duration()),
+		}
+
+		busy := append([]planner.TimeBlock{lunchBlock(day)}, placed...)
+		if !r.Flexible && overlapsAny(block, busy) {
+			conflicts = append(conflicts, block)
+			continue
+		}
+		if r.Flexible {
+			for _, meeting := range day.Meetings {
+				busy = append(busy, meeting.ToTimeBlock())
+			}
+			busy = planner.ApplyBuffers(busy, s.buffers(), day.WorkStart, day.WorkEnd)
+			var ok bool
+			if block, ok = nearestSlot(block, planner.FreeWindows(busy, day.WorkStart, day.WorkEnd)); !ok {
+				continue
+			}
+		}
+		placed = append(placed, block)
+	}
+	return placed, conflicts, nil
+}
+
+// routines returns the configured routines that are flexible or fixed.
+func (s *Service) routines(flexible bool) []config.Routine {
+	var routines []config.Routine
+	for _, r := range s.cfg.Routines {
+		if r.Flexible == flexible {
+			routines = append(routines, r)
+		}
+	}
+	return routines
+}
+
+// hasRoutine reports whether a routine block titled title was already created.
+func hasRoutine(events []calendar.Event, title string) bool {
+	return slices.ContainsFunc(events, func(e calendar.Event) bool {
+		return e.Managed && e.Type == planner.BlockTypeRoutine && e.Title == title
+	})
+}
+
+// nearestSlot moves block into the window where it starts closest to its
+// current start. It returns false when no window is long enough.
+func nearestSlot(block planner.TimeBlock, windows []planner.TimeBlock) (planner.TimeBlock, bool) {
+	length := block.End.Sub(block.Start)
+	best, found := block, false
+	var bestDistance time.Duration
+	for _, w := range windows {
+		if w.End.Sub(w.Start) < length {
+			continue
+		}
+		start := block.Start
+		if start.Before(w.Start) {
+			start = w.Start
+		}
+		if latest := w.End.Add(-length); start.After(latest) {
+			start = latest
+		}
+		distance := start.Sub(block.Start).Abs()
+		if !found || distance < bestDistance {
+			best.Start, best.End = start, start.Add(length)
+			bestDistance, found = distance, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,423 @@
+package planning
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// Calendar reads existing meetings and writes planned blocks.
+// It is implemented by calendar.GoogleClient.
+type Calendar interface {
+	FetchMeetings(ctx context.Context, calendarID string, start, end time.Time) ([]calendar.Event, error)
+	CreateEvent(ctx context.Context, calendarID string, event calendar.Event) (string, error)
+	MoveEvent(ctx context.Context, calendarID, eventID string, start, end time.Time) error
+	DeleteEvent(ctx context.Context, calendarID, eventID string) error
+}
+
+// Planner turns a day and a task list into blocks. It is implemented by ai.Client.
+type Planner interface {
+	GeneratePlan(ctx context.Context, req ai.PlanRequest) (*ai.PlanResponse, error)
+}
+
+// Refiner changes a generated plan as asked in plain language. It is implemented
+// by ai.Client.
+type Refiner interface {
+	RefinePlan(ctx context.Context, conv *ai.Conversation, instruction string) (*ai.PlanResponse, error)
+}
+
+// Service runs the plan pipeline: prepare the day, generate a plan and apply it
+// to the calendar. Each step can be run on its own so callers can show progress
+// or review the plan before it is applied.
+type Service struct {
+	cfg      *config.Config
+	calendar Calendar
+	planner  Planner
+	now      func() time.Time
+}
+
+func NewService(cfg *config.Config, cal Calendar, p Planner) *Service {
+	return &Service{
+		cfg:      cfg,
+		calendar: cal,
+		planner:  p,
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the function used to get the current time.
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Day is everything known about the planning date before the planner runs.
+type Day struct {
+	Date       time.Time
+	WorkStart  time.Time
+	WorkEnd    time.Time
+	LunchStart time.Time
+	LunchEnd   time.Time
+	Meetings   []calendar.Event
+	// Routines holds the configured routines placed on the day. Like lunch they
+	// are busy time for the planner and created only when their slot is free.
+	Routines []planner.TimeBlock
+	// RoutineConflicts holds the fixed routines left out because they overlap
+	// lunch or another routine.
+	RoutineConflicts []planner.TimeBlock
+	Busy             []planner.TimeBlock
+	Energy           planner.EnergyProfile
+	// StartAdjusted is set when WorkStart was moved to the current time.
+	StartAdjusted bool
+}
+
+// Plan is the result of running the planner for a day.
+type Plan struct {
+	Day      *Day
+	Mode     config.Mode
+	Tasks    []planner.Task
+	Response *ai.PlanResponse
+	// Generated holds the blocks as returned by the planner.
+	Generated []planner.TimeBlock
+	// Blocks holds the final blocks to create, including lunch.
+	Blocks []planner.TimeBlock
+	// Unscheduled lists tasks dropped while enforcing the mode cadence, or
+	// because their blocks were placed outside the task's After/Before window.
+	Unscheduled []planner.Task
+	Enforced    bool
+	// Suggested holds the prep and follow-up blocks proposed by the planner.
+	// They are not part of Blocks until accepted.
+	Suggested []planner.TimeBlock
+}
+
+// PrepareDay resolves work hours on the given date, fetches the meetings and builds
+// the list of busy blocks, including lunch, routines and meeting buffers.
+func (s *Service) PrepareDay(ctx context.Context, date time.Time) (*Day, error) {
+	return s.prepareDay(ctx, date, true)
+}
+
+// PrepareFullDay is PrepareDay for the full work hours, also when date is today.
+// It is used to look at a day as a whole rather than to plan the time left.
+func (s *Service) PrepareFullDay(ctx context.Context, date time.Time) (*Day, error) {
+	return s.prepareDay(ctx, date, false)
+}
+
+func (s *Service) prepareDay(ctx context.Context, date time.Time, fromNow bool) (*Day, error) {
+	workStart, err := planner.ParseTimeOnDate(s.cfg.WorkHours.Start, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid work start time: %w", err)
+	}
+	workEnd, err := planner.ParseTimeOnDate(s.cfg.WorkHours.End, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid work end time: %w", err)
+	}
+	lunchStart, err := planner.ParseTimeOnDate(s.cfg.LunchTime.Start, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lunch start time: %w", err)
+	}
+	lunchEnd, err := planner.ParseTimeOnDate(s.cfg.LunchTime.End, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lunch end time: %w", err)
+	}
+
+	day := &Day{
+		Date:       date,
+		WorkStart:  workStart,
+		WorkEnd:    workEnd,
+		LunchStart: lunchStart,
+		LunchEnd:   lunchEnd,
+	}
+
+	// Adjust workStart if planning for today and current time is after work start
+	now := s.now()
+	if fromNow && sameDate(date, now) && now.After(workStart) {
+		// Round up to next 15-minute slot for clean scheduling
+		roundedNow := now.Truncate(15 * time.Minute).Add(15 * time.Minute)
+		if !roundedNow.Before(workEnd) {
+			return nil, fmt.Errorf("no time left in work day to plan (it's already %s)", now.Format(planner.TimeFormat))
+		}
+		day.WorkStart = roundedNow
+		day.StartAdjusted = true
+	}
+
+	meetings, err := s.calendar.FetchMeetings(ctx, s.cfg.Calendar, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch meetings: %w", err)
+	}
+	day.Meetings = meetings
+	day.Routines, day.RoutineConflicts, err = s.placeRoutines(day)
+	if err != nil {
+		return nil, err
+	}
+	day.Busy = s.busyBlocks(day)
+
+	day.Energy, err = buildEnergyProfile(s.cfg.Energy, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid energy profile: %w", err)
+	}
+
+	return day, nil
+}
+
+// Generate asks the planner for a plan and post-processes it: the mode cadence is
+// enforced when required, and lunch and an early finish are added.
+func (s *Service) Generate(ctx context.Context, day *Day, mode config.Mode, tasks []planner.Task) (*Plan, error) {
+	resp, err := s.planner.GeneratePlan(ctx, ai.PlanRequest{
+		WorkStart:  day.WorkStart,
+		WorkEnd:    day.WorkEnd,
+		BusyBlocks: day.Busy,
+		Tasks:      tasks,
+		Mode:       mode,
+		Energy:     day.Energy,
+		Meetings:   s.meetingDetails(day),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.newPlan(day, mode, tasks, resp)
+}
+
+// newPlan validates the planner's response and post-processes its blocks.
+func (s *Service) newPlan(day *Day, mode config.Mode, tasks []planner.Task, resp *ai.PlanResponse) (*Plan, error) {
+	if err := resp.Validate(); err != nil {
+		return nil, fmt.Errorf("AI returned an invalid plan: %w", err)
+	}
+
+	generated, err := ParseBlocks(resp.Blocks, day.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AI blocks: %w", err)
+	}
+
+	plan := &Plan{
+		Day:       day,
+		Mode:      mode,
+		Tasks:     tasks,
+		Response:  resp,
+		Generated: generated,
+	}
+
+	var blocks []planner.TimeBlock
+	for _, b := range generated {
+		switch {
+		case !b.Suggested:
+			blocks = append(blocks, b)
+		case !b.Start.Before(day.WorkStart) && !b.End.After(day.WorkEnd) && !overlapsAny(b, day.Busy):
+			plan.Suggested = append(plan.Suggested, b)
+		}
+	}
+	if mode.EnforceCadence {
+		blocks, plan.Unscheduled = planner.EnforceCadence(blocks, tasks, day.Busy, day.WorkStart, day.WorkEnd, cadence(mode), day.Energy)
+		plan.Enforced = true
+	}
+
+	// The planner is only asked to respect time windows; drop tasks it placed
+	// outside of theirs
+	for _, task := range tasks {
+		if !outsideWindow(task, blocks, day.Date) {
+			continue
+		}
+		blocks = slices.DeleteFunc(blocks, func(b planner.TimeBlock) bool { return isTaskBlock(b, task) })
+		if !slices.ContainsFunc(plan.Unscheduled, func(t planner.Task) bool { return t.Title == task.Title }) {
+			plan.Unscheduled = append(plan.Unscheduled, task)
+		}
+	}
+
+	// Add lunch and routine blocks if their slot is free and still ahead
+	fixed := append([]planner.TimeBlock{lunchBlock(day)}, day.Routines...)
+	for _, b := range fixed {
+		if b.End.After(day.WorkStart) && isSlotFree(b.Start, b.End, day.Meetings) {
+			blocks = append(blocks, b)
+		}
+	}
+
+	if mode.EndEarly && s.cfg.OutOfOffice.Enabled {
+		if ooo, ok := planner.EarlyFinish(blocks, day.Busy, day.WorkEnd); ok {
+			blocks = append(blocks, ooo)
+		}
+	}
+
+	plan.Blocks = blocks
+
+	return plan, nil
+}
+
+// Accept adds the accepted suggestions to the blocks of the plan. Suggestions that
+// overlap a block of the plan are not added and are returned.
+func (p *Plan) Accept(accepted []planner.TimeBlock) []planner.TimeBlock {
+	var skipped []planner.TimeBlock
+	for _, b := range accepted {
+		if overlapsAny(b, p.Blocks) {
+			skipped = append(skipped, b)
+			continue
+		}
+		p.Blocks = append(p.Blocks, b)
+	}
+	return skipped
+}
+
+// meetingDetails returns the meetings of day for the planner when suggestions are
+// enabled, and nil otherwise.
+func (s *Service) meetingDetails(day *Day) []ai.Meeting {
+	if !s.cfg.Suggestions.Enabled {
+		return nil
+	}
+	tag := strings.ToLower(s.cfg.Suggestions.Tag())
+	var meetings []ai.Meeting
+	for _, e := range day.Meetings {
+		if e.Managed {
+			continue
+		}
+		meetings = append(meetings, ai.Meeting{
+			Title:       e.Title,
+			Description: e.Description,
+			Start:       e.Start,
+			End:         e.End,
+			NeedsNotes:  strings.Contains(strings.ToLower(e.Title+" "+e.Description), tag),
+		})
+	}
+	return meetings
+}
+
+// outsideWindow reports whether a focus block of task starts before its After time
+// or ends after its Before time.
+func outsideWindow(task planner.Task, blocks []planner.TimeBlock, date time.Time) bool {
+	after, afterErr := planner.ParseTimeOnDate(task.After, date)
+	before, beforeErr := planner.ParseTimeOnDate(task.Before, date)
+	return slices.ContainsFunc(blocks, func(b planner.TimeBlock) bool {
+		return isTaskBlock(b, task) &&
+			((afterErr == nil && b.Start.Before(after)) || (beforeErr == nil && b.End.After(before)))
+	})
+}
+
+// isTaskBlock reports whether b is a focus block of task. Enforced cadences
+// split tasks into "Title (1/3)" blocks.
+func isTaskBlock(b planner.TimeBlock, task planner.Task) bool {
+	return b.Type == planner.BlockTypeFocus &&
+		(b.Title == task.Title || strings.HasPrefix(b.Title, task.Title+" ("))
+}
+
+func overlapsAny(b planner.TimeBlock, others []planner.TimeBlock) bool {
+	return slices.ContainsFunc(others, func(o planner.TimeBlock) bool {
+		return b.Start.Before(o.End) && b.End.After(o.Start)
+	})
+}
+
+// busyBlocks returns lunch, the routines and the meetings of day, with buffers
+// around meetings.
+func (s *Service) busyBlocks(day *Day) []planner.TimeBlock {
+	busy := make([]planner.TimeBlock, 0, len(day.Meetings)+len(day.Routines)+1)
+	busy = append(busy, lunchBlock(day))
+	busy = append(busy, day.Routines...)
+	for _, meeting := range day.Meetings {
+		busy = append(busy, meeting.ToTimeBlock())
+	}
+	return planner.ApplyBuffers(busy, s.buffers(), day.WorkStart, day.WorkEnd)
+}
+
+// buffers returns the configured meeting buffers.
+func (s *Service) buffers() planner.Buffers {
+	return planner.Buffers{
+		Before: s.cfg.Buffers.BeforeMeeting(),
+		After:  s.cfg.Buffers.AfterMeeting(),
+		MinGap: s.cfg.Buffers.MinGap(),
+		Travel: s.cfg.Buffers.Travel(),
+	}
+}
+
+// ParseBlocks converts the planner's blocks into time blocks on the given date.
+func ParseBlocks(aiBlocks []ai.Block, date time.Time) ([]planner.TimeBlock, error) {
+	blocks := make([]planner.TimeBlock, len(aiBlocks))
+
+	for i, block := range aiBlocks {
+		timeBlock, err := block.ToTimeBlock(date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block %d: %w", i+1, err)
+		}
+		blocks[i] = timeBlock
+	}
+
+	return blocks, nil
+}
+
+// ToCalendarEvent converts a planned block into a calendar event, using native
+// Focus time and Out of office event types when they are enabled in config.
+func ToCalendarEvent(block planner.TimeBlock, cfg *config.Config) calendar.Event {
+	event := calendar.Event{
+		Type:        block.Type,
+		Title:       block.GetCalendarTitle(),
+		Description: block.GetCalendarDescription(),
+		Start:       block.Start,
+		End:         block.End,
+	}
+
+	switch {
+	case block.Type == planner.BlockTypeFocus && cfg.FocusTime.Enabled:
+		event.EventType = calendar.EventTypeFocusTime
+		event.AutoDeclineMode = cfg.FocusTime.AutoDeclineMode
+		event.DeclineMessage = cfg.FocusTime.DeclineMessage
+		event.ChatStatus = cfg.FocusTime.ChatStatus
+	case block.Type == planner.BlockTypeOutOfOffice:
+		event.EventType = calendar.EventTypeOutOfOffice
+		event.AutoDeclineMode = cfg.OutOfOffice.AutoDeclineMode
+		event.DeclineMessage = cfg.OutOfOffice.DeclineMessage
+	}
+
+	return event
+}
+
+func lunchBlock(day *Day) planner.TimeBlock {
+	return planner.TimeBlock{
+		Type:  planner.BlockTypeLunch,
+		Title: "Lunch",
+		Start: day.LunchStart,
+		End:   day.LunchEnd,
+	}
+}
+
+// isSlotFree checks if the time slot has no overlapping meetings
+func isSlotFree(start, end time.Time, meetings []calendar.Event) bool {
+	return !slices.ContainsFunc(meetings, func(m calendar.Event) bool {
+		return m.Start.Before(end) && m.End.After(start)
+	})
+}
+
+// buildEnergyProfile places the configured energy windows on the planning date.
+func buildEnergyProfile(profile config.EnergyProfile, date time.Time) (planner.EnergyProfile, error) {
+	levels := []struct {
+		level  string
+		ranges []config.TimeRange
+	}{
+		{planner.EnergyPeak, profile.Peak},
+		{planner.EnergySlump, profile.Slump},
+		{planner.EnergyWindDown, profile.WindDown},
+	}
+
+	var energy planner.EnergyProfile
+	for _, l := range levels {
+		for _, r := range l.ranges {
+			start, err := planner.ParseTimeOnDate(r.Start, date)
+			if err != nil {
+				return nil, err
+			}
+			end, err := planner.ParseTimeOnDate(r.End, date)
+			if err != nil {
+				return nil, err
+			}
+			energy = append(energy, planner.EnergyWindow{Level: l.level, Start: start, End: end})
+		}
+	}
+
+	return energy, nil
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
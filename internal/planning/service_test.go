@@ -0,0 +1,1006 @@
+package planning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		WorkHours:   config.TimeRange{Start: "09:00", End: "17:00"},
+		LunchTime:   config.TimeRange{Start: "12:00", End: "13:00"},
+		Calendar:    "primary",
+		DefaultMode: config.ModeNormal,
+	}
+}
+
+func newTestService(cfg *config.Config, cal Calendar, p Planner) *Service {
+	s := NewService(cfg, cal, p)
+	s.SetClock(func() time.Time { return calendartest.Date.AddDate(0, 0, -1) })
+	return s
+}
+
+func TestServiceRun(t *testing.T) {
+	cfg := testConfig()
+	cal := calendartest.NewMemory()
+	cal.Add("primary", calendar.Event{
+		Type:  planner.BlockTypeMeeting,
+		Title: "Standup",
+		Start: calendartest.At("10:00"),
+		End:   calendartest.At("10:15"),
+	})
+	fake := &aitest.StaticPlanner{Response: &ai.PlanResponse{Blocks: []ai.Block{
+		{Type: planner.BlockTypeFocus, Title: "Write docs", Start: "09:00", End: "10:00"},
+		{Type: planner.BlockTypeBreak, Title: "Short break", Start: "10:15", End: "10:30"},
+	}}}
+	service := newTestService(cfg, cal, fake)
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	if len(day.Meetings) != 1 || len(day.Busy) != 2 {
+		t.Fatalf("expected 1 meeting and 2 busy blocks, got %d and %d", len(day.Meetings), len(day.Busy))
+	}
+
+	tasks := planner.ParseTaskList("Write docs:L")
+	mode, _ := cfg.GetMode(config.ModeNormal)
+	plan, err := service.Generate(context.Background(), day, mode, tasks)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 planner request, got %d", len(requests))
+	}
+	if requests[0].Mode.Name != config.ModeNormal || len(requests[0].BusyBlocks) != 2 {
+		t.Errorf("unexpected planner request: %+v", requests[0])
+	}
+
+	// Two generated blocks plus lunch
+	if len(plan.Blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d: %v", len(plan.Blocks), plan.Blocks)
+	}
+
+	tx, err := service.Apply(context.Background(), plan, ApplyOptions{})
+	if err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	if len(tx.Created()) != 3 {
+		t.Errorf("expected 3 created events, got %d", len(tx.Created()))
+	}
+	for _, entry := range tx.Entries {
+		if entry.Event.ID == "" {
+			t.Errorf("expected an event ID for %s", entry.Block.Title)
+		}
+	}
+	if got := len(cal.Events("primary")); got != 4 {
+		t.Errorf("expected 4 events in calendar, got %d", got)
+	}
+}
+
+func TestServiceSkipsLunchWhenBusy(t *testing.T) {
+	cfg := testConfig()
+	cal := calendartest.NewMemory()
+	cal.Add("primary", calendar.Event{Type: planner.BlockTypeMeeting, Title: "Lunch & learn", Start: calendartest.At("12:30"), End: calendartest.At("13:30")})
+	service := newTestService(cfg, cal, &aitest.StaticPlanner{Response: &ai.PlanResponse{}})
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	plan, err := service.Generate(context.Background(), day, config.Mode{Name: "normal"}, nil)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	for _, b := range plan.Blocks {
+		if b.Type == planner.BlockTypeLunch {
+			t.Errorf("lunch should not be planned over a meeting")
+		}
+	}
+}
+
+func TestServiceDropsTasksOutsideTheirWindow(t *testing.T) {
+	cal := calendartest.NewMemory()
+	fake := &aitest.StaticPlanner{Response: &ai.PlanResponse{Blocks: []ai.Block{
+		{Type: planner.BlockTypeFocus, Title: "Call Bob", Start: "10:00", End: "10:30"},
+		{Type: planner.BlockTypeFocus, Title: "Write docs", Start: "10:30", End: "11:30"},
+		{Type: planner.BlockTypeFocus, Title: "Review PRs", Start: "15:00", End: "15:30"},
+	}}}
+	service := newTestService(testConfig(), cal, fake)
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	tasks := []planner.Task{
+		{Title: "Call Bob", Duration: planner.SizeM, After: "15:00"},
+		{Title: "Write docs", Duration: planner.SizeL, Before: "12:00"},
+		{Title: "Review PRs", Duration: planner.SizeM, After: "14:00", Before: "16:00"},
+	}
+	plan, err := service.Generate(context.Background(), day, config.Mode{Name: "normal"}, tasks)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	if len(plan.Unscheduled) != 1 || plan.Unscheduled[0].Title != "Call Bob" {
+		t.Errorf("expected Call Bob to be unscheduled, got %+v", plan.Unscheduled)
+	}
+	for _, b := range plan.Blocks {
+		if b.Title == "Call Bob" {
+			t.Errorf("a block before the task's After time should be dropped: %v", b)
+		}
+	}
+	if len(plan.Blocks) != 3 {
+		t.Errorf("expected the two other tasks and lunch, got %v", plan.Blocks)
+	}
+}
+
+func TestServiceRoutines(t *testing.T) {
+	cfg := testConfig()
+	cfg.Routines = []config.Routine{
+		{Title: "Inbox zero", Minutes: 20, Time: "09:00"},
+		{Title: "Timesheet", Minutes: 15, Time: "11:00", Weekdays: []string{"fri"}},
+		{Title: "Expenses", Minutes: 15, Time: "14:00"},
+		{Title: "Wrap-up", Minutes: 20, Time: "16:40", Flexible: true},
+	}
+	cal := calendartest.NewMemory()
+	cal.Add("primary",
+		calendar.Event{Type: planner.BlockTypeMeeting, Title: "Interview", Start: calendartest.At("14:00"), End: calendartest.At("15:00")},
+		calendar.Event{Type: planner.BlockTypeMeeting, Title: "Retro", Start: calendartest.At("16:30"), End: calendartest.At("17:00")})
+	service := newTestService(cfg, cal, &aitest.StaticPlanner{Response: &ai.PlanResponse{}})
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	want := []string{"Inbox zero 09:00", "Expenses 14:00", "Wrap-up 16:10"}
+	if len(day.Routines) != len(want) {
+		t.Fatalf("Routines = %+v, want %v", day.Routines, want)
+	}
+	for i, r := range day.Routines {
+		if got := r.Title + " " + r.Start.Format(planner.TimeFormat); got != want[i] {
+			t.Errorf("routine %d = %s, want %s", i, got, want[i])
+		}
+	}
+	if len(day.Busy) != 6 {
+		t.Errorf("expected lunch, routines and meetings to be busy, got %+v", day.Busy)
+	}
+
+	plan, err := service.Generate(context.Background(), day, config.Mode{Name: "normal"}, nil)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	var created []string
+	for _, b := range plan.Blocks {
+		if b.Type == planner.BlockTypeRoutine {
+			created = append(created, b.Title)
+		}
+	}
+	if strings.Join(created, ", ") != "Inbox zero, Wrap-up" {
+		t.Errorf("expected routines over meetings to be skipped, got %v", created)
+	}
+
+	if _, err := service.Apply(context.Background(), plan, ApplyOptions{}); err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	again, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	if len(again.Routines) != 1 || again.Routines[0].Title != "Expenses" {
+		t.Errorf("expected created routines to be left out, got %+v", again.Routines)
+	}
+}
+
+func TestServiceRoutineConflicts(t *testing.T) {
+	cfg := testConfig()
+	cfg.Routines = []config.Routine{
+		{Title: "Stretch", Minutes: 15, Time: "09:05", Flexible: true},
+		{Title: "Inbox zero", Minutes: 20, Time: "09:00"},
+		{Title: "Standup notes", Minutes: 15, Time: "12:15"},
+		{Title: "Plan the day", Minutes: 15, Time: "09:10"},
+	}
+	service := newTestService(cfg, calendartest.NewMemory(), &aitest.StaticPlanner{Response: &ai.PlanResponse{}})
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	// Fixed routines are placed first, so the flexible one moves out of their way
+	want := []string{"Inbox zero 09:00", "Stretch 09:20"}
+	if len(day.Routines) != len(want) {
+		t.Fatalf("Routines = %+v, want %v", day.Routines, want)
+	}
+	for i, r := range day.Routines {
+		if got := r.Title + " " + r.Start.Format(planner.TimeFormat); got != want[i] {
+			t.Errorf("routine %d = %s, want %s", i, got, want[i])
+		}
+	}
+	if len(day.RoutineConflicts) != 2 || day.RoutineConflicts[0].Title != "Standup notes" || day.RoutineConflicts[1].Title != "Plan the day" {
+		t.Errorf("expected the routines over lunch and another routine as conflicts, got %+v", day.RoutineConflicts)
+	}
+
+	plan, err := service.Generate(context.Background(), day, config.Mode{Name: "normal"}, nil)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	for i, b := range plan.Blocks {
+		if overlapsAny(b, plan.Blocks[i+1:]) {
+			t.Errorf("block %s (%s) overlaps another block", b.Title, b.Start.Format(planner.TimeFormat))
+		}
+	}
+}
+
+func TestServiceAdjustsStartForToday(t *testing.T) {
+	service := NewService(testConfig(), calendartest.NewMemory(), &aitest.StaticPlanner{})
+	service.SetClock(func() time.Time { return calendartest.At("10:07") })
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	if !day.StartAdjusted || day.WorkStart.Format(planner.TimeFormat) != "10:15" {
+		t.Errorf("expected start adjusted to 10:15, got %s (adjusted=%v)",
+			day.WorkStart.Format(planner.TimeFormat), day.StartAdjusted)
+	}
+
+	service.SetClock(func() time.Time { return calendartest.At("16:50") })
+	if _, err := service.PrepareDay(context.Background(), calendartest.Date); err == nil || !strings.Contains(err.Error(), "no time left") {
+		t.Errorf("expected no time left error, got %v", err)
+	}
+}
+
+func TestServiceApplyRollsBack(t *testing.T) {
+	tests := []struct {
+		name        string
+		keepPartial bool
+		wantLeft    int
+	}{
+		{"rollback", false, 1},
+		{"keep partial", true, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cal := calendartest.NewMemory()
+			cal.Add("primary", calendar.Event{Type: planner.BlockTypeMeeting, Title: "Standup", Start: calendartest.At("11:00"), End: calendartest.At("11:15")})
+			cal.FailOn = func(e calendar.Event) error {
+				if e.Type == planner.BlockTypeBreak {
+					return errors.New("boom")
+				}
+				return nil
+			}
+			service := newTestService(testConfig(), cal, &aitest.StaticPlanner{})
+
+			plan := &Plan{Blocks: []planner.TimeBlock{
+				{Type: planner.BlockTypeFocus, Title: "A", Start: calendartest.At("09:00"), End: calendartest.At("09:30")},
+				{Type: planner.BlockTypeBreak, Title: "Break", Start: calendartest.At("09:30"), End: calendartest.At("09:40")},
+				{Type: planner.BlockTypeFocus, Title: "B", Start: calendartest.At("09:40"), End: calendartest.At("10:10")},
+			}}
+
+			tx, err := service.Apply(context.Background(), plan, ApplyOptions{KeepPartial: tt.keepPartial})
+			if err == nil || !strings.Contains(err.Error(), "boom") {
+				t.Fatalf("Apply() error = %v, want the insert error", err)
+			}
+			// Blocks not started before the failure are skipped rather than attempted
+			failed := tx.Failed()
+			if len(failed) != 1 || failed[0].Block.Title != "Break" {
+				t.Errorf("unexpected failed entries: %+v", failed)
+			}
+			if got := len(cal.Events("primary")); got != tt.wantLeft {
+				t.Errorf("expected %d events left in calendar, got %d", tt.wantLeft, got)
+			}
+			if got := len(tx.Created()); got != tt.wantLeft-1 {
+				t.Errorf("expected %d created entries, got %d", tt.wantLeft-1, got)
+			}
+		})
+	}
+}
+
+func TestServiceApplyStopsAfterFailure(t *testing.T) {
+	cal := calendartest.NewMemory()
+	var attempts atomic.Int32
+	cal.FailOn = func(e calendar.Event) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	}
+	service := newTestService(testConfig(), cal, &aitest.StaticPlanner{})
+
+	plan := &Plan{}
+	for i := range 12 {
+		start := calendartest.At("09:00").Add(time.Duration(i) * 30 * time.Minute)
+		plan.Blocks = append(plan.Blocks, planner.TimeBlock{Type: planner.BlockTypeFocus, Title: "Task", Start: start, End: start.Add(30 * time.Minute)})
+	}
+
+	tx, err := service.Apply(context.Background(), plan, ApplyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Apply() error = %v, want the insert error", err)
+	}
+	attempted := int(attempts.Load())
+	if attempted > maxConcurrentWrites {
+		t.Errorf("expected no inserts to start after the first failure, got %d attempts", attempted)
+	}
+	// Only the attempted inserts count as failures
+	if got := len(tx.Failed()); got != attempted {
+		t.Errorf("expected %d failed entries, got %d", attempted, got)
+	}
+	if got := len(tx.Skipped()); got != len(plan.Blocks)-attempted {
+		t.Errorf("expected the blocks not attempted to be skipped, got %d", got)
+	}
+	want := fmt.Sprintf("failed to create %d of 12 block(s)", attempted)
+	if !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), fmt.Sprintf("%d more skipped", 12-attempted)) {
+		t.Errorf("Apply() error = %v, want %q and the skipped blocks", err, want)
+	}
+}
+
+func TestServiceApplyInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cal := calendartest.NewMemory()
+	cal.FailOn = func(e calendar.Event) error {
+		// Ctrl-C while the request is in flight
+		cancel()
+		return context.Canceled
+	}
+	service := newTestService(testConfig(), cal, &aitest.StaticPlanner{})
+
+	plan := &Plan{Blocks: []planner.TimeBlock{
+		{Type: planner.BlockTypeFocus, Title: "A", Start: calendartest.At("09:00"), End: calendartest.At("09:30")},
+	}}
+	tx, err := service.Apply(ctx, plan, ApplyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "may still be in the calendar") {
+		t.Errorf("Apply() error = %v, want a warning about the interrupted insert", err)
+	}
+	if interrupted := tx.Interrupted(); len(interrupted) != 1 || interrupted[0].Block.Title != "A" {
+		t.Errorf("Interrupted() = %+v, want block A", interrupted)
+	}
+}
+
+func TestServiceApplyCanceled(t *testing.T) {
+	cal := calendartest.NewMemory()
+	service := newTestService(testConfig(), cal, &aitest.StaticPlanner{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	plan := &Plan{Blocks: []planner.TimeBlock{
+		{Type: planner.BlockTypeFocus, Title: "A", Start: calendartest.At("09:00"), End: calendartest.At("09:30")},
+	}}
+	if _, err := service.Apply(ctx, plan, ApplyOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Apply() error = %v, want context.Canceled", err)
+	}
+	if got := len(cal.Events("primary")); got != 0 {
+		t.Errorf("expected no events after cancellation, got %d", got)
+	}
+}
+
+func TestToCalendarEventFocusTime(t *testing.T) {
+	cfg := testConfig()
+	cfg.FocusTime = config.FocusTime{Enabled: true, ChatStatus: "doNotDisturb"}
+
+	event := ToCalendarEvent(planner.TimeBlock{Type: planner.BlockTypeFocus, Title: "Docs"}, cfg)
+	if event.EventType != calendar.EventTypeFocusTime || event.ChatStatus != "doNotDisturb" {
+		t.Errorf("expected a focus time event, got %+v", event)
+	}
+
+	event = ToCalendarEvent(planner.TimeBlock{Type: planner.BlockTypeBreak, Title: "Break"}, cfg)
+	if event.EventType != "" {
+		t.Errorf("breaks should be default events, got %q", event.EventType)
+	}
+}
+
+func TestServiceFill(t *testing.T) {
+	cfg := testConfig()
+	fake := &aitest.StaticPlanner{Response: &ai.PlanResponse{Blocks: []ai.Block{
+		{Type: planner.BlockTypeFocus, Title: "Review PRs", Start: "14:00", End: "14:30"},
+		{Type: planner.BlockTypeFocus, Title: "Outside", Start: "16:00", End: "16:30"},
+	}}}
+	service := newTestService(cfg, calendartest.NewMemory(), fake)
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	mode, _ := cfg.GetMode(config.ModeNormal)
+	tasks := planner.ParseTaskList("Write docs:L, Review PRs:M")
+	blocks := []planner.TimeBlock{
+		{Type: planner.BlockTypeFocus, Title: "Write docs (1/2)", Start: calendartest.At("09:00"), End: calendartest.At("09:30")},
+		{Type: planner.BlockTypeFocus, Title: "Write docs (2/2)", Start: calendartest.At("09:30"), End: calendartest.At("10:00")},
+		{Type: planner.BlockTypeFocus, Title: "Old", Start: calendartest.At("14:00"), End: calendartest.At("15:00")},
+	}
+
+	filled, err := service.Fill(context.Background(), day, mode, tasks, blocks, calendartest.At("13:00"), calendartest.At("15:30"))
+	if err != nil {
+		t.Fatalf("Fill() error: %v", err)
+	}
+	if len(filled) != 1 || filled[0].Title != "Review PRs" {
+		t.Errorf("Fill() = %+v, want only the block inside the window", filled)
+	}
+
+	req := fake.Requests()[0]
+	if len(req.Tasks) != 1 || req.Tasks[0].Title != "Review PRs" || !req.WorkStart.Equal(calendartest.At("13:00")) {
+		t.Errorf("unexpected planner request: %+v", req)
+	}
+	if len(req.BusyBlocks) != len(day.Busy)+2 {
+		t.Errorf("expected the blocks outside the window to be busy, got %d busy blocks", len(req.BusyBlocks))
+	}
+
+	if _, err := service.Fill(context.Background(), day, mode, tasks[:1], blocks, calendartest.At("13:00"), calendartest.At("15:30")); !errors.Is(err, ErrNothingToFill) {
+		t.Errorf("Fill() error = %v, want ErrNothingToFill", err)
+	}
+}
+
+func TestServiceFillEnforcesCadence(t *testing.T) {
+	cfg := testConfig()
+	fake := &aitest.StaticPlanner{Response: &ai.PlanResponse{Blocks: []ai.Block{
+		{Type: planner.BlockTypeFocus, Title: "Review PRs", Start: "14:00", End: "14:30"},
+	}}}
+	service := newTestService(cfg, calendartest.NewMemory(), fake)
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	mode, _ := cfg.GetMode(config.ModePomodoro)
+	tasks := planner.ParseTaskList("Write docs:S, Review PRs:M")
+	blocks := []planner.TimeBlock{
+		{Type: planner.BlockTypeFocus, Title: "Write docs", Start: calendartest.At("09:00"), End: calendartest.At("09:25")},
+	}
+
+	filled, err := service.Fill(context.Background(), day, mode, tasks, blocks, calendartest.At("13:00"), calendartest.At("15:30"))
+	if err != nil {
+		t.Fatalf("Fill() error: %v", err)
+	}
+
+	want := []struct{ blockType, title, start, end string }{
+		{planner.BlockTypeFocus, "Review PRs (1/2)", "13:00", "13:25"},
+		{planner.BlockTypeBreak, "Short break", "13:25", "13:30"},
+		{planner.BlockTypeFocus, "Review PRs (2/2)", "13:30", "13:55"},
+	}
+	if len(filled) != len(want) {
+		t.Fatalf("Fill() = %+v, want %d blocks", filled, len(want))
+	}
+	for i, w := range want {
+		b := filled[i]
+		if b.Type != w.blockType || b.Title != w.title || !b.Start.Equal(calendartest.At(w.start)) || !b.End.Equal(calendartest.At(w.end)) {
+			t.Errorf("block %d = %s %q %s - %s, want %s %q %s - %s", i, b.Type, b.Title,
+				b.Start.Format(planner.TimeFormat), b.End.Format(planner.TimeFormat), w.blockType, w.title, w.start, w.end)
+		}
+	}
+}
+
+func managed(blockType, task, start, end string) calendar.Event {
+	block := planner.TimeBlock{Type: blockType, Title: task, Start: calendartest.At(start), End: calendartest.At(end)}
+	return calendar.Event{
+		Type:        blockType,
+		Title:       block.GetCalendarTitle(),
+		Description: block.GetCalendarDescription(),
+		Start:       block.Start,
+		End:         block.End,
+		Managed:     true,
+	}
+}
+
+func TestServiceReconcile(t *testing.T) {
+	cfg := testConfig()
+	cfg.Buffers = config.Buffers{AfterMeetingMinutes: 5}
+	cal := calendartest.NewMemory()
+	cal.Add("primary",
+		managed(planner.BlockTypeFocus, "Write docs", "09:00", "10:00"),
+		managed(planner.BlockTypeFocus, "Review PRs", "14:00", "15:00"),
+		managed(planner.BlockTypeBreak, "Short break", "15:00", "15:15"),
+		managed(planner.BlockTypeFocus, "Plan sprint", "15:15", "16:15"),
+		managed(planner.BlockTypeBreak, "Short break", "16:15", "16:30"),
+		managed(planner.BlockTypeFocus, "Inbox", "16:30", "16:50"),
+		calendar.Event{Type: planner.BlockTypeMeeting, Title: "Design review", Start: calendartest.At("14:00"), End: calendartest.At("15:00")},
+	)
+	service := newTestService(cfg, cal, &aitest.StaticPlanner{})
+
+	changes, err := service.Reconcile(context.Background(), calendartest.Date, ReconcileOptions{})
+	if err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+
+	want := []string{
+		"moved Focus time (Review PRs) 14:00 - 15:00 → 15:05 - 16:05 (conflicts with Design review)",
+		"moved Break (Short break) 15:00 - 15:15 → 16:05 - 16:20",
+		"shrunk Focus time (Plan sprint) 15:15 - 16:15 → 16:20 - 17:00",
+		"removed Break (Short break) 16:15 - 16:30",
+		"removed Focus time (Inbox) 16:30 - 16:50",
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %v", len(changes), len(want), changes)
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Errorf("change %d = %q, want %q", i, c, want[i])
+		}
+	}
+
+	var titles []string
+	for _, e := range cal.Events("primary") {
+		titles = append(titles, e.Start.Format(planner.TimeFormat)+" "+EventLabel(e))
+	}
+	wantTitles := "09:00 Focus time (Write docs), 14:00 Design review, 15:05 Focus time (Review PRs), 16:05 Break (Short break), 16:20 Focus time (Plan sprint)"
+	if strings.Join(titles, ", ") != wantTitles {
+		t.Errorf("calendar after reconcile = %v", titles)
+	}
+
+	// Nothing left to fix
+	if changes, err := service.Reconcile(context.Background(), calendartest.Date, ReconcileOptions{}); err != nil || len(changes) != 0 {
+		t.Errorf("second Reconcile() = %v, %v", changes, err)
+	}
+}
+
+func TestServiceReconcileInProgress(t *testing.T) {
+	cal := calendartest.NewMemory()
+	cal.Add("primary",
+		managed(planner.BlockTypeFocus, "Write docs", "09:00", "11:00"),
+		calendar.Event{Type: planner.BlockTypeMeeting, Title: "Incident", Start: calendartest.At("10:00"), End: calendartest.At("10:30")},
+	)
+	service := NewService(testConfig(), cal, &aitest.StaticPlanner{})
+	service.SetClock(func() time.Time { return calendartest.At("09:42") })
+
+	changes, err := service.Reconcile(context.Background(), calendartest.Date, ReconcileOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].String() != "shrunk Focus time (Write docs) 09:00 - 11:00 → 09:00 - 10:00 (conflicts with Incident)" {
+		t.Errorf("Reconcile() = %v", changes)
+	}
+	if events := cal.Events("primary"); !events[0].End.Equal(calendartest.At("11:00")) {
+		t.Errorf("dry run should not change the calendar, got %+v", events[0])
+	}
+}
+
+func TestServiceReplan(t *testing.T) {
+	cal := calendartest.NewMemory()
+	cal.Add("primary",
+		managed(planner.BlockTypeFocus, "Write docs", "09:00", "10:00"),
+		managed(planner.BlockTypeFocus, "Review PRs", "10:30", "11:30"),
+		managed(planner.BlockTypeBreak, "Short break", "11:30", "11:45"),
+		managed(planner.BlockTypeLunch, "Lunch", "12:00", "13:00"),
+		managed(planner.BlockTypeFocus, "Plan sprint", "15:00", "16:00"),
+		calendar.Event{Type: planner.BlockTypeMeeting, Title: "Incident", Start: calendartest.At("15:00"), End: calendartest.At("16:00")},
+	)
+	fake := &aitest.StaticPlanner{Response: &ai.PlanResponse{Blocks: []ai.Block{
+		{Type: planner.BlockTypeFocus, Title: "Review PRs", Start: "11:30", End: "12:00"},
+		{Type: planner.BlockTypeFocus, Title: "Plan sprint", Start: "13:00", End: "14:00"},
+	}}}
+	service := newTestService(testConfig(), cal, fake)
+
+	r, err := service.PrepareReplan(context.Background(), calendartest.Date, calendartest.At("11:00"))
+	if err != nil {
+		t.Fatalf("PrepareReplan() error: %v", err)
+	}
+	if len(r.Kept) != 2 || len(r.Replaced) != 3 {
+		t.Fatalf("expected 2 kept and 3 replaced blocks, got %d and %d", len(r.Kept), len(r.Replaced))
+	}
+	if elapsed := r.Elapsed(); len(elapsed) != 1 || BlockTitle(elapsed[0]) != "Write docs" {
+		t.Errorf("Elapsed() = %+v, want Write docs", elapsed)
+	}
+
+	tasks := planner.ParseTaskList("Write docs:XL, Review PRs:XL, Plan sprint:L")
+	remaining := r.Remaining(tasks, map[string]bool{"Write docs": true})
+	if len(remaining) != 2 || remaining[0].Duration != 30*time.Minute || remaining[1].Title != "Plan sprint" {
+		t.Fatalf("Remaining() = %+v", remaining)
+	}
+
+	mode, _ := testConfig().GetMode(config.ModeNormal)
+	plan, err := service.Generate(context.Background(), r.Day, mode, remaining)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if req := fake.Requests()[0]; !req.WorkStart.Equal(calendartest.At("11:00")) {
+		t.Errorf("WorkStart = %v, want 11:00", req.WorkStart)
+	}
+
+	before := cal.Events("primary")
+	tx, changes, err := service.ApplyReplan(context.Background(), r, plan)
+	if err != nil {
+		t.Fatalf("ApplyReplan() error: %v", err)
+	}
+
+	want := []string{
+		"moved Focus time (Plan sprint) 15:00 - 16:00 → 13:00 - 14:00",
+		"removed Break (Short break) 11:30 - 11:45",
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+
+	// Review PRs is new; Plan sprint and lunch keep their events
+	if tx.Entries[0].Reused || !tx.Entries[1].Reused || tx.Entries[1].Event.ID != before[4].ID || !tx.Entries[2].Reused {
+		t.Errorf("unexpected transaction: %+v", tx.Entries)
+	}
+
+	events := cal.Events("primary")
+	if len(events) != 6 {
+		t.Fatalf("expected 6 events after replanning, got %+v", events)
+	}
+	for _, e := range events {
+		if e.ID == before[4].ID && !e.Start.Equal(calendartest.At("13:00")) {
+			t.Errorf("expected Plan sprint to be moved to 13:00, got %v", e.Start)
+		}
+		if e.ID == before[2].ID {
+			t.Errorf("expected the break to be removed")
+		}
+	}
+}
+
+func TestServiceReplanFromThePast(t *testing.T) {
+	cal := calendartest.NewMemory()
+	cal.Add("primary",
+		managed(planner.BlockTypeFocus, "Write docs", "09:00", "10:00"),
+		managed(planner.BlockTypeFocus, "Review PRs", "13:00", "14:00"),
+		managed(planner.BlockTypeFocus, "Plan sprint", "15:00", "16:00"),
+	)
+	service := newTestService(testConfig(), cal, &aitest.StaticPlanner{})
+	service.SetClock(func() time.Time { return calendartest.At("14:00") })
+
+	// replan --from 09:00 in the afternoon must not replace the morning
+	r, err := service.PrepareReplan(context.Background(), calendartest.Date, calendartest.At("09:00"))
+	if err != nil {
+		t.Fatalf("PrepareReplan() error: %v", err)
+	}
+	if !r.From.Equal(calendartest.At("14:15")) || !r.Day.WorkStart.Equal(calendartest.At("14:15")) {
+		t.Errorf("From = %v, WorkStart = %v, want both moved up to 14:15", r.From, r.Day.WorkStart)
+	}
+	if len(r.Kept) != 2 || len(r.Replaced) != 1 || BlockTitle(r.Replaced[0]) != "Plan sprint" {
+		t.Errorf("expected only Plan sprint to be replaced, got kept %+v and replaced %+v", r.Kept, r.Replaced)
+	}
+}
+
+func TestServiceSuggestions(t *testing.T) {
+	cfg := testConfig()
+	cfg.Suggestions = config.Suggestions{Enabled: true}
+	cal := calendartest.NewMemory()
+	cal.Add("primary",
+		calendar.Event{Type: planner.BlockTypeMeeting, Title: "Design review", Description: "Agenda #Notes", Start: calendartest.At("15:00"), End: calendartest.At("16:00")},
+		calendar.Event{Type: planner.BlockTypeMeeting, Title: "Standup", Start: calendartest.At("10:00"), End: calendartest.At("10:15")},
+		managed(planner.BlockTypeFocus, "Old", "09:00", "09:30"),
+	)
+	fake := &aitest.StaticPlanner{Response: &ai.PlanResponse{Blocks: []ai.Block{
+		{Type: planner.BlockTypeFocus, Title: "Write docs", Start: "13:00", End: "14:00"},
+		{Type: planner.BlockTypeFocus, Title: "Prep for Design review", Start: "14:30", End: "15:00", Suggested: true},
+		{Type: planner.BlockTypeFocus, Title: "Notes for Design review", Start: "16:00", End: "16:20", Suggested: true},
+		{Type: planner.BlockTypeFocus, Title: "Prep for Standup", Start: "09:55", End: "10:05", Suggested: true},
+	}}}
+	service := newTestService(cfg, cal, fake)
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	mode, _ := cfg.GetMode(config.ModeNormal)
+	plan, err := service.Generate(context.Background(), day, mode, planner.ParseTaskList("Write docs:L"))
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	meetings := fake.Requests()[0].Meetings
+	if len(meetings) != 2 || meetings[0].NeedsNotes || !meetings[1].NeedsNotes || meetings[1].Description != "Agenda #Notes" {
+		t.Errorf("unexpected meetings sent to the planner: %+v", meetings)
+	}
+
+	// The standup prep overlaps the meeting and is dropped
+	if len(plan.Suggested) != 2 || plan.Suggested[0].Title != "Prep for Design review" {
+		t.Fatalf("Suggested = %+v", plan.Suggested)
+	}
+	if len(plan.Blocks) != 2 {
+		t.Errorf("expected suggestions to stay out of the blocks until accepted, got %+v", plan.Blocks)
+	}
+
+	skipped := plan.Accept([]planner.TimeBlock{
+		plan.Suggested[1],
+		{Type: planner.BlockTypeFocus, Title: "Clash", Start: calendartest.At("13:30"), End: calendartest.At("13:45"), Suggested: true},
+	})
+	if len(skipped) != 1 || skipped[0].Title != "Clash" || len(plan.Blocks) != 3 || !plan.Blocks[2].Suggested {
+		t.Errorf("Accept() skipped %+v, blocks %+v", skipped, plan.Blocks)
+	}
+
+	cfg.Suggestions.Enabled = false
+	if _, err := service.Generate(context.Background(), day, mode, planner.ParseTaskList("Write docs:L")); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if got := fake.Requests()[1].Meetings; got != nil {
+		t.Errorf("expected no meeting details without opting in, got %+v", got)
+	}
+}
+
+func TestServiceRefine(t *testing.T) {
+	cfg := testConfig()
+	cal := calendartest.NewMemory()
+	cal.Add("primary", calendar.Event{Type: planner.BlockTypeMeeting, Title: "Standup", Start: calendartest.At("10:00"), End: calendartest.At("10:15")})
+	openAI := aitest.NewServer(
+		aitest.Reply{Content: `{"blocks": [
+			{"type": "focus", "title": "Write docs", "start": "09:00", "end": "10:00"},
+			{"type": "focus", "title": "Review PRs", "start": "10:15", "end": "10:30"}
+		]}`},
+		aitest.Reply{Content: `{"blocks": [
+			{"type": "focus", "title": "Write docs", "start": "14:00", "end": "15:00"},
+			{"type": "focus", "title": "Review PRs", "start": "10:15", "end": "10:30"},
+			{"type": "break", "title": "Short break", "start": "15:00", "end": "15:15"}
+		]}`},
+		aitest.Reply{Content: `{"blocks": [
+			{"type": "focus", "title": "Write docs", "start": "09:30", "end": "10:30"}
+		]}`},
+	)
+	defer openAI.Close()
+	service := newTestService(cfg, cal, ai.NewClient("sk-test", ai.WithEndpoint(openAI.Endpoint())))
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	mode, _ := cfg.GetMode(config.ModeNormal)
+	plan, err := service.Generate(context.Background(), day, mode, planner.ParseTaskList("Write docs:L, Review PRs:S"))
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	refined, err := service.Refine(context.Background(), plan, "Move Write docs to the afternoon")
+	if err != nil {
+		t.Fatalf("Refine() error: %v", err)
+	}
+	var diffs []string
+	for _, d := range DiffBlocks(plan.Blocks, refined.Blocks) {
+		diffs = append(diffs, d.String())
+	}
+	want := []string{
+		"~ focus Write docs (09:00 - 10:00 → 14:00 - 15:00)",
+		"+ break Short break (15:00 - 15:15)",
+	}
+	if strings.Join(diffs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DiffBlocks() =\n%s\nwant\n%s", strings.Join(diffs, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The last reply overlaps the standup
+	if _, err := service.Refine(context.Background(), refined, "Start earlier"); err == nil || !strings.Contains(err.Error(), "overlaps Standup") {
+		t.Errorf("Refine() error = %v, want an overlap error", err)
+	}
+
+	static := newTestService(cfg, cal, &aitest.StaticPlanner{Response: plan.Response})
+	if _, err := static.Refine(context.Background(), plan, "Anything"); err == nil {
+		t.Error("Refine() expected an error for a planner without refinement")
+	}
+}
+
+func TestDiffBlocks(t *testing.T) {
+	focus := func(title, start, end string) planner.TimeBlock {
+		return planner.TimeBlock{Type: planner.BlockTypeFocus, Title: title, Start: calendartest.At(start), End: calendartest.At(end)}
+	}
+	before := []planner.TimeBlock{
+		focus("Write docs", "09:00", "10:00"),
+		focus("Review PRs", "10:00", "10:30"),
+		focus("Write docs", "13:00", "14:00"),
+	}
+	after := []planner.TimeBlock{
+		focus("Write docs", "09:00", "10:00"),
+		focus("Write docs", "10:00", "11:00"),
+		focus("Email", "15:00", "15:30"),
+	}
+
+	var got []string
+	for _, d := range DiffBlocks(before, after) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		"~ focus Write docs (13:00 - 14:00 → 10:00 - 11:00)",
+		"- focus Review PRs (10:00 - 10:30)",
+		"+ focus Email (15:00 - 15:30)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DiffBlocks() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := DiffBlocks(before, before); len(got) != 0 {
+		t.Errorf("expected no differences for the same blocks, got %v", got)
+	}
+}
+
+func TestParseTasks(t *testing.T) {
+	cfg := testConfig()
+
+	parsed, err := ParseTasks(context.Background(), cfg, &aitest.StaticPlanner{}, "write docs (1h, before lunch), review PRs after lunch")
+	if err != nil {
+		t.Fatalf("ParseTasks() error: %v", err)
+	}
+	if parsed.Fallback == nil {
+		t.Error("expected a fallback for a planner that does not parse tasks")
+	}
+	if len(parsed.Tasks) != 2 || parsed.Tasks[0].Before != "12:00" || parsed.Tasks[1].After != "13:00" {
+		t.Errorf("unexpected tasks: %+v", parsed.Tasks)
+	}
+
+	openAI := aitest.NewServer(aitest.Reply{Content: `{"tasks": []}`})
+	defer openAI.Close()
+	parsed, err = ParseTasks(context.Background(), cfg, ai.NewClient("sk-test", ai.WithEndpoint(openAI.Endpoint())), "inbox 20m")
+	if err != nil {
+		t.Fatalf("ParseTasks() error: %v", err)
+	}
+	if parsed.Fallback == nil || len(parsed.Tasks) != 1 || parsed.Tasks[0].Duration != 20*time.Minute {
+		t.Errorf("expected the patterns to be used when the AI finds no tasks, got %+v", parsed)
+	}
+
+	if _, err := ParseTasks(context.Background(), cfg, &aitest.StaticPlanner{}, " , "); err == nil {
+		t.Error("ParseTasks() expected an error for an empty list")
+	}
+}
+
+func TestServiceAnalyze(t *testing.T) {
+	meeting := func(title, start, end string) calendar.Event {
+		return calendar.Event{Type: planner.BlockTypeMeeting, Title: title, Start: calendartest.At(start), End: calendartest.At(end)}
+	}
+	oneOnOne := meeting("1:1", "10:30", "11:00")
+	oneOnOne.Organized = true
+	sync := meeting("Sync", "14:30", "15:00")
+	sync.Description = "Weekly sync #movable"
+
+	cal := calendartest.NewMemory()
+	cal.Add("primary",
+		meeting("Standup", "09:30", "09:45"), oneOnOne, sync, meeting("Review", "16:00", "16:30"),
+		managed(planner.BlockTypeFocus, "Write docs", "13:00", "14:00"))
+	service := newTestService(testConfig(), cal, &aitest.StaticPlanner{})
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	analysis, err := service.Analyze(context.Background(), day)
+	if err != nil {
+		t.Fatalf("Analyze() error: %v", err)
+	}
+
+	if len(analysis.Movable) != 2 {
+		t.Fatalf("expected the organized and tagged meetings to be movable, got %+v", analysis.Movable)
+	}
+	want := []string{"1:1 (10:30 - 11:00 → 09:00 - 09:30)", "Sync (14:30 - 15:00 → 16:30 - 17:00)"}
+	if len(analysis.Moves) != len(want) {
+		t.Fatalf("Moves = %v, want %v", analysis.Moves, want)
+	}
+	for i, m := range analysis.Moves {
+		if m.String() != want[i] {
+			t.Errorf("move %d = %s, want %s", i, m, want[i])
+		}
+	}
+	before, after := analysis.Before, analysis.After
+	if before.Largest != 90*time.Minute || before.Score() != 71 || after.Largest != 3*time.Hour || after.Windows != 2 || after.Score() != 43 {
+		t.Errorf("unexpected fragmentation: before %+v (%d), after %+v (%d)", before, before.Score(), after, after.Score())
+	}
+	if events := cal.Events("primary"); !events[1].Start.Equal(calendartest.At("10:30")) {
+		t.Errorf("expected Analyze to leave the calendar unchanged, got %+v", events[1])
+	}
+
+	skipped, err := service.ApplyMoves(context.Background(), analysis.Moves)
+	if err != nil {
+		t.Fatalf("ApplyMoves() error: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0].Meeting.Title != "Sync" {
+		t.Errorf("expected the tagged meeting organized by someone else to be skipped, got %v", skipped)
+	}
+	events := cal.Events("primary")
+	if events[0].Title != "1:1" {
+		t.Errorf("expected the 1:1 to be moved, got %+v", events)
+	}
+	if i := slices.IndexFunc(events, func(e calendar.Event) bool { return e.Title == "Sync" }); !events[i].Start.Equal(calendartest.At("14:30")) {
+		t.Errorf("expected Sync to stay at 14:30, got %+v", events[i])
+	}
+}
+
+func TestServiceAnalyzeToday(t *testing.T) {
+	oneOnOne := calendar.Event{Type: planner.BlockTypeMeeting, Title: "1:1", Start: calendartest.At("15:00"), End: calendartest.At("15:30"), Organized: true}
+	standup := calendar.Event{Type: planner.BlockTypeMeeting, Title: "Standup", Start: calendartest.At("09:30"), End: calendartest.At("09:45"), Organized: true}
+	cal := calendartest.NewMemory()
+	cal.Add("primary", standup, oneOnOne)
+	service := newTestService(testConfig(), cal, &aitest.StaticPlanner{})
+
+	tests := []struct {
+		name string
+		now  string
+		want []string
+	}{
+		{"during the day", "10:50", []string{"1:1 (15:00 - 15:30 → 11:30 - 12:00)"}},
+		{"after work", "18:00", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service.SetClock(func() time.Time { return calendartest.At(tt.now) })
+
+			day, err := service.PrepareFullDay(context.Background(), calendartest.Date)
+			if err != nil {
+				t.Fatalf("PrepareFullDay() error: %v", err)
+			}
+			analysis, err := service.Analyze(context.Background(), day)
+			if err != nil {
+				t.Fatalf("Analyze() error: %v", err)
+			}
+
+			// The score covers the whole day, but the standup is over and stays
+			if analysis.Before.Free != 6*time.Hour+15*time.Minute {
+				t.Errorf("Before.Free = %v, want the free time of the full work day", analysis.Before.Free)
+			}
+			var got []string
+			for _, m := range analysis.Moves {
+				got = append(got, m.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Moves = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceApplyMovesMovesBack(t *testing.T) {
+	oneOnOne := calendar.Event{Type: planner.BlockTypeMeeting, Title: "1:1", Start: calendartest.At("10:30"), End: calendartest.At("11:00"), Organized: true}
+	cal := calendartest.NewMemory()
+	cal.Add("primary", oneOnOne)
+	oneOnOne = cal.Events("primary")[0]
+	service := newTestService(testConfig(), cal, &aitest.StaticPlanner{})
+
+	gone := calendar.Event{ID: "deleted", Title: "Planning", Start: calendartest.At("14:00"), End: calendartest.At("15:00"), Organized: true}
+	_, err := service.ApplyMoves(context.Background(), []Move{
+		{Meeting: oneOnOne, Start: calendartest.At("09:00"), End: calendartest.At("09:30")},
+		{Meeting: gone, Start: calendartest.At("15:00"), End: calendartest.At("16:00")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "moved back") {
+		t.Fatalf("ApplyMoves() error = %v, want a failure that moved the 1:1 back", err)
+	}
+	if events := cal.Events("primary"); !events[0].Start.Equal(calendartest.At("10:30")) {
+		t.Errorf("expected the 1:1 to be back at 10:30, got %+v", events[0])
+	}
+}
+
+func TestServiceAnalyzeAttendees(t *testing.T) {
+	oneOnOne := calendar.Event{
+		Type:      planner.BlockTypeMeeting,
+		Title:     "1:1",
+		Start:     calendartest.At("10:30"),
+		End:       calendartest.At("11:00"),
+		Organized: true,
+		Attendees: []string{"bob@example.com", "carol@example.com"},
+	}
+	cal := calendartest.NewMemory()
+	cal.Add("primary", oneOnOne)
+	// Bob's busy time includes the 1:1 itself, merged with the meeting after it
+	cal.Add("bob@example.com", calendar.Event{Title: "Busy", Start: calendartest.At("10:30"), End: calendartest.At("12:00")})
+	cal.Private = map[string]bool{"carol@example.com": true}
+	service := newTestService(testConfig(), cal, &aitest.StaticPlanner{})
+
+	day, err := service.PrepareDay(context.Background(), calendartest.Date)
+	if err != nil {
+		t.Fatalf("PrepareDay() error: %v", err)
+	}
+	analysis, err := service.Analyze(context.Background(), day)
+	if err != nil {
+		t.Fatalf("Analyze() error: %v", err)
+	}
+
+	// Next to lunch would be closer, but Bob is busy then
+	want := "1:1 (10:30 - 11:00 → 09:00 - 09:30)"
+	if len(analysis.Moves) != 1 || analysis.Moves[0].String() != want {
+		t.Fatalf("Moves = %v, want [%s]", analysis.Moves, want)
+	}
+	if len(analysis.Unchecked) != 1 || analysis.Unchecked[0] != "carol@example.com" {
+		t.Errorf("Unchecked = %v, want carol@example.com", analysis.Unchecked)
+	}
+}
@@ -0,0 +1,53 @@
+package planning
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// TaskParser turns a to-do list written in plain language into tasks. It is
+// implemented by ai.Client.
+type TaskParser interface {
+	ParseTasks(ctx context.Context, text string, hints ai.TaskHints) ([]planner.Task, error)
+}
+
+// ParsedTasks is a to-do list turned into tasks.
+type ParsedTasks struct {
+	Tasks []planner.Task
+	// Fallback is set to the reason the planner could not parse the list when
+	// the tasks were parsed by planner.ParseNaturalTasks instead.
+	Fallback error
+}
+
+// ParseTasks parses a to-do list written in plain language with p when it is a
+// TaskParser, and falls back to the patterns of planner.ParseNaturalTasks when it
+// is not or fails. Lunch and work hours come from cfg. An error is only returned
+// when no task was found at all or ctx is done.
+func ParseTasks(ctx context.Context, cfg *config.Config, p Planner, text string) (*ParsedTasks, error) {
+	result := &ParsedTasks{}
+	if parser, ok := p.(TaskParser); ok {
+		result.Tasks, result.Fallback = parser.ParseTasks(ctx, text, ai.TaskHints{
+			WorkStart:  cfg.WorkHours.Start,
+			WorkEnd:    cfg.WorkHours.End,
+			LunchStart: cfg.LunchTime.Start,
+			LunchEnd:   cfg.LunchTime.End,
+		})
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	} else {
+		result.Fallback = fmt.Errorf("the planner does not parse tasks")
+	}
+
+	if result.Fallback != nil {
+		result.Tasks = planner.ParseNaturalTasks(text, cfg.LunchTime.Start, cfg.LunchTime.End)
+	}
+	if len(result.Tasks) == 0 {
+		return nil, fmt.Errorf("no tasks found in %q", text)
+	}
+	return result, nil
+}
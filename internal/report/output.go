@@ -0,0 +1,61 @@
+package report
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"text/tabwriter"
+)
+
+//go:embed report.html.tmpl
+var htmlTemplate string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"comparison": comparison,
+}).Parse(htmlTemplate))
+
+// WriteTable prints the report as an aligned text table.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Week %s to %s\n\n", r.From, r.To)
+	fmt.Fprintln(tw, "DAY\tMEETINGS\tFOCUS\tLARGEST FREE BLOCK\tCONTEXT SWITCHES")
+	for _, d := range r.Days {
+		fmt.Fprintf(tw, "%s\t%.1fh\t%.1fh\t%dmin\t%d\n", d.Date, d.MeetingHours, d.FocusHours, d.LargestFreeMinutes, d.ContextSwitches)
+	}
+	fmt.Fprintf(tw, "TOTAL\t%.1fh\t%.1fh\t\t%d\n", r.MeetingHours, r.FocusHours, r.ContextSwitches)
+
+	if len(r.Targets) > 0 {
+		fmt.Fprintln(tw, "\nTARGET\tGOAL\tACTUAL\t")
+		for _, t := range r.Targets {
+			status := "✓"
+			if !t.Met {
+				status = "✗"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.1f\t%s\n", t.Name, comparison(t), t.Actual, status)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// WriteJSON prints the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteHTML writes the report as a self-contained HTML page.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+func comparison(t Target) string {
+	if t.AtMost {
+		return fmt.Sprintf("≤ %g", t.Target)
+	}
+	return fmt.Sprintf("≥ %g", t.Target)
+}
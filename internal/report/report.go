@@ -0,0 +1,184 @@
+// Package report computes weekly focus time, meeting load and fragmentation
+// statistics from calendar events and compares them to the configured targets.
+package report
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// Day holds the statistics of one workday.
+type Day struct {
+	Date         string  `json:"date"`
+	MeetingHours float64 `json:"meeting_hours"`
+	// FocusHours counts focus blocks created by Barely In Charge.
+	FocusHours float64 `json:"focus_hours"`
+	// LargestFreeMinutes is the longest stretch of work hours without meetings.
+	LargestFreeMinutes int `json:"largest_free_block_minutes"`
+	// ContextSwitches counts changes between meetings and focus tasks.
+	ContextSwitches int `json:"context_switches"`
+}
+
+// Target is the result of comparing a statistic to a configured target.
+type Target struct {
+	Name   string  `json:"name"`
+	Target float64 `json:"target"`
+	Actual float64 `json:"actual"`
+	// AtMost is set for upper limits; other targets are lower limits.
+	AtMost bool `json:"at_most"`
+	Met    bool `json:"met"`
+}
+
+type Report struct {
+	From            string   `json:"from"`
+	To              string   `json:"to"`
+	Days            []Day    `json:"days"`
+	MeetingHours    float64  `json:"meeting_hours"`
+	FocusHours      float64  `json:"focus_hours"`
+	ContextSwitches int      `json:"context_switches"`
+	Targets         []Target `json:"targets,omitempty"`
+}
+
+// Week returns Monday to Friday of the week containing date.
+func Week(date time.Time) []time.Time {
+	y, m, d := date.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+	offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+	monday := day.AddDate(0, 0, -offset)
+
+	days := make([]time.Time, 5)
+	for i := range days {
+		days[i] = monday.AddDate(0, 0, i)
+	}
+	return days
+}
+
+// Build computes the report for the given days from the calendar events.
+func Build(events []calendar.Event, days []time.Time, workHours config.TimeRange, targets config.Targets) (*Report, error) {
+	r := &Report{}
+	if len(days) > 0 {
+		r.From = days[0].Format(config.DateFormat)
+		r.To = days[len(days)-1].Format(config.DateFormat)
+	}
+
+	for _, date := range days {
+		workStart, err := planner.ParseTimeOnDate(workHours.Start, date)
+		if err != nil {
+			return nil, err
+		}
+		workEnd, err := planner.ParseTimeOnDate(workHours.End, date)
+		if err != nil {
+			return nil, err
+		}
+
+		day := buildDay(events, workStart, workEnd)
+		day.Date = date.Format(config.DateFormat)
+		r.Days = append(r.Days, day)
+
+		r.MeetingHours += day.MeetingHours
+		r.FocusHours += day.FocusHours
+		r.ContextSwitches += day.ContextSwitches
+	}
+
+	r.Targets = r.checkTargets(targets)
+	return r, nil
+}
+
+func buildDay(events []calendar.Event, workStart, workEnd time.Time) Day {
+	var meetings, focus []planner.TimeBlock
+	var activities []calendar.Event
+	for _, e := range events {
+		if !e.Start.Before(workEnd) || !e.End.After(workStart) {
+			continue
+		}
+		switch {
+		case !e.Managed:
+			meetings = append(meetings, e.ToTimeBlock())
+			activities = append(activities, e)
+		case e.Type == planner.BlockTypeFocus:
+			focus = append(focus, e.ToTimeBlock())
+			activities = append(activities, e)
+		}
+	}
+
+	largestFree := time.Duration(0)
+	for _, w := range planner.FreeWindows(meetings, workStart, workEnd) {
+		largestFree = max(largestFree, w.End.Sub(w.Start))
+	}
+
+	return Day{
+		MeetingHours:       covered(meetings, workStart, workEnd).Hours(),
+		FocusHours:         covered(focus, workStart, workEnd).Hours(),
+		LargestFreeMinutes: int(largestFree.Minutes()),
+		ContextSwitches:    contextSwitches(activities),
+	}
+}
+
+// covered returns how much of the window is covered by blocks, counting overlaps once.
+func covered(blocks []planner.TimeBlock, start, end time.Time) time.Duration {
+	free := time.Duration(0)
+	for _, w := range planner.FreeWindows(blocks, start, end) {
+		free += w.End.Sub(w.Start)
+	}
+	return end.Sub(start) - free
+}
+
+// contextSwitches counts the changes of activity through the day. Consecutive focus
+// blocks of the same task, e.g. pomodoros separated by breaks, are one activity.
+func contextSwitches(activities []calendar.Event) int {
+	slices.SortStableFunc(activities, func(a, b calendar.Event) int { return a.Start.Compare(b.Start) })
+
+	switches := 0
+	for i := 1; i < len(activities); i++ {
+		if activityKey(activities[i]) != activityKey(activities[i-1]) {
+			switches++
+		}
+	}
+	return switches
+}
+
+func activityKey(e calendar.Event) string {
+	if !e.Managed {
+		return "meeting:" + e.Title
+	}
+	// Focus blocks are titled "Focus time"; the task is at the end of the description
+	task := e.Description
+	if i := strings.LastIndex(task, ": "); i >= 0 {
+		task = task[i+2:]
+	}
+	return "focus:" + planner.TaskTitle(task)
+}
+
+func (r *Report) checkTargets(t config.Targets) []Target {
+	var targets []Target
+
+	if t.FocusHoursPerWeek > 0 {
+		targets = append(targets, atLeast("Focus hours", t.FocusHoursPerWeek, r.FocusHours))
+	}
+	if t.MaxMeetingHoursPerWeek > 0 {
+		targets = append(targets, atMost("Meeting hours", t.MaxMeetingHoursPerWeek, r.MeetingHours))
+	}
+	if t.MinFreeBlockMinutes > 0 && len(r.Days) > 0 {
+		worst := slices.MinFunc(r.Days, func(a, b Day) int { return a.LargestFreeMinutes - b.LargestFreeMinutes })
+		targets = append(targets, atLeast("Largest free block on the worst day (min)", float64(t.MinFreeBlockMinutes), float64(worst.LargestFreeMinutes)))
+	}
+	if t.MaxContextSwitchesPerDay > 0 && len(r.Days) > 0 {
+		busiest := slices.MaxFunc(r.Days, func(a, b Day) int { return a.ContextSwitches - b.ContextSwitches })
+		targets = append(targets, atMost("Context switches on the busiest day", float64(t.MaxContextSwitchesPerDay), float64(busiest.ContextSwitches)))
+	}
+
+	return targets
+}
+
+func atLeast(name string, target, actual float64) Target {
+	return Target{Name: name, Target: target, Actual: actual, Met: actual >= target}
+}
+
+func atMost(name string, target, actual float64) Target {
+	return Target{Name: name, Target: target, Actual: actual, AtMost: true, Met: actual <= target}
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+var monday = time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+
+func at(day int, hhmm string) time.Time {
+	t, err := planner.ParseTimeOnDate(hhmm, monday.AddDate(0, 0, day))
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func meeting(day int, title, start, end string) calendar.Event {
+	return calendar.Event{Type: planner.BlockTypeMeeting, Title: title, Start: at(day, start), End: at(day, end)}
+}
+
+func focus(day int, task, start, end string) calendar.Event {
+	return calendar.Event{
+		Type:        planner.BlockTypeFocus,
+		Title:       "Focus time",
+		Description: "Focus time block planned by Barely In Charge: " + task,
+		Start:       at(day, start),
+		End:         at(day, end),
+		Managed:     true,
+	}
+}
+
+func TestWeek(t *testing.T) {
+	days := Week(time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC)) // Thursday
+	if len(days) != 5 || !days[0].Equal(monday) || days[4].Weekday() != time.Friday {
+		t.Errorf("Week() = %v", days)
+	}
+	if sunday := Week(time.Date(2030, 1, 13, 0, 0, 0, 0, time.UTC)); !sunday[0].Equal(monday) {
+		t.Errorf("Week() of a Sunday should start on the Monday before, got %v", sunday[0])
+	}
+}
+
+func TestBuild(t *testing.T) {
+	events := []calendar.Event{
+		meeting(0, "Standup", "09:00", "09:30"),
+		meeting(0, "Overlapping", "09:15", "10:00"),
+		focus(0, "Write docs (1/2)", "10:00", "10:30"),
+		{Type: planner.BlockTypeBreak, Title: "Break", Start: at(0, "10:30"), End: at(0, "10:45"), Managed: true},
+		focus(0, "Write docs (2/2)", "10:45", "11:15"),
+		meeting(0, "1:1", "14:00", "15:00"),
+		meeting(0, "After hours", "17:30", "18:30"),
+		focus(1, "Review PRs", "09:00", "11:00"),
+	}
+	targets := config.Targets{FocusHoursPerWeek: 4, MaxMeetingHoursPerWeek: 5, MinFreeBlockMinutes: 120, MaxContextSwitchesPerDay: 3}
+
+	r, err := Build(events, Week(monday), config.TimeRange{Start: "09:00", End: "17:00"}, targets)
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+
+	if r.From != "2030-01-07" || r.To != "2030-01-11" || len(r.Days) != 5 {
+		t.Fatalf("unexpected range: %s to %s, %d days", r.From, r.To, len(r.Days))
+	}
+
+	want := Day{Date: "2030-01-07", MeetingHours: 2, FocusHours: 1, LargestFreeMinutes: 240, ContextSwitches: 3}
+	if r.Days[0] != want {
+		t.Errorf("Monday = %+v, want %+v", r.Days[0], want)
+	}
+	if r.Days[1].FocusHours != 2 || r.Days[1].LargestFreeMinutes != 480 || r.Days[1].ContextSwitches != 0 {
+		t.Errorf("unexpected Tuesday: %+v", r.Days[1])
+	}
+	if r.MeetingHours != 2 || r.FocusHours != 3 || r.ContextSwitches != 3 {
+		t.Errorf("unexpected totals: %+v", r)
+	}
+
+	met := map[string]bool{}
+	for _, target := range r.Targets {
+		met[target.Name] = target.Met
+	}
+	wantMet := map[string]bool{
+		"Focus hours":   false,
+		"Meeting hours": true,
+		"Largest free block on the worst day (min)": true,
+		"Context switches on the busiest day":       true,
+	}
+	for name, want := range wantMet {
+		if got, ok := met[name]; !ok || got != want {
+			t.Errorf("target %q met = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	r, err := Build([]calendar.Event{meeting(0, "<script>", "09:00", "10:00")}, Week(monday),
+		config.TimeRange{Start: "09:00", End: "17:00"}, config.Targets{FocusHoursPerWeek: 10})
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteHTML(&buf); err != nil {
+		t.Fatalf("WriteHTML() error: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{"<!DOCTYPE html>", "2030-01-07", "Focus hours", "✗ missed", "≥ 10"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML report is missing %q", want)
+		}
+	}
+}
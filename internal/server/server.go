@@ -0,0 +1,460 @@
+// Package server exposes the plan pipeline over a local REST/JSON API and serves
+// a small web page to preview, adjust and apply a plan.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/history"
+	"github.com/Alvkoen/barely-incharge/internal/httpx"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+// maxBodyBytes limits the size of request bodies.
+const maxBodyBytes = 1 << 20
+
+// Server handles the API and web page requests.
+type Server struct {
+	cfg      *config.Config
+	calendar planning.Calendar
+	planner  planning.Planner
+	history  *history.Store
+	now      func() time.Time
+}
+
+// New returns a server planning with the given clients. Applied plans are
+// recorded in store.
+func New(cfg *config.Config, cal planning.Calendar, p planning.Planner, store *history.Store) *Server {
+	return &Server{
+		cfg:      cfg,
+		calendar: cal,
+		planner:  p,
+		history:  store,
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the function used to get the current time.
+func (s *Server) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Handler returns the HTTP handler with all routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /plan", sameOrigin(s.handlePlan))
+	mux.HandleFunc("GET /plans/{date}", sameOrigin(s.handleListPlans))
+	mux.HandleFunc("DELETE /plans/{id}", sameOrigin(s.handleDeletePlan))
+
+	static, err := fs.Sub(webFS, "web")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("GET /", http.FileServerFS(static))
+
+	return mux
+}
+
+// PlanRequest is the body of POST /plan.
+type PlanRequest struct {
+	// Tasks uses the same format as plan --tasks.
+	Tasks string `json:"tasks"`
+	// Mode defaults to the configured default mode.
+	Mode string `json:"mode,omitempty"`
+	// Date (YYYY-MM-DD) defaults to the configured planning date.
+	Date string `json:"date,omitempty"`
+	// Blocks replaces the generated plan with adjusted blocks; the planner is
+	// not called when set.
+	Blocks []ai.Block `json:"blocks,omitempty"`
+	// Apply creates the blocks in the calendar. Without it the plan is only
+	// previewed.
+	Apply bool `json:"apply,omitempty"`
+}
+
+// PlanResponse is the body returned by POST /plan.
+type PlanResponse struct {
+	Date        string   `json:"date"`
+	Mode        string   `json:"mode"`
+	WorkStart   string   `json:"work_start"`
+	WorkEnd     string   `json:"work_end"`
+	Meetings    []Block  `json:"meetings"`
+	Blocks      []Block  `json:"blocks"`
+	Unscheduled []string `json:"unscheduled,omitempty"`
+	Applied     bool     `json:"applied"`
+	RecordID    string   `json:"record_id,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// Block is a block or meeting on the timeline, with times as HH:MM.
+type Block struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	EventID string `json:"event_id,omitempty"`
+	Status  string `json:"status,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// sameOrigin rejects API requests that a web page on another site could send on
+// the user's behalf: requests from another origin, requests for a host name that
+// is not this server (DNS rebinding) and bodies that are not JSON, which browsers
+// would send without asking the server first.
+func sameOrigin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isLocalHost(r) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %s is not allowed", r.Host))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Host != r.Host {
+				writeError(w, http.StatusForbidden, fmt.Errorf("origin %s is not allowed", origin))
+				return
+			}
+		}
+		if r.Method == http.MethodPost {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("content type must be application/json"))
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// isLocalHost reports whether the request is addressed to a loopback name or to
+// the address the server accepted it on.
+func isLocalHost(r *http.Request) bool {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && r.Host == addr.String() {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	var req PlanRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	modeName := strings.TrimSpace(req.Mode)
+	if modeName == "" {
+		modeName = s.cfg.DefaultMode
+	}
+	mode, err := s.cfg.GetMode(modeName)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	date, err := s.planningDate(req.Date)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tasks := planner.ParseTaskList(req.Tasks)
+	if len(tasks) == 0 && len(req.Blocks) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("tasks are required"))
+		return
+	}
+
+	ctx := r.Context()
+	service := planning.NewService(s.cfg, s.calendar, s.planner)
+	service.SetClock(s.now)
+
+	day, err := service.PrepareDay(ctx, date)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	var plan *planning.Plan
+	if len(req.Blocks) > 0 {
+		plan, err = adjustedPlan(day, mode, tasks, req.Blocks)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	} else {
+		plan, err = service.Generate(ctx, day, mode, tasks)
+		if err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+	}
+
+	resp := newPlanResponse(plan)
+	if !req.Apply {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	record := history.NewRecord(date, s.cfg.Calendar, mode, tasks)
+	record.SetPlan(plan)
+	tx, applyErr := service.Apply(ctx, plan, planning.ApplyOptions{})
+	record.SetTransaction(tx)
+	record.SetError(applyErr)
+	if err := s.history.Append(record); err != nil {
+		// The blocks are in the calendar either way; report the plan as applied
+		resp.Error = fmt.Sprintf("failed to save plan history: %v", err)
+	}
+
+	resp.RecordID = record.ID
+	for i, b := range record.Blocks {
+		resp.Blocks[i].EventID = b.EventID
+		resp.Blocks[i].Status = b.Status
+	}
+
+	if applyErr != nil {
+		resp.Error = applyErr.Error()
+		writeJSON(w, statusFor(applyErr), resp)
+		return
+	}
+	resp.Applied = true
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleListPlans(w http.ResponseWriter, r *http.Request) {
+	date := r.PathValue("date")
+	if _, err := time.Parse(config.DateFormat, date); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid date (expected YYYY-MM-DD): %w", err))
+		return
+	}
+
+	records, err := s.history.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	plans := []history.Record{}
+	for _, record := range records {
+		if record.Date == date {
+			plans = append(plans, record)
+		}
+	}
+	writeJSON(w, http.StatusOK, plans)
+}
+
+func (s *Server) handleDeletePlan(w http.ResponseWriter, r *http.Request) {
+	record, err := s.history.Get(r.PathValue("id"))
+	if errors.Is(err, history.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !record.DeletedAt.IsZero() {
+		writeError(w, http.StatusConflict, fmt.Errorf("plan #%s was already deleted", record.ID))
+		return
+	}
+
+	deleteErr := s.deleteEvents(r.Context(), &record)
+	if deleteErr == nil {
+		record.DeletedAt = s.now()
+	}
+	if err := s.history.Update(record); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleteErr != nil {
+		writeError(w, statusFor(deleteErr), deleteErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+// deleteEvents removes the events created by a plan run and marks their blocks
+// as deleted. Blocks whose event could not be deleted keep their status, so the
+// request can be retried.
+func (s *Server) deleteEvents(ctx context.Context, record *history.Record) error {
+	var errs []error
+	for i := range record.Blocks {
+		b := &record.Blocks[i]
+		if b.Status != history.StatusCreated || b.EventID == "" {
+			continue
+		}
+		if err := s.calendar.DeleteEvent(ctx, record.Calendar, b.EventID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete '%s': %w", b.Title, err))
+			continue
+		}
+		b.Status = history.StatusDeleted
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) planningDate(value string) (time.Time, error) {
+	if value == "" {
+		return s.cfg.GetPlanningDate()
+	}
+	date, err := time.ParseInLocation(config.DateFormat, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date (expected YYYY-MM-DD): %w", err)
+	}
+	return date, nil
+}
+
+// adjustedPlan builds a plan from blocks edited on the web page. The blocks must
+// stay within the work hours left and must not overlap each other or busy time:
+// meetings for every block, and lunch and routines for the blocks planned around
+// them.
+func adjustedPlan(day *planning.Day, mode config.Mode, tasks []planner.Task, blocks []ai.Block) (*planning.Plan, error) {
+	plan := &planning.Plan{Day: day, Mode: mode, Tasks: tasks}
+	for i, b := range blocks {
+		if err := validateBlock(b); err != nil {
+			return nil, fmt.Errorf("invalid block %d: %w", i+1, err)
+		}
+		block, err := b.ToTimeBlock(day.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block %d: %w", i+1, err)
+		}
+		if block.Start.Before(day.WorkStart) || block.End.After(day.WorkEnd) {
+			return nil, fmt.Errorf("block %d (%s) is outside work hours %s - %s", i+1, b.Title,
+				day.WorkStart.Format(planner.TimeFormat), day.WorkEnd.Format(planner.TimeFormat))
+		}
+		if busy, ok := overlapping(block, busyFor(day, block)); ok {
+			return nil, fmt.Errorf("block %d (%s) overlaps %s %s - %s", i+1, b.Title, busy.Title,
+				busy.Start.Format(planner.TimeFormat), busy.End.Format(planner.TimeFormat))
+		}
+		if other, ok := overlapping(block, plan.Blocks); ok {
+			return nil, fmt.Errorf("block %d (%s) overlaps %s %s - %s", i+1, b.Title, other.Title,
+				other.Start.Format(planner.TimeFormat), other.End.Format(planner.TimeFormat))
+		}
+		plan.Blocks = append(plan.Blocks, block)
+	}
+	return plan, nil
+}
+
+// busyFor returns the busy time block has to keep clear of. Lunch and routine
+// blocks take the place of the busy time planned for them.
+func busyFor(day *planning.Day, block planner.TimeBlock) []planner.TimeBlock {
+	var busy []planner.TimeBlock
+	for _, m := range day.Meetings {
+		busy = append(busy, m.ToTimeBlock())
+	}
+	if block.Type == planner.BlockTypeLunch || block.Type == planner.BlockTypeRoutine {
+		return busy
+	}
+	lunch := planner.TimeBlock{Type: planner.BlockTypeLunch, Title: "Lunch", Start: day.LunchStart, End: day.LunchEnd}
+	return append(append(busy, lunch), day.Routines...)
+}
+
+// overlapping returns the first of others that overlaps b.
+func overlapping(b planner.TimeBlock, others []planner.TimeBlock) (planner.TimeBlock, bool) {
+	for _, o := range others {
+		if b.Start.Before(o.End) && b.End.After(o.Start) {
+			return o, true
+		}
+	}
+	return planner.TimeBlock{}, false
+}
+
+// validateBlock accepts the block types of a final plan: the focus and break
+// blocks from the planner plus lunch, routines and an early finish.
+func validateBlock(b ai.Block) error {
+	if b.Type == planner.BlockTypeLunch || b.Type == planner.BlockTypeRoutine || b.Type == planner.BlockTypeOutOfOffice {
+		start, err := time.Parse(planner.TimeFormat, b.Start)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %w", err)
+		}
+		end, err := time.Parse(planner.TimeFormat, b.End)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %w", err)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("end %s is not after start %s", b.End, b.Start)
+		}
+		return nil
+	}
+	return b.Validate()
+}
+
+func newPlanResponse(plan *planning.Plan) PlanResponse {
+	day := plan.Day
+	resp := PlanResponse{
+		Date:      day.Date.Format(config.DateFormat),
+		Mode:      plan.Mode.Name,
+		WorkStart: day.WorkStart.Format(planner.TimeFormat),
+		WorkEnd:   day.WorkEnd.Format(planner.TimeFormat),
+		Meetings:  []Block{},
+		Blocks:    make([]Block, len(plan.Blocks)),
+	}
+	for _, m := range day.Meetings {
+		resp.Meetings = append(resp.Meetings, Block{
+			Type:  m.Type,
+			Title: m.Title,
+			Start: m.Start.Format(planner.TimeFormat),
+			End:   m.End.Format(planner.TimeFormat),
+		})
+	}
+	for i, b := range plan.Blocks {
+		resp.Blocks[i] = Block{
+			Type:  b.Type,
+			Title: b.Title,
+			Start: b.Start.Format(planner.TimeFormat),
+			End:   b.End.Format(planner.TimeFormat),
+		}
+	}
+	for _, t := range plan.Unscheduled {
+		resp.Unscheduled = append(resp.Unscheduled, t.Title)
+	}
+	return resp
+}
+
+// statusFor maps errors from the calendar and the planner to a response status.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return 499
+	case errors.Is(err, httpx.ErrQuota):
+		return http.StatusTooManyRequests
+	case errors.Is(err, httpx.ErrTransient), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, httpx.ErrAuth), errors.Is(err, httpx.ErrBadRequest):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/history"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *calendartest.Memory, *aitest.StaticPlanner, *history.Store) {
+	t.Helper()
+
+	cfg := &config.Config{
+		WorkHours:   config.TimeRange{Start: "09:00", End: "17:00"},
+		LunchTime:   config.TimeRange{Start: "12:00", End: "13:00"},
+		Calendar:    "primary",
+		DefaultMode: config.ModeNormal,
+	}
+	cal := calendartest.NewMemory()
+	cal.Add("primary", calendar.Event{Type: planner.BlockTypeMeeting, Title: "Standup", Start: calendartest.LocalAt("10:00"), End: calendartest.LocalAt("10:15")})
+	fake := &aitest.StaticPlanner{Response: &ai.PlanResponse{Blocks: []ai.Block{
+		{Type: planner.BlockTypeFocus, Title: "Write docs", Start: "09:00", End: "10:00"},
+	}}}
+	store, err := history.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("history.Open() error: %v", err)
+	}
+
+	s := New(cfg, cal, fake, store)
+	s.SetClock(func() time.Time { return calendartest.LocalAt("00:00").AddDate(0, 0, -1) })
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts, cal, fake, store
+}
+
+func do(t *testing.T, method, url, body string, out any) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("failed to decode %s %s response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestPreviewAndApplyAdjustedPlan(t *testing.T) {
+	ts, cal, fake, store := newTestServer(t)
+
+	var preview PlanResponse
+	if status := do(t, http.MethodPost, ts.URL+"/plan", `{"tasks": "Write docs:L", "date": "2030-01-07"}`, &preview); status != http.StatusOK {
+		t.Fatalf("preview status = %d, want 200 (%+v)", status, preview)
+	}
+	if len(preview.Meetings) != 1 || len(preview.Blocks) != 2 || preview.Blocks[1].Type != planner.BlockTypeLunch {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if preview.Applied || len(cal.Events("primary")) != 1 {
+		t.Errorf("preview should not create events")
+	}
+
+	// Move the focus block after the standup, as if dragged on the web page
+	body := `{"tasks": "Write docs:L", "date": "2030-01-07", "apply": true, "blocks": [
+		{"type": "focus", "title": "Write docs", "start": "10:30", "end": "11:30"},
+		{"type": "lunch", "title": "Lunch", "start": "12:00", "end": "13:00"}
+	]}`
+	var applied PlanResponse
+	if status := do(t, http.MethodPost, ts.URL+"/plan", body, &applied); status != http.StatusOK {
+		t.Fatalf("apply status = %d, want 200 (%+v)", status, applied)
+	}
+	if len(fake.Requests()) != 1 {
+		t.Errorf("applying adjusted blocks should not call the planner again, got %d requests", len(fake.Requests()))
+	}
+	if !applied.Applied || applied.RecordID != "1" || applied.Blocks[0].Status != history.StatusCreated {
+		t.Fatalf("unexpected apply response: %+v", applied)
+	}
+
+	events := cal.Events("primary")
+	if len(events) != 3 || !events[1].Start.Equal(calendartest.LocalAt("10:30")) {
+		t.Errorf("unexpected calendar events: %+v", events)
+	}
+
+	record, err := store.Get("1")
+	if err != nil || record.Status() != "applied" {
+		t.Errorf("unexpected history record: %+v, %v", record, err)
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+	ts, _, _, _ := newTestServer(t)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"invalid json", `{`, http.StatusBadRequest},
+		{"no tasks", `{"date": "2030-01-07"}`, http.StatusBadRequest},
+		{"unknown mode", `{"tasks": "A", "mode": "nope"}`, http.StatusBadRequest},
+		{"invalid date", `{"tasks": "A", "date": "07.01.2030"}`, http.StatusBadRequest},
+		{"invalid block", `{"tasks": "A", "date": "2030-01-07", "blocks": [{"type": "focus", "title": "A", "start": "11:00", "end": "10:00"}]}`, http.StatusBadRequest},
+		{"block after work", `{"tasks": "A", "date": "2030-01-07", "apply": true, "blocks": [{"type": "focus", "title": "A", "start": "16:30", "end": "17:30"}]}`, http.StatusBadRequest},
+		{"block over a meeting", `{"tasks": "A", "date": "2030-01-07", "apply": true, "blocks": [{"type": "focus", "title": "A", "start": "09:30", "end": "10:30"}]}`, http.StatusBadRequest},
+		{"block over lunch", `{"tasks": "A", "date": "2030-01-07", "apply": true, "blocks": [{"type": "focus", "title": "A", "start": "12:30", "end": "13:30"}]}`, http.StatusBadRequest},
+		{"overlapping blocks", `{"tasks": "A", "date": "2030-01-07", "apply": true, "blocks": [
+			{"type": "focus", "title": "A", "start": "14:00", "end": "15:00"},
+			{"type": "break", "title": "Break", "start": "14:45", "end": "15:00"}]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp errorResponse
+			if status := do(t, http.MethodPost, ts.URL+"/plan", tt.body, &resp); status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+			if resp.Error == "" {
+				t.Errorf("expected an error message")
+			}
+		})
+	}
+}
+
+func TestRejectsCrossSiteRequests(t *testing.T) {
+	ts, cal, fake, _ := newTestServer(t)
+	body := `{"tasks": "Write docs:L", "date": "2030-01-07", "apply": true}`
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		origin      string
+		host        string
+		want        int
+	}{
+		// A form or fetch from another page that browsers send without a preflight
+		{"cross-origin text/plain", http.MethodPost, "/plan", "text/plain", "https://evil.example", "", http.StatusForbidden},
+		{"cross-origin json", http.MethodPost, "/plan", "application/json", "https://evil.example", "", http.StatusForbidden},
+		{"same-origin text/plain", http.MethodPost, "/plan", "text/plain;charset=UTF-8", ts.URL, "", http.StatusUnsupportedMediaType},
+		{"no content type", http.MethodPost, "/plan", "", "", "", http.StatusUnsupportedMediaType},
+		{"rebound host name", http.MethodPost, "/plan", "application/json", "", "evil.example:8080", http.StatusForbidden},
+		{"cross-origin delete", http.MethodDelete, "/plans/1", "", "https://evil.example", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("failed to build request: %v", err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	if len(fake.Requests()) != 0 || len(cal.Events("primary")) != 1 {
+		t.Errorf("rejected requests must not plan or create events")
+	}
+
+	// The page itself sends its own origin
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/plan", strings.NewReader(`{"tasks": "Write docs:L", "date": "2030-01-07"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", ts.URL)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("same-origin request status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestListAndDeletePlans(t *testing.T) {
+	ts, cal, _, _ := newTestServer(t)
+
+	if status := do(t, http.MethodPost, ts.URL+"/plan", `{"tasks": "Write docs:L", "date": "2030-01-07", "apply": true}`, nil); status != http.StatusOK {
+		t.Fatalf("apply status = %d, want 200", status)
+	}
+
+	var records []history.Record
+	if status := do(t, http.MethodGet, ts.URL+"/plans/2030-01-07", "", &records); status != http.StatusOK || len(records) != 1 {
+		t.Fatalf("GET /plans = %d, %+v", status, records)
+	}
+	if status := do(t, http.MethodGet, ts.URL+"/plans/2030-01-08", "", &records); status != http.StatusOK || len(records) != 0 {
+		t.Errorf("GET /plans for another day = %d, %+v", status, records)
+	}
+
+	var deleted history.Record
+	if status := do(t, http.MethodDelete, ts.URL+"/plans/1", "", &deleted); status != http.StatusOK {
+		t.Fatalf("DELETE status = %d, want 200", status)
+	}
+	if deleted.Status() != "deleted" || deleted.Blocks[0].Status != history.StatusDeleted {
+		t.Errorf("unexpected deleted record: %+v", deleted)
+	}
+	if events := cal.Events("primary"); len(events) != 1 || events[0].Title != "Standup" {
+		t.Errorf("expected only the meeting to remain, got %+v", events)
+	}
+
+	if status := do(t, http.MethodDelete, ts.URL+"/plans/1", "", nil); status != http.StatusConflict {
+		t.Errorf("second DELETE status = %d, want 409", status)
+	}
+	if status := do(t, http.MethodDelete, ts.URL+"/plans/9", "", nil); status != http.StatusNotFound {
+		t.Errorf("DELETE of a missing plan status = %d, want 404", status)
+	}
+}
+
+func TestWebPage(t *testing.T) {
+	ts, _, _, _ := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("GET / = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
@@ -0,0 +1,8 @@
+package server
+
+import "embed"
+
+// webFS holds the web page served at /.
+//
+//go:embed web
+var webFS embed.FS
@@ -0,0 +1,201 @@
+// Timeline editor for the plan API. Blocks can be dragged to move them and
+// resized from the bottom edge before applying; times snap to 5 minutes.
+const pxPerMinute = 1.2;
+const snapMinutes = 5;
+
+const form = document.getElementById("plan-form");
+const applyButton = document.getElementById("apply");
+const statusLine = document.getElementById("status");
+const timeline = document.getElementById("timeline");
+
+let plan = null;
+
+const toMinutes = (hhmm) => {
+  const [h, m] = hhmm.split(":").map(Number);
+  return h * 60 + m;
+};
+const toHHMM = (minutes) =>
+  String(Math.floor(minutes / 60)).padStart(2, "0") + ":" + String(minutes % 60).padStart(2, "0");
+
+function setStatus(text, isError) {
+  statusLine.textContent = text;
+  statusLine.className = isError ? "error" : "";
+}
+
+function request() {
+  const data = new FormData(form);
+  return { tasks: data.get("tasks"), mode: data.get("mode"), date: data.get("date") };
+}
+
+async function call(method, path, body) {
+  const resp = await fetch(path, {
+    method,
+    headers: { "Content-Type": "application/json" },
+    body: body ? JSON.stringify(body) : undefined,
+  });
+  const json = await resp.json();
+  if (!resp.ok) {
+    const err = new Error(json.error || resp.statusText);
+    err.body = json;
+    throw err;
+  }
+  return json;
+}
+
+async function generate(event) {
+  event.preventDefault();
+  setStatus("Generating plan...");
+  try {
+    plan = await call("POST", "/plan", request());
+    form.elements.date.value = plan.date;
+    applyButton.disabled = false;
+    setStatus(`Plan for ${plan.date} (${plan.mode}). Drag blocks to adjust, then apply.`);
+    render();
+    loadHistory();
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+}
+
+async function apply() {
+  setStatus("Creating blocks in calendar...");
+  applyButton.disabled = true;
+  try {
+    plan = await call("POST", "/plan", { ...request(), blocks: plan.blocks, apply: true });
+    setStatus(`Applied as plan #${plan.record_id}.`);
+  } catch (err) {
+    if (err.body && err.body.blocks) {
+      plan = err.body;
+    }
+    applyButton.disabled = false;
+    setStatus(err.message, true);
+  }
+  render();
+  loadHistory();
+}
+
+function render() {
+  timeline.replaceChildren();
+  if (!plan) {
+    return;
+  }
+
+  const dayStart = Math.floor(toMinutes(plan.work_start) / 60) * 60;
+  const dayEnd = Math.ceil(toMinutes(plan.work_end) / 60) * 60;
+  timeline.style.height = (dayEnd - dayStart) * pxPerMinute + "px";
+
+  for (let m = dayStart; m < dayEnd; m += 60) {
+    const hour = document.createElement("div");
+    hour.className = "hour";
+    hour.style.top = (m - dayStart) * pxPerMinute + "px";
+    hour.textContent = toHHMM(m);
+    timeline.appendChild(hour);
+  }
+
+  for (const meeting of plan.meetings) {
+    timeline.appendChild(blockElement(meeting, "meeting", dayStart));
+  }
+  plan.blocks.forEach((block) => {
+    const el = blockElement(block, block.type, dayStart);
+    if (!plan.applied) {
+      makeEditable(el, block, dayStart, dayEnd);
+    }
+    timeline.appendChild(el);
+  });
+
+  const unscheduled = document.getElementById("unscheduled");
+  unscheduled.replaceChildren(...(plan.unscheduled || []).map((title) => {
+    const li = document.createElement("li");
+    li.textContent = "No room left for: " + title;
+    return li;
+  }));
+}
+
+function blockElement(block, className, dayStart) {
+  const el = document.createElement("div");
+  el.className = `block ${className} ${block.status || ""}`;
+  place(el, block, dayStart);
+  el.textContent = `${block.start}–${block.end} ${block.title}`;
+  return el;
+}
+
+function place(el, block, dayStart) {
+  el.style.top = (toMinutes(block.start) - dayStart) * pxPerMinute + "px";
+  el.style.height = (toMinutes(block.end) - toMinutes(block.start)) * pxPerMinute + "px";
+}
+
+function makeEditable(el, block, dayStart, dayEnd) {
+  el.classList.add("editable");
+  const handle = document.createElement("div");
+  handle.className = "handle";
+  el.appendChild(handle);
+
+  el.addEventListener("pointerdown", (down) => {
+    down.preventDefault();
+    const resizing = down.target === handle;
+    const start = toMinutes(block.start);
+    const end = toMinutes(block.end);
+    el.setPointerCapture(down.pointerId);
+    el.classList.add("dragging");
+
+    const move = (e) => {
+      const delta = Math.round((e.clientY - down.clientY) / pxPerMinute / snapMinutes) * snapMinutes;
+      if (resizing) {
+        block.end = toHHMM(Math.min(dayEnd, Math.max(start + snapMinutes, end + delta)));
+      } else {
+        const shift = Math.min(dayEnd - end, Math.max(dayStart - start, delta));
+        block.start = toHHMM(start + shift);
+        block.end = toHHMM(end + shift);
+      }
+      place(el, block, dayStart);
+      el.firstChild.textContent = `${block.start}–${block.end} ${block.title}`;
+    };
+    const up = () => {
+      el.classList.remove("dragging");
+      el.removeEventListener("pointermove", move);
+      el.removeEventListener("pointerup", up);
+    };
+    el.addEventListener("pointermove", move);
+    el.addEventListener("pointerup", up);
+  });
+}
+
+async function loadHistory() {
+  const list = document.getElementById("history");
+  if (!form.elements.date.value) {
+    return;
+  }
+  try {
+    const records = await call("GET", "/plans/" + form.elements.date.value);
+    list.replaceChildren(...records.map((record) => {
+      const li = document.createElement("li");
+      const created = (record.blocks || []).filter((b) => b.status === "created").length;
+      li.textContent = `#${record.id} ${record.mode}, ${created} block(s) in calendar `;
+      if (created > 0 && !record.deleted_at) {
+        const button = document.createElement("button");
+        button.textContent = "Delete";
+        button.addEventListener("click", () => deletePlan(record.id));
+        li.appendChild(button);
+      } else if (record.deleted_at) {
+        li.append("(deleted)");
+      }
+      return li;
+    }));
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+}
+
+async function deletePlan(id) {
+  try {
+    await call("DELETE", "/plans/" + id);
+    setStatus(`Deleted the blocks of plan #${id}.`);
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+  loadHistory();
+}
+
+form.addEventListener("submit", generate);
+applyButton.addEventListener("click", apply);
+form.elements.date.addEventListener("change", loadHistory);
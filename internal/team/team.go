@@ -0,0 +1,182 @@
+// Package team finds the time of day when a whole team is free, to protect it
+// as a shared focus block. Busy time is read through the FreeBusy API, so
+// teammates only need to share their free/busy information.
+package team
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// MinWindow is the shortest shared window worth proposing as focus time.
+const MinWindow = 30 * time.Minute
+
+// Calendar reads free/busy information and creates the shared block. It is
+// implemented by calendar.GoogleClient.
+type Calendar interface {
+	FreeBusy(ctx context.Context, calendarIDs []string, start, end time.Time) ([]calendar.FreeBusy, error)
+	CanWrite(ctx context.Context, calendarID string) (bool, error)
+	CreateEvent(ctx context.Context, calendarID string, event calendar.Event) (string, error)
+}
+
+// Member is a teammate with work hours on the planning date.
+type Member struct {
+	Name      string
+	Calendar  string
+	WorkStart time.Time
+	WorkEnd   time.Time
+	Busy      []planner.TimeBlock
+	// Err is set when the busy time of the member could not be read.
+	Err error
+}
+
+// Members returns the user, as "You", followed by the teammates in cfg, with
+// their work hours resolved on date in their time zones.
+func Members(cfg *config.Config, date time.Time) ([]Member, error) {
+	all := append([]config.TeamMember{{Name: "You", Calendar: cfg.Calendar}}, cfg.Team.Members...)
+	members := make([]Member, 0, len(all))
+	for _, m := range all {
+		hours := m.WorkHours
+		if hours == (config.TimeRange{}) {
+			hours = cfg.WorkHours
+		}
+		loc, err := m.Location()
+		if err != nil {
+			return nil, err
+		}
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+		start, err := planner.ParseTimeOnDate(hours.Start, day)
+		if err != nil {
+			return nil, fmt.Errorf("invalid work start time for %s: %w", m.Label(), err)
+		}
+		end, err := planner.ParseTimeOnDate(hours.End, day)
+		if err != nil {
+			return nil, fmt.Errorf("invalid work end time for %s: %w", m.Label(), err)
+		}
+		members = append(members, Member{Name: m.Label(), Calendar: m.Calendar, WorkStart: start, WorkEnd: end})
+	}
+	return members, nil
+}
+
+// Result is the time a team is free together.
+type Result struct {
+	Members []Member
+	// Start and End bound the time within everyone's work hours. End is not
+	// after Start when work hours do not overlap.
+	Start time.Time
+	End   time.Time
+	// Windows lists the times within Start and End when nobody is busy, in order.
+	Windows []planner.TimeBlock
+}
+
+// Find reads the busy time of every member and returns the windows when all of
+// them are within work hours and free. Members whose busy time cannot be read
+// are reported with Err set; only their work hours are taken into account.
+func Find(ctx context.Context, cal Calendar, members []Member) (*Result, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no team members")
+	}
+
+	r := &Result{Members: slices.Clone(members)}
+	ids := make([]string, len(members))
+	var from, to time.Time
+	for i, m := range members {
+		ids[i] = m.Calendar
+		if i == 0 || m.WorkStart.Before(from) {
+			from = m.WorkStart
+		}
+		if i == 0 || m.WorkEnd.After(to) {
+			to = m.WorkEnd
+		}
+		if i == 0 || m.WorkStart.After(r.Start) {
+			r.Start = m.WorkStart
+		}
+		if i == 0 || m.WorkEnd.Before(r.End) {
+			r.End = m.WorkEnd
+		}
+	}
+
+	freeBusy, err := cal.FreeBusy(ctx, ids, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var busy []planner.TimeBlock
+	for i, fb := range freeBusy {
+		r.Members[i].Busy, r.Members[i].Err = fb.Busy, fb.Err
+		if fb.Err == nil {
+			busy = append(busy, fb.Busy...)
+		}
+	}
+
+	if r.Start.Before(r.End) {
+		r.Windows = planner.FreeWindows(busy, r.Start, r.End)
+	}
+	return r, nil
+}
+
+// Propose returns a shared focus block of length in the earliest window long
+// enough for it, or the longest window when none is. It returns false when no
+// window is at least MinWindow long.
+func (r *Result) Propose(length time.Duration) (planner.TimeBlock, bool) {
+	var longest planner.TimeBlock
+	for _, w := range r.Windows {
+		if w.End.Sub(w.Start) >= length {
+			return planner.TimeBlock{Type: planner.BlockTypeMeeting, Start: w.Start, End: w.Start.Add(length)}, true
+		}
+		if w.End.Sub(w.Start) > longest.End.Sub(longest.Start) {
+			longest = w
+		}
+	}
+	if longest.End.Sub(longest.Start) < MinWindow {
+		return planner.TimeBlock{}, false
+	}
+	return planner.TimeBlock{Type: planner.BlockTypeMeeting, Start: longest.Start, End: longest.End}, true
+}
+
+// Created is the outcome of creating the shared block on a member's calendar.
+type Created struct {
+	Member  Member
+	EventID string
+	// Skipped is set when the user cannot write to the member's calendar.
+	Skipped bool
+	Err     error
+}
+
+// Create adds block to the calendar of every member the user can write to, as an
+// ordinary event titled title. It returns one outcome per member.
+func Create(ctx context.Context, cal Calendar, members []Member, block planner.TimeBlock, title string) []Created {
+	names := make([]string, len(members))
+	for i, m := range members {
+		names[i] = m.Name
+	}
+	event := calendar.Event{
+		Type:        planner.BlockTypeMeeting,
+		Title:       title,
+		Description: fmt.Sprintf("Shared focus time for %s. Please don't book meetings.", strings.Join(names, ", ")),
+		Start:       block.Start,
+		End:         block.End,
+	}
+
+	created := make([]Created, len(members))
+	for i, m := range members {
+		created[i].Member = m
+		ok, err := cal.CanWrite(ctx, m.Calendar)
+		if err != nil {
+			created[i].Err = err
+			continue
+		}
+		if !ok {
+			created[i].Skipped = true
+			continue
+		}
+		created[i].EventID, created[i].Err = cal.CreateEvent(ctx, m.Calendar, event)
+	}
+	return created
+}
@@ -0,0 +1,121 @@
+package team
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+func meeting(start, end string) calendar.Event {
+	return calendar.Event{Type: planner.BlockTypeMeeting, Title: "Meeting", Start: calendartest.At(start), End: calendartest.At(end)}
+}
+
+// unreadable reports the busy time of one calendar as not available.
+type unreadable struct {
+	*calendartest.Memory
+	calendarID string
+}
+
+func (u unreadable) FreeBusy(ctx context.Context, calendarIDs []string, start, end time.Time) ([]calendar.FreeBusy, error) {
+	result, err := u.Memory.FreeBusy(ctx, calendarIDs, start, end)
+	for i := range result {
+		if result[i].CalendarID == u.calendarID {
+			result[i] = calendar.FreeBusy{CalendarID: u.calendarID, Err: errors.New("notFound")}
+		}
+	}
+	return result, err
+}
+
+func TestFind(t *testing.T) {
+	cfg := &config.Config{
+		WorkHours: config.TimeRange{Start: "09:00", End: "17:00"},
+		Calendar:  "primary",
+		Team: config.Team{Members: []config.TeamMember{
+			{Name: "Ana", Calendar: "ana@example.com", WorkHours: config.TimeRange{Start: "10:00", End: "18:00"}},
+			{Name: "Bo", Calendar: "bo@example.com", TimeZone: "UTC"},
+			{Calendar: "cy@example.com"},
+		}},
+	}
+	members, err := Members(cfg, calendartest.Date)
+	if err != nil {
+		t.Fatalf("Members() error: %v", err)
+	}
+	// Work hours are resolved in UTC to keep the test independent of the local zone
+	for i := range members {
+		members[i].WorkStart, members[i].WorkEnd = members[i].WorkStart.In(time.UTC), members[i].WorkEnd.In(time.UTC)
+	}
+	if len(members) != 4 || members[0].Name != "You" || members[3].Name != "cy@example.com" {
+		t.Fatalf("unexpected members: %+v", members)
+	}
+	if !members[2].WorkStart.Equal(calendartest.At("09:00")) {
+		t.Errorf("expected Bo's work hours in UTC, got %s", members[2].WorkStart)
+	}
+
+	cal := calendartest.NewMemory()
+	cal.Add("primary", meeting("10:00", "10:30"))
+	cal.Add("ana@example.com", meeting("12:00", "13:00"))
+	cal.Add("bo@example.com", meeting("13:30", "14:00"), meeting("16:00", "17:00"))
+	cal.Add("cy@example.com", meeting("11:00", "16:00"))
+
+	// Cy's calendar can't be read, so only their work hours count
+	members[0].WorkStart, members[0].WorkEnd = calendartest.At("09:00"), calendartest.At("17:00")
+	members[1].WorkStart, members[1].WorkEnd = calendartest.At("10:00"), calendartest.At("18:00")
+	members[3].WorkStart, members[3].WorkEnd = calendartest.At("09:00"), calendartest.At("17:00")
+	result, err := Find(context.Background(), unreadable{cal, "cy@example.com"}, members)
+	if err != nil {
+		t.Fatalf("Find() error: %v", err)
+	}
+	if result.Members[3].Err == nil || len(result.Members[2].Busy) != 2 {
+		t.Errorf("unexpected members: %+v", result.Members)
+	}
+	want := [][2]string{{"10:30", "12:00"}, {"13:00", "13:30"}, {"14:00", "16:00"}}
+	if len(result.Windows) != len(want) {
+		t.Fatalf("Windows = %+v, want %v", result.Windows, want)
+	}
+	for i, w := range want {
+		if !result.Windows[i].Start.Equal(calendartest.At(w[0])) || !result.Windows[i].End.Equal(calendartest.At(w[1])) {
+			t.Errorf("window %d = %s - %s, want %s - %s", i, result.Windows[i].Start.Format(planner.TimeFormat),
+				result.Windows[i].End.Format(planner.TimeFormat), w[0], w[1])
+		}
+	}
+
+	tests := []struct {
+		length     time.Duration
+		start, end string
+	}{
+		{2 * time.Hour, "14:00", "16:00"},
+		{time.Hour, "10:30", "11:30"},
+		// No window is long enough, so the longest one is proposed
+		{3 * time.Hour, "14:00", "16:00"},
+	}
+	for _, tt := range tests {
+		block, ok := result.Propose(tt.length)
+		if !ok || !block.Start.Equal(calendartest.At(tt.start)) || !block.End.Equal(calendartest.At(tt.end)) {
+			t.Errorf("Propose(%s) = %+v, %v, want %s - %s", tt.length, block, ok, tt.start, tt.end)
+		}
+	}
+
+	cal.ReadOnly = map[string]bool{"ana@example.com": true}
+	block, _ := result.Propose(2 * time.Hour)
+	created := Create(context.Background(), cal, result.Members, block, "Team focus time")
+	if created[0].EventID == "" || !created[1].Skipped || created[2].EventID == "" || created[2].Err != nil {
+		t.Errorf("unexpected outcomes: %+v", created)
+	}
+	events := cal.Events("bo@example.com")
+	if len(events) != 3 || events[1].Title != "Team focus time" || events[1].Managed {
+		t.Errorf("expected an ordinary event on Bo's calendar, got %+v", events)
+	}
+}
+
+func TestProposeNoWindow(t *testing.T) {
+	result := &Result{Windows: []planner.TimeBlock{{Start: calendartest.At("10:00"), End: calendartest.At("10:20")}}}
+	if _, ok := result.Propose(time.Hour); ok {
+		t.Error("Propose() expected no proposal for windows shorter than MinWindow")
+	}
+}
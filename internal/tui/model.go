@@ -0,0 +1,282 @@
+// Package tui is a full-screen day view to adjust a generated plan with the
+// keyboard before applying it to the calendar.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+// step is how far a block moves or grows per key press.
+const step = 5 * time.Minute
+
+// ApplyFunc writes the edited plan to the calendar.
+type ApplyFunc func(ctx context.Context, plan *planning.Plan) (*planning.Transaction, error)
+
+// Model is the Bubble Tea model of the day view.
+type Model struct {
+	ctx      context.Context
+	service  *planning.Service
+	plan     *planning.Plan
+	timeline *Timeline
+	apply    ApplyFunc
+
+	cursor  int
+	editing bool
+	input   []rune
+	// pending describes a running regenerate or apply; keys are ignored meanwhile.
+	pending string
+	status  string
+	isError bool
+
+	tx       *planning.Transaction
+	applyErr error
+	width    int
+	height   int
+}
+
+type fillMsg struct {
+	start, end time.Time
+	blocks     []planner.TimeBlock
+	err        error
+}
+
+type applyMsg struct {
+	tx  *planning.Transaction
+	err error
+}
+
+// New returns the day view for a generated plan. service is used to regenerate
+// gaps and apply is called when the plan is applied.
+func New(ctx context.Context, service *planning.Service, plan *planning.Plan, apply ApplyFunc) Model {
+	return Model{
+		ctx:      ctx,
+		service:  service,
+		plan:     plan,
+		timeline: NewTimeline(plan.Day, plan.Blocks),
+		apply:    apply,
+		height:   40,
+	}
+}
+
+// Plan returns the plan with the edited blocks.
+func (m Model) Plan() *planning.Plan {
+	plan := *m.plan
+	plan.Blocks = m.timeline.Blocks
+	return &plan
+}
+
+// Result returns the transaction of the apply, or nil when the plan was not applied.
+func (m Model) Result() (*planning.Transaction, error) {
+	return m.tx, m.applyErr
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case fillMsg:
+		m.pending = ""
+		if msg.err != nil {
+			m.setError(fmt.Errorf("failed to regenerate: %w", msg.err))
+			return m, nil
+		}
+		m.timeline.Replace(msg.start, msg.end, msg.blocks)
+		m.setStatus(fmt.Sprintf("Regenerated %s - %s with %d block(s)",
+			msg.start.Format(planner.TimeFormat), msg.end.Format(planner.TimeFormat), len(msg.blocks)))
+		m.clampCursor()
+		return m, nil
+
+	case applyMsg:
+		m.pending = ""
+		m.tx, m.applyErr = msg.tx, msg.err
+		if msg.err != nil {
+			m.setError(msg.err)
+			return m, nil
+		}
+		m.setStatus(fmt.Sprintf("Created %d block(s) in calendar. Press q to quit.", len(msg.tx.Created())))
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			return m, tea.Quit
+		}
+		if m.pending != "" {
+			return m, nil
+		}
+		if m.editing {
+			return m.updateEditing(msg)
+		}
+		return m.updateKey(msg)
+	}
+
+	return m, nil
+}
+
+func (m Model) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	if key == "q" || key == "esc" {
+		return m, tea.Quit
+	}
+
+	items := m.timeline.Items()
+	switch key {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		return m, nil
+	case "down", "j":
+		if m.cursor < len(items)-1 {
+			m.cursor++
+		}
+		return m, nil
+	}
+
+	if m.applied() {
+		m.setStatus("The plan was applied. Press q to quit.")
+		return m, nil
+	}
+	if len(items) == 0 {
+		return m, nil
+	}
+	item := items[m.cursor]
+
+	switch key {
+	case "g":
+		return m.regenerate(item)
+	case "a":
+		m.pending = "Creating blocks in calendar..."
+		plan := m.Plan()
+		return m, func() tea.Msg {
+			tx, err := m.apply(m.ctx, plan)
+			return applyMsg{tx: tx, err: err}
+		}
+	}
+
+	if item.Kind != ItemBlock {
+		switch key {
+		case "K", "shift+up", "J", "shift+down", "+", "=", "-", "e", "enter", "d", "x":
+			m.setStatus("Select a planned block to change it")
+		}
+		return m, nil
+	}
+
+	var (
+		index int
+		err   error
+	)
+	switch key {
+	case "K", "shift+up":
+		index, err = m.timeline.Move(item.Index, -step)
+	case "J", "shift+down":
+		index, err = m.timeline.Move(item.Index, step)
+	case "+", "=":
+		index, err = m.timeline.Resize(item.Index, step)
+	case "-":
+		index, err = m.timeline.Resize(item.Index, -step)
+	case "e", "enter":
+		m.editing = true
+		m.input = []rune(item.Block.Title)
+		m.setStatus("Edit the title, enter to save, esc to cancel")
+		return m, nil
+	case "d", "x":
+		m.timeline.Delete(item.Index)
+		m.setStatus("Deleted " + item.Block.Title)
+		m.clampCursor()
+		return m, nil
+	default:
+		return m, nil
+	}
+
+	if err != nil {
+		m.setError(err)
+		return m, nil
+	}
+	m.status = ""
+	m.selectBlock(index)
+	return m, nil
+}
+
+func (m Model) updateEditing(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.editing = false
+		m.status = ""
+	case tea.KeyEnter:
+		item := m.timeline.Items()[m.cursor]
+		if err := m.timeline.Rename(item.Index, string(m.input)); err != nil {
+			m.setError(err)
+			return m, nil
+		}
+		m.editing = false
+		m.status = ""
+	case tea.KeyBackspace:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.input = append(m.input, msg.Runes...)
+	}
+	return m, nil
+}
+
+// regenerate asks the planner for new blocks in the free window around item.
+func (m Model) regenerate(item Item) (tea.Model, tea.Cmd) {
+	start, end, ok := m.timeline.Window(item)
+	if !ok {
+		m.setStatus("No free time here to regenerate")
+		return m, nil
+	}
+
+	m.pending = fmt.Sprintf("Regenerating %s - %s...", start.Format(planner.TimeFormat), end.Format(planner.TimeFormat))
+	blocks := m.timeline.Blocks
+	return m, func() tea.Msg {
+		filled, err := m.service.Fill(m.ctx, m.plan.Day, m.plan.Mode, m.plan.Tasks, blocks, start, end)
+		if errors.Is(err, planning.ErrNothingToFill) {
+			err = fmt.Errorf("%w; delete or shorten blocks elsewhere first", err)
+		}
+		return fillMsg{start: start, end: end, blocks: filled, err: err}
+	}
+}
+
+func (m Model) applied() bool {
+	return m.tx != nil && m.applyErr == nil
+}
+
+// selectBlock moves the cursor to block index, after the blocks were reordered.
+func (m *Model) selectBlock(index int) {
+	for i, item := range m.timeline.Items() {
+		if item.Kind == ItemBlock && item.Index == index {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+func (m *Model) clampCursor() {
+	if n := len(m.timeline.Items()); m.cursor >= n {
+		m.cursor = max(n-1, 0)
+	}
+}
+
+func (m *Model) setStatus(s string) {
+	m.status, m.isError = s, false
+}
+
+func (m *Model) setError(err error) {
+	m.status, m.isError = err.Error(), true
+}
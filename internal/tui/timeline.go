@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+// ItemKind tells what a timeline row shows.
+type ItemKind int
+
+const (
+	ItemMeeting ItemKind = iota
+	ItemBlock
+	ItemGap
+)
+
+// Item is one row of the timeline.
+type Item struct {
+	Kind  ItemKind
+	Block planner.TimeBlock
+	// Index is the position in Timeline.Blocks of an ItemBlock.
+	Index int
+}
+
+// Timeline holds the planned blocks of a day while they are edited. Meetings are
+// fixed; blocks cannot overlap them or each other and stay within work hours.
+type Timeline struct {
+	Day    *planning.Day
+	Blocks []planner.TimeBlock
+}
+
+func NewTimeline(day *planning.Day, blocks []planner.TimeBlock) *Timeline {
+	t := &Timeline{Day: day, Blocks: slices.Clone(blocks)}
+	t.sort()
+	return t
+}
+
+// Items returns the meetings, blocks and free gaps of the day in time order.
+func (t *Timeline) Items() []Item {
+	var items []Item
+	var busy []planner.TimeBlock
+	for _, m := range t.Day.Meetings {
+		items = append(items, Item{Kind: ItemMeeting, Block: m.ToTimeBlock(), Index: -1})
+		busy = append(busy, m.ToTimeBlock())
+	}
+	for i, b := range t.Blocks {
+		items = append(items, Item{Kind: ItemBlock, Block: b, Index: i})
+		busy = append(busy, b)
+	}
+	for _, gap := range planner.FreeWindows(busy, t.Day.WorkStart, t.Day.WorkEnd) {
+		items = append(items, Item{Kind: ItemGap, Block: gap, Index: -1})
+	}
+
+	slices.SortStableFunc(items, func(a, b Item) int { return a.Block.Start.Compare(b.Block.Start) })
+	return items
+}
+
+// Move shifts block i by d, keeping its length. It returns the new position of
+// the block.
+func (t *Timeline) Move(i int, d time.Duration) (int, error) {
+	b := t.Blocks[i]
+	b.Start = b.Start.Add(d)
+	b.End = b.End.Add(d)
+	return t.set(i, b)
+}
+
+// Resize moves the end of block i by d. It returns the new position of the block.
+func (t *Timeline) Resize(i int, d time.Duration) (int, error) {
+	b := t.Blocks[i]
+	b.End = b.End.Add(d)
+	if !b.End.After(b.Start) {
+		return i, fmt.Errorf("%s can't get any shorter", b.Title)
+	}
+	return t.set(i, b)
+}
+
+// Rename changes the title of block i.
+func (t *Timeline) Rename(i int, title string) error {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return fmt.Errorf("title can't be empty")
+	}
+	t.Blocks[i].Title = title
+	return nil
+}
+
+// Delete removes block i.
+func (t *Timeline) Delete(i int) {
+	t.Blocks = slices.Delete(t.Blocks, i, i+1)
+}
+
+// Window returns the free time around item: the gap between meetings, lunch and
+// buffers that overlaps it. Planned blocks don't limit the window, so it can be
+// planned again as a whole.
+func (t *Timeline) Window(item Item) (start, end time.Time, ok bool) {
+	for _, w := range planner.FreeWindows(t.Day.Busy, t.Day.WorkStart, t.Day.WorkEnd) {
+		if w.Start.Before(item.Block.End) && w.End.After(item.Block.Start) {
+			return w.Start, w.End, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// Replace removes the blocks overlapping start to end and adds blocks instead.
+func (t *Timeline) Replace(start, end time.Time, blocks []planner.TimeBlock) {
+	t.Blocks = slices.DeleteFunc(t.Blocks, func(b planner.TimeBlock) bool {
+		return b.Start.Before(end) && b.End.After(start)
+	})
+	t.Blocks = append(t.Blocks, blocks...)
+	t.sort()
+}
+
+// set replaces block i after checking that b fits in the day, and returns its
+// position once the blocks are in order again.
+func (t *Timeline) set(i int, b planner.TimeBlock) (int, error) {
+	if b.Start.Before(t.Day.WorkStart) || b.End.After(t.Day.WorkEnd) {
+		return i, fmt.Errorf("%s would be outside work hours", b.Title)
+	}
+	for _, m := range t.Day.Meetings {
+		if b.Start.Before(m.End) && b.End.After(m.Start) {
+			return i, fmt.Errorf("%s would overlap %s", b.Title, m.Title)
+		}
+	}
+	for j, o := range t.Blocks {
+		if j != i && b.Start.Before(o.End) && b.End.After(o.Start) {
+			return i, fmt.Errorf("%s would overlap %s", b.Title, o.Title)
+		}
+	}
+	t.Blocks[i] = b
+	t.sort()
+	// Blocks don't overlap, so the start time identifies the block
+	return slices.IndexFunc(t.Blocks, func(o planner.TimeBlock) bool { return o.Start.Equal(b.Start) }), nil
+}
+
+func (t *Timeline) sort() {
+	slices.SortStableFunc(t.Blocks, func(a, b planner.TimeBlock) int { return a.Start.Compare(b.Start) })
+}
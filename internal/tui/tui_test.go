@@ -0,0 +1,175 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai"
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+func testDay() *planning.Day {
+	standup := calendar.Event{Type: planner.BlockTypeMeeting, Title: "Standup", Start: calendartest.At("11:00"), End: calendartest.At("11:30")}
+	return &planning.Day{
+		Date:      calendartest.Date,
+		WorkStart: calendartest.At("09:00"),
+		WorkEnd:   calendartest.At("13:00"),
+		Meetings:  []calendar.Event{standup},
+		Busy:      []planner.TimeBlock{standup.ToTimeBlock()},
+	}
+}
+
+func testBlocks() []planner.TimeBlock {
+	return []planner.TimeBlock{
+		{Type: planner.BlockTypeBreak, Title: "Break", Start: calendartest.At("10:00"), End: calendartest.At("10:15")},
+		{Type: planner.BlockTypeFocus, Title: "Write docs", Start: calendartest.At("09:00"), End: calendartest.At("10:00")},
+	}
+}
+
+func TestTimelineItems(t *testing.T) {
+	tl := NewTimeline(testDay(), testBlocks())
+
+	var got []string
+	for _, item := range tl.Items() {
+		got = append(got, item.Block.Start.Format(planner.TimeFormat))
+	}
+	want := "09:00 10:00 10:15 11:00 11:30"
+	if strings.Join(got, " ") != want {
+		t.Errorf("item starts = %v, want %s", got, want)
+	}
+	if items := tl.Items(); items[2].Kind != ItemGap || items[3].Kind != ItemMeeting {
+		t.Errorf("unexpected item kinds: %+v", items)
+	}
+}
+
+func TestTimelineEdits(t *testing.T) {
+	tl := NewTimeline(testDay(), testBlocks())
+
+	if _, err := tl.Move(0, -5*time.Minute); err == nil {
+		t.Errorf("Move() before work start should fail")
+	}
+	if _, err := tl.Resize(0, 5*time.Minute); err == nil || !strings.Contains(err.Error(), "overlap Break") {
+		t.Errorf("Resize() into the next block error = %v", err)
+	}
+
+	// Moving the break past the standup is blocked, next to it is fine
+	if _, err := tl.Move(1, time.Hour); err == nil || !strings.Contains(err.Error(), "overlap Standup") {
+		t.Errorf("Move() onto a meeting error = %v", err)
+	}
+	i, err := tl.Move(1, 30*time.Minute)
+	if err != nil || i != 1 || !tl.Blocks[1].Start.Equal(calendartest.At("10:30")) {
+		t.Errorf("Move() = %d, %v, blocks %+v", i, err, tl.Blocks)
+	}
+
+	if err := tl.Rename(0, "  "); err == nil {
+		t.Errorf("Rename() to an empty title should fail")
+	}
+	tl.Delete(0)
+	if len(tl.Blocks) != 1 || tl.Blocks[0].Title != "Break" {
+		t.Errorf("unexpected blocks after Delete(): %+v", tl.Blocks)
+	}
+}
+
+func TestTimelineWindow(t *testing.T) {
+	tl := NewTimeline(testDay(), testBlocks())
+
+	start, end, ok := tl.Window(tl.Items()[0])
+	if !ok || !start.Equal(calendartest.At("09:00")) || !end.Equal(calendartest.At("11:00")) {
+		t.Errorf("Window() = %v - %v, %v", start, end, ok)
+	}
+	if _, _, ok := tl.Window(tl.Items()[3]); ok {
+		t.Errorf("Window() of a meeting should not find free time")
+	}
+}
+
+func press(t *testing.T, m Model, keys ...string) Model {
+	t.Helper()
+	for _, key := range keys {
+		var msg tea.KeyMsg
+		switch key {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "backspace":
+			msg = tea.KeyMsg{Type: tea.KeyBackspace}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		}
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		// Run commands synchronously, like the Bubble Tea runtime would
+		for cmd != nil {
+			next := cmd()
+			if _, quit := next.(tea.QuitMsg); quit {
+				break
+			}
+			updated, cmd = m.Update(next)
+			m = updated.(Model)
+		}
+	}
+	return m
+}
+
+func TestModelEditAndApply(t *testing.T) {
+	cfg := &config.Config{Calendar: "primary"}
+	fake := &aitest.StaticPlanner{Response: &ai.PlanResponse{Blocks: []ai.Block{
+		{Type: planner.BlockTypeFocus, Title: "Review PRs", Start: "11:30", End: "12:30"},
+	}}}
+	cal := calendartest.NewMemory()
+	service := planning.NewService(cfg, cal, fake)
+	plan := &planning.Plan{
+		Day:    testDay(),
+		Mode:   config.Mode{Name: "normal"},
+		Tasks:  planner.ParseTaskList("Write docs:L, Review PRs:L"),
+		Blocks: testBlocks(),
+	}
+
+	var applied *planning.Plan
+	apply := func(ctx context.Context, p *planning.Plan) (*planning.Transaction, error) {
+		applied = p
+		return service.Apply(ctx, p, planning.ApplyOptions{})
+	}
+	m := New(context.Background(), service, plan, apply)
+
+	// Shorten the focus block, rename it and move the break down
+	m = press(t, m, "-", "e", "backspace", "backspace", "backspace", "backspace", "enter")
+	m = press(t, m, "j", "j", "J")
+	if m.isError {
+		t.Fatalf("unexpected error: %s", m.status)
+	}
+	blocks := m.Plan().Blocks
+	if blocks[0].Title != "Write" || !blocks[0].End.Equal(calendartest.At("09:55")) || !blocks[1].Start.Equal(calendartest.At("10:05")) {
+		t.Errorf("unexpected blocks after editing: %+v", blocks)
+	}
+
+	// Regenerate the free time after the standup
+	m = press(t, m, "j", "j", "j", "g")
+	if m.isError || !strings.Contains(m.status, "Regenerated 11:30 - 13:00") {
+		t.Fatalf("unexpected status after regenerate: %s", m.status)
+	}
+	if req := fake.Requests()[0]; !req.WorkStart.Equal(calendartest.At("11:30")) || len(req.Tasks) != 2 {
+		t.Errorf("unexpected planner request: %+v", req)
+	}
+
+	m = press(t, m, "a")
+	if applied == nil || len(applied.Blocks) != 3 {
+		t.Fatalf("expected the 3 edited blocks to be applied, got %+v", applied)
+	}
+	if tx, err := m.Result(); err != nil || len(tx.Created()) != 3 || len(cal.Events("primary")) != 3 {
+		t.Errorf("Result() = %+v, %v", tx, err)
+	}
+
+	// Edits are locked after applying
+	m = press(t, m, "d")
+	if len(m.Plan().Blocks) != 3 || !strings.Contains(m.View(), "Review PRs") {
+		t.Errorf("blocks changed after apply: %+v", m.Plan().Blocks)
+	}
+}
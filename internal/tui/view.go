@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+)
+
+// rowDuration is the time covered by one line of the timeline.
+const rowDuration = 15 * time.Minute
+
+const help = "↑/↓ select · J/K move · +/- resize · e edit · d delete · g regenerate gap · a apply · q quit"
+
+var (
+	titleStyle  = lipgloss.NewStyle().Bold(true)
+	helpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("114"))
+	timeStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	gapStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	// blockColors are the bar colors per block type.
+	blockColors = map[string]lipgloss.Color{
+		planner.BlockTypeMeeting:     lipgloss.Color("245"),
+		planner.BlockTypeLunch:       lipgloss.Color("220"),
+		planner.BlockTypeRoutine:     lipgloss.Color("180"),
+		planner.BlockTypeFocus:       lipgloss.Color("69"),
+		planner.BlockTypeBreak:       lipgloss.Color("114"),
+		planner.BlockTypeOutOfOffice: lipgloss.Color("141"),
+	}
+)
+
+func (m Model) View() string {
+	var b strings.Builder
+
+	day := m.plan.Day
+	fmt.Fprintf(&b, "%s\n", titleStyle.Render(fmt.Sprintf("%s · %s mode · %s - %s",
+		day.Date.Format("Monday, January 2, 2006"), m.plan.Mode.Name,
+		day.WorkStart.Format(planner.TimeFormat), day.WorkEnd.Format(planner.TimeFormat))))
+	b.WriteString("\n")
+
+	lines, selected := m.timelineLines()
+	// Keep the selected item on screen: header, status and help take 5 lines
+	visible := max(m.height-5, 5)
+	offset := 0
+	if selected >= visible {
+		offset = selected - visible + 1
+	}
+	end := min(offset+visible, len(lines))
+	for _, line := range lines[offset:end] {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	switch {
+	case m.pending != "":
+		b.WriteString(statusStyle.Render(m.pending))
+	case m.editing:
+		fmt.Fprintf(&b, "Title: %s█", string(m.input))
+	case m.isError:
+		b.WriteString(errorStyle.Render(m.status))
+	default:
+		b.WriteString(statusStyle.Render(m.status))
+	}
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render(help))
+
+	return b.String()
+}
+
+// timelineLines renders the items, one line per rowDuration, and returns the
+// index of the first line of the selected item.
+func (m Model) timelineLines() ([]string, int) {
+	var lines []string
+	selected := 0
+
+	for i, item := range m.timeline.Items() {
+		rows := max(int(item.Block.End.Sub(item.Block.Start)/rowDuration), 1)
+		isSelected := i == m.cursor
+		if isSelected {
+			selected = len(lines)
+		}
+
+		marker := "  "
+		if isSelected {
+			marker = "› "
+		}
+
+		label, bar := itemLabel(item)
+		for row := range rows {
+			clock := "     "
+			if row == 0 {
+				clock = item.Block.Start.Format(planner.TimeFormat)
+			}
+			text := ""
+			if row == 0 {
+				text = " " + label
+			}
+			if isSelected {
+				text = lipgloss.NewStyle().Reverse(true).Render(text)
+			}
+			lines = append(lines, marker+timeStyle.Render(clock)+" "+bar+text)
+		}
+	}
+
+	return lines, selected
+}
+
+// itemLabel returns the text and the colored bar of an item.
+func itemLabel(item Item) (string, string) {
+	b := item.Block
+	span := fmt.Sprintf("%s - %s", b.Start.Format(planner.TimeFormat), b.End.Format(planner.TimeFormat))
+	if item.Kind == ItemGap {
+		return gapStyle.Render(fmt.Sprintf("free %d min", int(b.End.Sub(b.Start).Minutes()))), gapStyle.Render("┊")
+	}
+
+	blockType := b.Type
+	if item.Kind == ItemMeeting {
+		blockType = planner.BlockTypeMeeting
+	}
+	color, ok := blockColors[blockType]
+	if !ok {
+		color = blockColors[planner.BlockTypeMeeting]
+	}
+	bar := lipgloss.NewStyle().Foreground(color).Render("██")
+	return fmt.Sprintf("%s  %s", b.Title, timeStyle.Render(span)), bar
+}
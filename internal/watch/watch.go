@@ -0,0 +1,99 @@
+// Package watch keeps the planned blocks of the day out of the way of meetings
+// added after planning. It checks the calendar on an interval and whenever a
+// push notification arrives, and logs every change it makes.
+package watch
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+// Watcher reconciles the calendar of the planning date on every check.
+type Watcher struct {
+	service  *planning.Service
+	date     func() (time.Time, error)
+	interval time.Duration
+	opts     planning.ReconcileOptions
+	log      *log.Logger
+}
+
+// New returns a watcher that checks the day returned by date every interval.
+func New(service *planning.Service, date func() (time.Time, error), interval time.Duration, opts planning.ReconcileOptions, logger *log.Logger) *Watcher {
+	return &Watcher{
+		service:  service,
+		date:     date,
+		interval: interval,
+		opts:     opts,
+		log:      logger,
+	}
+}
+
+// Run checks the calendar right away, then every interval and on every value
+// received from trigger, until ctx is done.
+func (w *Watcher) Run(ctx context.Context, trigger <-chan struct{}) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-trigger:
+		}
+	}
+}
+
+// Check reconciles the day once and logs the changes. Errors are logged too, so
+// a failing check doesn't stop the watcher.
+func (w *Watcher) Check(ctx context.Context) ([]planning.Change, error) {
+	date, err := w.date()
+	if err != nil {
+		w.log.Printf("failed to get the planning date: %v", err)
+		return nil, err
+	}
+
+	changes, err := w.service.Reconcile(ctx, date, w.opts)
+	prefix := ""
+	if w.opts.DryRun {
+		prefix = "[dry run] "
+	}
+	for _, c := range changes {
+		w.log.Printf("%s%s", prefix, c)
+	}
+	if err != nil && ctx.Err() == nil {
+		w.log.Printf("check failed: %v", err)
+	}
+	return changes, err
+}
+
+// WebhookHandler receives Google Calendar push notifications and sends on trigger
+// for every change. Notifications without the channel token are rejected.
+func WebhookHandler(token string, trigger chan<- struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Goog-Channel-Token")), []byte(token)) != 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// "sync" only confirms that the channel was created
+		if r.Header.Get("X-Goog-Resource-State") != "sync" {
+			select {
+			case trigger <- struct{}{}:
+			default:
+				// A check is already pending
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
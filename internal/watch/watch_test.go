@@ -0,0 +1,106 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvkoen/barely-incharge/internal/ai/aitest"
+	"github.com/Alvkoen/barely-incharge/internal/calendar"
+	"github.com/Alvkoen/barely-incharge/internal/calendar/calendartest"
+	"github.com/Alvkoen/barely-incharge/internal/config"
+	"github.com/Alvkoen/barely-incharge/internal/planner"
+	"github.com/Alvkoen/barely-incharge/internal/planning"
+)
+
+func TestWatcherRun(t *testing.T) {
+	cfg := &config.Config{
+		WorkHours: config.TimeRange{Start: "09:00", End: "17:00"},
+		Calendar:  "primary",
+	}
+	cal := calendartest.NewMemory()
+	focus := planner.TimeBlock{Type: planner.BlockTypeFocus, Title: "Write docs", Start: calendartest.At("14:00"), End: calendartest.At("15:00")}
+	cal.Add("primary", calendar.Event{
+		Type:        focus.Type,
+		Title:       focus.GetCalendarTitle(),
+		Description: focus.GetCalendarDescription(),
+		Start:       focus.Start,
+		End:         focus.End,
+		Managed:     true,
+	})
+	service := planning.NewService(cfg, cal, &aitest.StaticPlanner{})
+
+	var out bytes.Buffer
+	w := New(service, func() (time.Time, error) { return calendartest.Date, nil }, time.Hour, planning.ReconcileOptions{}, log.New(&out, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	trigger := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx, trigger)
+		close(done)
+	}()
+
+	// The first check runs before the meeting is added; the trigger picks it up
+	cal.Add("primary", calendar.Event{Type: planner.BlockTypeMeeting, Title: "Design review", Start: calendartest.At("14:30"), End: calendartest.At("15:00")})
+	trigger <- struct{}{}
+	trigger <- struct{}{}
+	cancel()
+	<-done
+
+	want := "moved Focus time (Write docs) 14:00 - 15:00 → 15:00 - 16:00 (conflicts with Design review)\n"
+	if out.String() != want {
+		t.Errorf("log = %q, want %q", out.String(), want)
+	}
+	if events := cal.Events("primary"); !events[1].Start.Equal(calendartest.At("15:00")) {
+		t.Errorf("expected the focus block to be moved, got %+v", events)
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	trigger := make(chan struct{}, 1)
+	handler := WebhookHandler("secret", trigger)
+
+	tests := []struct {
+		name      string
+		method    string
+		token     string
+		state     string
+		want      int
+		triggered bool
+	}{
+		{"change", http.MethodPost, "secret", "exists", http.StatusOK, true},
+		{"sync", http.MethodPost, "secret", "sync", http.StatusOK, false},
+		{"wrong token", http.MethodPost, "nope", "exists", http.StatusForbidden, false},
+		{"get", http.MethodGet, "secret", "exists", http.StatusMethodNotAllowed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(""))
+			req.Header.Set("X-Goog-Channel-Token", tt.token)
+			req.Header.Set("X-Goog-Resource-State", tt.state)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			select {
+			case <-trigger:
+				if !tt.triggered {
+					t.Errorf("unexpected trigger")
+				}
+			default:
+				if tt.triggered {
+					t.Errorf("expected a trigger")
+				}
+			}
+		})
+	}
+}